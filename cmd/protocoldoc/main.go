@@ -0,0 +1,34 @@
+// Command protocoldoc generates the JSON Schema and Markdown document of the
+// real-time protocol. Run it with go generate ./internal/protocol.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/rkrmr33/quickwiz/internal/protocol"
+)
+
+func main() {
+	modelsDir := flag.String("models", "internal/models", "Directory of the package declaring the payload types")
+	schemaPath := flag.String("schema", "internal/protocol/schema.json", "Where to write the JSON Schema")
+	docPath := flag.String("doc", "docs/protocol.md", "Where to write the Markdown document")
+	flag.Parse()
+
+	comments, err := protocol.LoadComments(*modelsDir)
+	if err != nil {
+		log.Fatalf("Failed to read payload comments: %v", err)
+	}
+
+	schema, err := protocol.Schema(comments)
+	if err != nil {
+		log.Fatalf("Failed to build schema: %v", err)
+	}
+	if err := os.WriteFile(*schemaPath, schema, 0o644); err != nil {
+		log.Fatalf("Failed to write schema: %v", err)
+	}
+	if err := os.WriteFile(*docPath, protocol.Document(comments), 0o644); err != nil {
+		log.Fatalf("Failed to write document: %v", err)
+	}
+}
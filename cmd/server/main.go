@@ -1,42 +1,184 @@
 package main
 
 import (
-	"html/template"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/rkrmr33/quickwiz/internal/assets"
+	"github.com/rkrmr33/quickwiz/internal/catalog"
+	"github.com/rkrmr33/quickwiz/internal/cluster"
+	"github.com/rkrmr33/quickwiz/internal/config"
 	"github.com/rkrmr33/quickwiz/internal/handlers"
+	"github.com/rkrmr33/quickwiz/internal/library"
+	"github.com/rkrmr33/quickwiz/internal/metrics"
+	"github.com/rkrmr33/quickwiz/internal/parser"
+	"github.com/rkrmr33/quickwiz/internal/protocol"
 	"github.com/rkrmr33/quickwiz/internal/quiz"
 )
 
+// httpShutdownTimeout bounds finishing in-flight HTTP requests once games have
+// drained and connections are closed
+const httpShutdownTimeout = 5 * time.Second
+
 func main() {
+	// Load configuration from flags, environment and config file
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		slog.Error("Failed to load configuration", "error", err)
+		os.Exit(2)
+	}
+	if cfg.PrintConfig {
+		if err := cfg.Dump(os.Stdout); err != nil {
+			slog.Error("Failed to print configuration", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Setup structured logging
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelInfo,
+		Level: cfg.SlogLevel(),
 	}))
 	slog.SetDefault(logger)
 
-	slog.Info("Starting QuicKwiz server")
+	slog.Info("Starting QuicKwiz server", "config_file", cfg.ConfigFile, "log_level", cfg.LogLevel)
+
+	// Cancelled on SIGINT or SIGTERM to start a graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Run a broker for the other instances if asked to
+	if cfg.BrokerListen != "" {
+		listener, err := net.Listen("tcp", cfg.BrokerListen)
+		if err != nil {
+			slog.Error("Failed to start broker", "error", err, "addr", cfg.BrokerListen)
+			os.Exit(1)
+		}
+		broker := cluster.NewBroker()
+		go broker.Serve(listener)
+		defer broker.Close()
+		slog.Info("Broker listening", "addr", listener.Addr().String())
+		slog.Warn("The built-in broker keeps sessions in memory without authentication; use it for development and testing only")
+	}
+
+	// Share sessions, broadcasts and game leases through the broker, if any
+	nodeID := cfg.NodeID
+	if nodeID == "" {
+		host, _ := os.Hostname()
+		nodeID = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	var store quiz.Store
+	var pubsub cluster.PubSub
+	var leases cluster.Leaser
+	if cfg.Broker != "" {
+		client, err := cluster.Dial(cfg.Broker)
+		if err != nil {
+			slog.Error("Failed to connect to broker", "error", err, "addr", cfg.Broker)
+			os.Exit(1)
+		}
+		defer client.Close()
+		store, pubsub, leases = quiz.NewKVStore(client), client, client
+		slog.Info("Joined cluster", "broker", cfg.Broker, "node_id", nodeID)
+	}
 
 	// Initialize quiz manager
-	quizManager := quiz.NewManager()
-	slog.Info("Quiz manager initialized")
+	quizManager := quiz.NewManagerWithOptions(quiz.Options{
+		SessionTTL:      cfg.SessionTTL,
+		MaxSessions:     cfg.MaxSessions,
+		MaxParticipants: cfg.MaxParticipants,
+		Store:           store,
+	})
+	slog.Info("Quiz manager initialized",
+		"session_ttl", cfg.SessionTTL.String(),
+		"max_sessions", cfg.MaxSessions,
+		"max_participants", cfg.MaxParticipants)
+
+	// Quizzes that do not set their own timings use the configured ones
+	timings := parser.Timings{
+		TimePerQuestion:      cfg.TimePerQuestion,
+		TimeBetweenQuestions: cfg.TimeBetweenQuestions,
+		TimeBetweenRounds:    cfg.TimeBetweenRounds,
+		RoundIntroTime:       cfg.RoundIntroTime,
+	}
+
+	// Initialize quiz library
+	quizLibrary, err := library.NewLibraryWithTimings(cfg.LibraryDir, timings)
+	if err != nil {
+		slog.Error("Failed to load quiz library", "error", err, "dir", cfg.LibraryDir)
+		os.Exit(1)
+	}
+	slog.Info("Quiz library loaded", "dir", cfg.LibraryDir, "quizzes", len(quizLibrary.List()))
+
+	// Load templates and static files (embedded unless web-dir overrides them)
+	webAssets, err := assets.Load(cfg.WebDir)
+	if err != nil {
+		slog.Error("Failed to load web assets", "error", err, "override_dir", cfg.WebDir)
+		os.Exit(1)
+	}
+	slog.Info("Templates loaded successfully", "embedded", cfg.WebDir == "")
 
-	// Load templates
-	templates := template.Must(template.ParseGlob("web/templates/*.html"))
-	slog.Info("Templates loaded successfully")
+	// Index built-in quiz catalog and, when it is read from disk, reload it
+	// when files change
+	catalogFS, err := fs.Sub(webAssets.Static, "quizzes")
+	if err != nil {
+		slog.Error("Failed to open quiz catalog", "error", err)
+		os.Exit(1)
+	}
+	if cfg.CatalogDir != "" {
+		catalogFS = os.DirFS(cfg.CatalogDir)
+	}
+	quizCatalog, err := catalog.NewCatalog(catalogFS)
+	if err != nil {
+		slog.Error("Failed to index quiz catalog", "error", err)
+		os.Exit(1)
+	}
+	if cfg.CatalogDir != "" || cfg.WebDir != "" {
+		go quizCatalog.Watch(ctx, 5*time.Second)
+	}
 
 	// Initialize handlers
-	handler := handlers.NewHandler(quizManager, templates)
+	handler := handlers.NewHandler(quizManager, quizLibrary, quizCatalog, webAssets.Templates, handlers.Options{
+		Timings:    timings,
+		AdminToken: cfg.AdminToken,
+		PubSub:     pubsub,
+		Leases:     leases,
+		NodeID:     nodeID,
+	})
+
+	// Take over the games of instances that stop running them
+	go handler.WatchGames(ctx)
+
+	// Expose session and connection gauges alongside the package metrics
+	quizManager.RegisterMetrics(metrics.Default)
+	handler.RegisterMetrics(metrics.Default)
 
 	// Setup router
 	r := mux.NewRouter()
 
+	// Prometheus metrics
+	r.Handle("/metrics", metrics.Handler()).Methods("GET")
+
+	// Health and admin routes
+	r.HandleFunc("/healthz", handler.HealthzHandler).Methods("GET")
+	r.HandleFunc("/readyz", handler.ReadyzHandler).Methods("GET")
+	r.HandleFunc("/admin/status", handler.AdminStatusHandler).Methods("GET")
+
 	// Static files
-	r.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.Dir("web/static"))))
+	r.PathPrefix("/static/").Handler(http.StripPrefix("/static/", webAssets.StaticHandler()))
 
 	// Web routes
 	r.HandleFunc("/", handler.HomeHandler).Methods("GET")
@@ -49,9 +191,25 @@ func main() {
 	r.HandleFunc("/api/quiz/{code}/join", handler.JoinQuizHandler).Methods("POST")
 	r.HandleFunc("/api/quiz/{code}/start", handler.StartQuizHandler).Methods("POST")
 	r.HandleFunc("/api/quiz/{code}/answer", handler.SubmitAnswerHandler).Methods("POST")
+	r.Handle("/api/protocol", protocol.SchemaHandler()).Methods("GET")
+
+	// Catalog routes
+	r.HandleFunc("/api/catalog", handler.CatalogHandler).Methods("GET")
+	r.HandleFunc("/api/catalog/tags", handler.CatalogTagsHandler).Methods("GET")
+	r.HandleFunc("/api/catalog/{id}", handler.CatalogQuizHandler).Methods("GET")
 
-	// WebSocket route
+	// Quiz library routes
+	r.HandleFunc("/api/quizzes", handler.ListQuizzesHandler).Methods("GET")
+	r.HandleFunc("/api/quizzes", handler.SaveQuizHandler).Methods("POST")
+	r.HandleFunc("/api/quizzes/{id}", handler.GetSavedQuizHandler).Methods("GET")
+	r.HandleFunc("/api/quizzes/{id}", handler.UpdateSavedQuizHandler).Methods("PUT")
+	r.HandleFunc("/api/quizzes/{id}", handler.DeleteSavedQuizHandler).Methods("DELETE")
+	r.HandleFunc("/api/quizzes/{id}/versions", handler.SavedQuizVersionsHandler).Methods("GET")
+
+	// Real-time routes; Server-Sent Events are the fallback where WebSockets are blocked
 	r.HandleFunc("/ws/{code}", handler.WebSocketHandler)
+	r.HandleFunc("/sse/{code}", handler.SSEHandler).Methods("GET")
+	r.HandleFunc("/api/quiz/{code}/command", handler.CommandHandler).Methods("POST")
 
 	// 404 handler
 	r.NotFoundHandler = http.HandlerFunc(handler.NotFoundHandler)
@@ -60,19 +218,72 @@ func main() {
 
 	// Cleanup goroutine
 	go func() {
-		ticker := time.NewTicker(1 * time.Hour)
+		ticker := time.NewTicker(cfg.CleanupInterval)
 		defer ticker.Stop()
-		for range ticker.C {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
 			slog.Info("Running session cleanup")
-			quizManager.CleanupOldSessions()
+			if err := quizManager.CleanupOldSessions(); err != nil {
+				slog.Error("Session cleanup failed", "error", err)
+			}
+			handler.PrunePresence()
 		}
 	}()
 
 	// Start server
-	port := "8080"
-	slog.Info("QuicKwiz server starting", "port", port, "url", "http://localhost:"+port)
-	if err := http.ListenAndServe(":"+port, r); err != nil {
+	server := &http.Server{
+		Addr:    cfg.Addr,
+		Handler: r,
+	}
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("QuicKwiz server starting", "addr", cfg.Addr, "tls", cfg.TLS())
+		if cfg.TLS() {
+			serverErr <- server.ListenAndServeTLS(cfg.TLSCert, cfg.TLSKey)
+		} else {
+			serverErr <- server.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-serverErr:
 		slog.Error("Server failed to start", "error", err)
 		os.Exit(1)
+	case <-ctx.Done():
+	}
+	stop() // A second signal kills the process immediately
+
+	slog.Info("Shutting down", "timeout", cfg.ShutdownTimeout.String(), "drain_games", cfg.DrainGames)
+	drainCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	// Reject new games, let running ones finish, then disconnect clients. HTTP
+	// keeps serving meanwhile so players can still submit answers.
+	quizManager.Drain()
+	handler.Shutdown(drainCtx, cfg.DrainGames)
+
+	// Sessions in the shared store are saved as they change, so only those
+	// kept in memory are lost
+	if store == nil {
+		sessions := 0
+		for _, n := range quizManager.SessionCounts() {
+			sessions += n
+		}
+		if sessions > 0 {
+			slog.Warn("Sessions kept in memory end with the server", "sessions", sessions)
+		}
+	}
+
+	// The drain may have used the whole timeout, so HTTP gets its own
+	httpCtx, cancelHTTP := context.WithTimeout(context.Background(), httpShutdownTimeout)
+	defer cancelHTTP()
+	if err := server.Shutdown(httpCtx); err != nil {
+		slog.Error("Server shutdown did not complete", "error", err)
+		os.Exit(1)
 	}
+	slog.Info("Server stopped")
 }
@@ -0,0 +1,119 @@
+package assets
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/rkrmr33/quickwiz/web"
+)
+
+// RequiredTemplates are the templates the handlers render
+var RequiredTemplates = []string{"index.html", "join.html", "quiz.html", "404.html"}
+
+const (
+	// cacheControl is sent for embedded static files, which only change with a new build
+	cacheControl = "public, max-age=3600"
+	// devCacheControl is sent when serving from an override directory during development
+	devCacheControl = "no-cache"
+)
+
+// Assets holds the parsed templates and the static file system
+type Assets struct {
+	Templates *template.Template
+	Static    fs.FS
+	dev       bool
+	etags     sync.Map // cache key -> ETag
+}
+
+// Load loads the web assets embedded in the binary. If overrideDir is set, the
+// templates/ and static/ directories are read from it instead, which allows
+// editing them without rebuilding during development. It fails if any
+// required template is missing.
+func Load(overrideDir string) (*Assets, error) {
+	var root fs.FS = web.FS
+	if overrideDir != "" {
+		if _, err := os.Stat(overrideDir); err != nil {
+			return nil, fmt.Errorf("web override directory: %w", err)
+		}
+		root = os.DirFS(overrideDir)
+	}
+
+	templates, err := template.ParseFS(root, "templates/*.html")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse templates: %w", err)
+	}
+
+	for _, name := range RequiredTemplates {
+		if templates.Lookup(name) == nil {
+			return nil, fmt.Errorf("required template %s is missing", name)
+		}
+	}
+
+	static, err := fs.Sub(root, "static")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open static files: %w", err)
+	}
+
+	return &Assets{
+		Templates: templates,
+		Static:    static,
+		dev:       overrideDir != "",
+	}, nil
+}
+
+// StaticHandler serves static files with cache headers and content-based
+// ETags, answering conditional requests with 304 Not Modified. Mount it with
+// http.StripPrefix so request paths are relative to the static directory.
+func (a *Assets) StaticHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+		if name == "" {
+			http.NotFound(w, r)
+			return
+		}
+
+		info, err := fs.Stat(a.Static, name)
+		if err != nil || info.IsDir() {
+			http.NotFound(w, r)
+			return
+		}
+
+		data, err := fs.ReadFile(a.Static, name)
+		if err != nil {
+			http.Error(w, "Failed to read file", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("ETag", a.etag(name, info, data))
+		if a.dev {
+			w.Header().Set("Cache-Control", devCacheControl)
+		} else {
+			w.Header().Set("Cache-Control", cacheControl)
+		}
+
+		// ServeContent handles If-None-Match, ranges and the content type
+		http.ServeContent(w, r, name, info.ModTime(), bytes.NewReader(data))
+	})
+}
+
+// etag returns a strong ETag for a file, cached by name, size and modification time
+func (a *Assets) etag(name string, info fs.FileInfo, data []byte) string {
+	key := fmt.Sprintf("%s:%d:%d", name, info.Size(), info.ModTime().UnixNano())
+	if etag, ok := a.etags.Load(key); ok {
+		return etag.(string)
+	}
+
+	sum := sha256.Sum256(data)
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+	a.etags.Store(key, etag)
+	return etag
+}
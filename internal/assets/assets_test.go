@@ -0,0 +1,80 @@
+package assets
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadEmbedded(t *testing.T) {
+	a, err := Load("")
+	if err != nil {
+		t.Fatalf("Failed to load embedded assets: %v", err)
+	}
+
+	for _, name := range RequiredTemplates {
+		if a.Templates.Lookup(name) == nil {
+			t.Errorf("Expected template %s to be embedded", name)
+		}
+	}
+}
+
+func TestLoadMissingTemplate(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "templates"), 0o755)
+	os.MkdirAll(filepath.Join(dir, "static"), 0o755)
+	os.WriteFile(filepath.Join(dir, "templates", "index.html"), []byte("<html></html>"), 0o644)
+
+	if _, err := Load(dir); err == nil {
+		t.Error("Expected error for missing templates, got nil")
+	}
+}
+
+func TestLoadMissingOverrideDir(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Expected error for missing override directory, got nil")
+	}
+}
+
+func TestStaticHandler(t *testing.T) {
+	a, err := Load("")
+	if err != nil {
+		t.Fatalf("Failed to load embedded assets: %v", err)
+	}
+	handler := a.StaticHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/quizzes/food-1.md", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Error("Expected an ETag header")
+	}
+	if rec.Header().Get("Cache-Control") != cacheControl {
+		t.Errorf("Expected Cache-Control '%s', got '%s'", cacheControl, rec.Header().Get("Cache-Control"))
+	}
+
+	// Conditional request with a matching ETag
+	req = httptest.NewRequest(http.MethodGet, "/quizzes/food-1.md", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("Expected 304, got %d", rec.Code)
+	}
+
+	// Directories and missing files are not served
+	for _, path := range []string{"/", "/quizzes", "/missing.txt", "/../web.go"} {
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", path, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rkrmr33/quickwiz/internal/models"
+	"github.com/rkrmr33/quickwiz/internal/parser"
+)
+
+// ErrNotFound is returned when a catalog quiz does not exist
+var ErrNotFound = errors.New("catalog quiz not found")
+
+// entry is an indexed quiz with its markdown
+type entry struct {
+	info     models.CatalogEntry
+	markdown string
+}
+
+// Catalog indexes the built-in quizzes in a directory of markdown files
+type Catalog struct {
+	fsys        fs.FS
+	entries     map[string]*entry
+	fingerprint string
+	mu          sync.RWMutex
+}
+
+// Filter narrows down catalog searches. Empty fields match everything.
+type Filter struct {
+	Query      string   // Case-insensitive text matched against title, description, category and tags
+	Category   string   // Exact category
+	Difficulty string   // Exact difficulty
+	Tags       []string // All tags must be present
+}
+
+// NewCatalog creates a catalog over the markdown files in fsys and indexes them
+func NewCatalog(fsys fs.FS) (*Catalog, error) {
+	c := &Catalog{
+		fsys:    fsys,
+		entries: make(map[string]*entry),
+	}
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Reload re-indexes every quiz. Files that fail to parse are logged and skipped
+// so one broken quiz does not hide the rest of the catalog.
+func (c *Catalog) Reload() error {
+	fingerprint, err := c.scanFingerprint()
+	if err != nil {
+		return err
+	}
+
+	files, err := fs.Glob(c.fsys, "*.md")
+	if err != nil {
+		return fmt.Errorf("failed to list catalog: %w", err)
+	}
+
+	entries := make(map[string]*entry, len(files))
+	for _, file := range files {
+		data, err := fs.ReadFile(c.fsys, file)
+		if err != nil {
+			slog.Warn("Catalog failed to read quiz", "file", file, "error", err)
+			continue
+		}
+
+		quiz, err := parser.ParseQuizMarkdown(string(data))
+		if err != nil {
+			slog.Warn("Catalog failed to parse quiz", "file", file, "error", err)
+			parser.CountFailure(parser.SourceCatalog)
+			continue
+		}
+
+		id := quizID(file)
+		entries[id] = &entry{
+			info:     catalogEntry(id, quiz),
+			markdown: string(data),
+		}
+	}
+
+	c.mu.Lock()
+	c.entries = entries
+	c.fingerprint = fingerprint
+	c.mu.Unlock()
+
+	slog.Info("Catalog indexed", "quizzes", len(entries))
+	return nil
+}
+
+// Search returns the quizzes matching the filter, sorted by category then title
+func (c *Catalog) Search(filter Filter) []models.CatalogEntry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	results := make([]models.CatalogEntry, 0, len(c.entries))
+	for _, e := range c.entries {
+		if filter.matches(e.info) {
+			results = append(results, e.info)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Category != results[j].Category {
+			return results[i].Category < results[j].Category
+		}
+		if results[i].Title != results[j].Title {
+			return results[i].Title < results[j].Title
+		}
+		return results[i].ID < results[j].ID
+	})
+
+	return results
+}
+
+// Get returns a catalog quiz and its markdown. IDs are matched
+// case-insensitively.
+func (c *Catalog) Get(id string) (*models.CatalogEntry, string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	e, exists := c.entries[strings.ToLower(id)]
+	if !exists {
+		return nil, "", ErrNotFound
+	}
+	info := e.info
+	return &info, e.markdown, nil
+}
+
+// Tags returns every tag used in the catalog with its number of quizzes
+func (c *Catalog) Tags() map[string]int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	tags := make(map[string]int)
+	for _, e := range c.entries {
+		for _, tag := range e.info.Tags {
+			tags[tag]++
+		}
+	}
+	return tags
+}
+
+// Watch polls the catalog for changed, added or removed files and reloads it
+// until ctx is cancelled
+func (c *Catalog) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		fingerprint, err := c.scanFingerprint()
+		if err != nil {
+			slog.Warn("Catalog failed to scan for changes", "error", err)
+			continue
+		}
+
+		c.mu.RLock()
+		changed := fingerprint != c.fingerprint
+		c.mu.RUnlock()
+
+		if changed {
+			slog.Info("Catalog changed, reloading")
+			if err := c.Reload(); err != nil {
+				slog.Error("Catalog failed to reload", "error", err)
+			}
+		}
+	}
+}
+
+// scanFingerprint summarizes the names, sizes and modification times of the
+// quiz files so changes can be detected without re-parsing everything
+func (c *Catalog) scanFingerprint() (string, error) {
+	files, err := fs.Glob(c.fsys, "*.md")
+	if err != nil {
+		return "", fmt.Errorf("failed to list catalog: %w", err)
+	}
+
+	var b strings.Builder
+	for _, file := range files {
+		info, err := fs.Stat(c.fsys, file)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&b, "%s:%d:%d;", file, info.Size(), info.ModTime().UnixNano())
+	}
+	return b.String(), nil
+}
+
+// matches reports whether an entry passes the filter
+func (f Filter) matches(e models.CatalogEntry) bool {
+	if f.Category != "" && !strings.EqualFold(e.Category, f.Category) {
+		return false
+	}
+	if f.Difficulty != "" && !strings.EqualFold(e.Difficulty, f.Difficulty) {
+		return false
+	}
+	for _, want := range f.Tags {
+		found := false
+		for _, tag := range e.Tags {
+			if strings.EqualFold(tag, want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if f.Query != "" {
+		query := strings.ToLower(f.Query)
+		haystack := strings.ToLower(strings.Join(append([]string{e.Title, e.Description, e.Category}, e.Tags...), " "))
+		if !strings.Contains(haystack, query) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// quizID derives the ID of a quiz from its file name, lowercased so IDs match
+// however the file name is cased ("Space-1.md" -> "space-1")
+func quizID(file string) string {
+	return strings.ToLower(strings.TrimSuffix(path.Base(file), ".md"))
+}
+
+// catalogEntry builds the catalog description of a parsed quiz. The category
+// comes from front matter, falling back to the file name prefix ("food-1" -> "food").
+func catalogEntry(id string, quiz *models.Quiz) models.CatalogEntry {
+	category := quiz.Category
+	if category == "" {
+		category = id
+		if i := strings.LastIndex(id, "-"); i > 0 {
+			category = id[:i]
+		}
+	}
+
+	return models.CatalogEntry{
+		ID:            id,
+		Title:         quiz.Title,
+		Description:   quiz.Description,
+		Author:        quiz.Author,
+		Category:      strings.ToLower(category),
+		Difficulty:    quiz.Difficulty,
+		Language:      quiz.Language,
+		Tags:          quiz.Tags,
+		QuestionCount: len(quiz.Questions),
+	}
+}
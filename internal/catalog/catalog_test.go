@@ -0,0 +1,137 @@
+package catalog
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+const spaceQuiz = `# Space Quiz
+
+### Which planet is known as the Red Planet?
+- Venus
+- Mars
+* Answer: Mars`
+
+const taggedQuiz = `---
+title: Capitals
+description: Capital cities of Europe
+category: Geography
+difficulty: hard
+tags: [europe, capitals]
+---
+
+### Capital of France?
+- Paris
+- Rome
+* Answer: Paris
+
+### Capital of Italy?
+- Paris
+- Rome
+* Answer: Rome`
+
+func TestNewCatalog(t *testing.T) {
+	fsys := fstest.MapFS{
+		"space-1.md":  {Data: []byte(spaceQuiz)},
+		"Europe.md":   {Data: []byte(taggedQuiz)},
+		"broken-1.md": {Data: []byte("# No questions")},
+		"notes.txt":   {Data: []byte("ignored")},
+	}
+
+	c, err := NewCatalog(fsys)
+	if err != nil {
+		t.Fatalf("Failed to create catalog: %v", err)
+	}
+
+	all := c.Search(Filter{})
+	if len(all) != 2 {
+		t.Fatalf("Expected 2 quizzes (broken one skipped), got %d", len(all))
+	}
+
+	space, markdown, err := c.Get("space-1")
+	if err != nil {
+		t.Fatalf("Failed to get quiz: %v", err)
+	}
+	if space.Category != "space" || space.QuestionCount != 1 || space.Title != "Space Quiz" {
+		t.Errorf("Unexpected entry: %+v", space)
+	}
+	if markdown != spaceQuiz {
+		t.Error("Expected markdown to be returned")
+	}
+
+	// IDs are lowercased, whatever the case of the file name
+	europe, _, err := c.Get("europe")
+	if err != nil {
+		t.Fatalf("Failed to get quiz with a mixed-case file name: %v", err)
+	}
+	if europe.ID != "europe" {
+		t.Errorf("Expected ID europe, got %s", europe.ID)
+	}
+	if europe.Category != "geography" || europe.Difficulty != "hard" || europe.QuestionCount != 2 {
+		t.Errorf("Expected front matter metadata to be indexed, got %+v", europe)
+	}
+
+	if _, _, err := c.Get("missing"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	c, _ := NewCatalog(fstest.MapFS{
+		"space-1.md": {Data: []byte(spaceQuiz)},
+		"europe.md":  {Data: []byte(taggedQuiz)},
+	})
+
+	tests := []struct {
+		name     string
+		filter   Filter
+		expected int
+	}{
+		{"query title", Filter{Query: "space"}, 1},
+		{"query description", Filter{Query: "EUROPE"}, 1},
+		{"category", Filter{Category: "Geography"}, 1},
+		{"difficulty", Filter{Difficulty: "hard"}, 1},
+		{"single tag", Filter{Tags: []string{"capitals"}}, 1},
+		{"all tags required", Filter{Tags: []string{"capitals", "asia"}}, 0},
+		{"no match", Filter{Query: "dinosaurs"}, 0},
+	}
+
+	for _, tt := range tests {
+		if results := c.Search(tt.filter); len(results) != tt.expected {
+			t.Errorf("%s: expected %d results, got %d", tt.name, tt.expected, len(results))
+		}
+	}
+
+	tags := c.Tags()
+	if tags["europe"] != 1 || tags["capitals"] != 1 {
+		t.Errorf("Unexpected tags: %v", tags)
+	}
+}
+
+func TestWatchReloads(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "space-1.md"), []byte(spaceQuiz), 0o644)
+
+	c, err := NewCatalog(os.DirFS(dir))
+	if err != nil {
+		t.Fatalf("Failed to create catalog: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Watch(ctx, 10*time.Millisecond)
+
+	os.WriteFile(filepath.Join(dir, "europe.md"), []byte(taggedQuiz), 0o644)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(c.Search(Filter{})) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected catalog to pick up the new quiz")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,263 @@
+package cluster
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// writeTimeout bounds writing one frame to a connection
+const writeTimeout = 5 * time.Second
+
+// frame is one message of the broker protocol: a request from a Client, the
+// reply to it, or a published message pushed to a subscriber. Frames are
+// JSON objects, one per line.
+type frame struct {
+	ID      uint64   `json:"id,omitempty"` // Matches a reply to its request
+	Op      string   `json:"op"`
+	Key     string   `json:"key,omitempty"` // Key, lease or topic; prefix for keys
+	Value   []byte   `json:"value,omitempty"`
+	Version uint64   `json:"version,omitempty"` // Version of the value, 0 when there is none
+	Owner   string   `json:"owner,omitempty"`
+	TTL     int64    `json:"ttl_ms,omitempty"`
+	OK      bool     `json:"ok,omitempty"`
+	Keys    []string `json:"keys,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// Broker operations
+const (
+	opPublish     = "publish"
+	opSubscribe   = "subscribe"
+	opUnsubscribe = "unsubscribe"
+	opMessage     = "message" // Pushed to subscribers
+	opGet         = "get"
+	opSet         = "set"
+	opDelete      = "delete"
+	opKeys        = "keys"
+	opAcquire     = "acquire"
+	opRelease     = "release"
+	opReply       = "reply"
+)
+
+// entry is a stored value
+type entry struct {
+	value   []byte
+	version uint64
+}
+
+// Broker is the server every instance of a cluster connects to. It relays
+// published messages to subscribers, stores versioned values and hands out
+// leases, all in memory. It is meant for development and testing: when it
+// stops, the cluster loses its sessions, and it accepts any connection, so it
+// must only listen on a trusted network.
+type Broker struct {
+	values   map[string]entry
+	leases   leaseTable
+	subs     map[string]map[*peer]bool // topic -> subscribed connections
+	peers    map[*peer]bool
+	listener net.Listener
+	closed   bool
+	mu       sync.Mutex
+}
+
+// peer is a connection to the broker
+type peer struct {
+	conn    net.Conn
+	enc     *json.Encoder
+	topics  map[string]bool
+	writeMu sync.Mutex
+}
+
+// NewBroker creates an empty broker
+func NewBroker() *Broker {
+	return &Broker{
+		values: make(map[string]entry),
+		leases: make(leaseTable),
+		subs:   make(map[string]map[*peer]bool),
+		peers:  make(map[*peer]bool),
+	}
+}
+
+// Serve accepts connections on l until Close is called
+func (b *Broker) Serve(l net.Listener) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return net.ErrClosed
+	}
+	b.listener = l
+	b.mu.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			b.mu.Lock()
+			closed := b.closed
+			b.mu.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+
+		p := &peer{conn: conn, enc: json.NewEncoder(conn), topics: make(map[string]bool)}
+		b.mu.Lock()
+		b.peers[p] = true
+		b.mu.Unlock()
+		go b.serve(p)
+	}
+}
+
+// Close stops accepting connections and closes the open ones
+func (b *Broker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for p := range b.peers {
+		p.conn.Close()
+	}
+	if b.listener != nil {
+		return b.listener.Close()
+	}
+	return nil
+}
+
+// serve handles the requests of one connection until it closes
+func (b *Broker) serve(p *peer) {
+	defer b.disconnect(p)
+
+	dec := json.NewDecoder(bufio.NewReader(p.conn))
+	for {
+		var f frame
+		if err := dec.Decode(&f); err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				slog.Warn("Broker failed to read request", "error", err, "remote_addr", p.conn.RemoteAddr())
+			}
+			return
+		}
+
+		reply := b.handle(p, f)
+		reply.ID = f.ID
+		reply.Op = opReply
+		if err := p.write(reply); err != nil {
+			return
+		}
+	}
+}
+
+// handle runs one request
+func (b *Broker) handle(p *peer, f frame) frame {
+	switch f.Op {
+	case opPublish:
+		b.publish(f.Key, f.Value)
+		return frame{OK: true}
+	case opSubscribe, opUnsubscribe:
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if f.Op == opSubscribe {
+			if b.subs[f.Key] == nil {
+				b.subs[f.Key] = make(map[*peer]bool)
+			}
+			b.subs[f.Key][p] = true
+			p.topics[f.Key] = true
+		} else {
+			b.unsubscribe(p, f.Key)
+		}
+		return frame{OK: true}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch f.Op {
+	case opGet:
+		e := b.values[f.Key]
+		return frame{OK: e.version > 0, Value: e.value, Version: e.version}
+	case opSet:
+		// Only the holder of the current version may replace a value
+		e := b.values[f.Key]
+		if f.Version != e.version {
+			return frame{Version: e.version}
+		}
+		b.values[f.Key] = entry{value: f.Value, version: e.version + 1}
+		return frame{OK: true, Version: e.version + 1}
+	case opDelete:
+		delete(b.values, f.Key)
+		return frame{OK: true}
+	case opKeys:
+		keys := []string{}
+		for key := range b.values {
+			if strings.HasPrefix(key, f.Key) {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		return frame{OK: true, Keys: keys}
+	case opAcquire:
+		return frame{OK: b.leases.acquire(f.Key, f.Owner, time.Duration(f.TTL)*time.Millisecond)}
+	case opRelease:
+		b.leases.release(f.Key, f.Owner)
+		return frame{OK: true}
+	}
+	return frame{Error: "unknown operation " + f.Op}
+}
+
+// publish pushes a message to every connection subscribed to topic
+func (b *Broker) publish(topic string, data []byte) {
+	b.mu.Lock()
+	peers := make([]*peer, 0, len(b.subs[topic]))
+	for p := range b.subs[topic] {
+		peers = append(peers, p)
+	}
+	b.mu.Unlock()
+
+	for _, p := range peers {
+		if err := p.write(frame{Op: opMessage, Key: topic, Value: data}); err != nil {
+			slog.Warn("Broker failed to deliver message", "error", err, "topic", topic, "remote_addr", p.conn.RemoteAddr())
+		}
+	}
+}
+
+// unsubscribe removes a connection from a topic; the caller must hold the lock
+func (b *Broker) unsubscribe(p *peer, topic string) {
+	delete(b.subs[topic], p)
+	if len(b.subs[topic]) == 0 {
+		delete(b.subs, topic)
+	}
+	delete(p.topics, topic)
+}
+
+// disconnect forgets a closed connection
+func (b *Broker) disconnect(p *peer) {
+	p.conn.Close()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for topic := range p.topics {
+		b.unsubscribe(p, topic)
+	}
+	delete(b.peers, p)
+}
+
+// write sends a frame, closing the connection if it cannot be written
+func (p *peer) write(f frame) error {
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+
+	p.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if err := p.enc.Encode(f); err != nil {
+		p.conn.Close()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,335 @@
+package cluster
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// requestTimeout bounds a request to the broker
+	requestTimeout = 5 * time.Second
+	// maxRedialDelay caps the backoff between reconnection attempts
+	maxRedialDelay = 5 * time.Second
+)
+
+var (
+	// ErrClosed is returned by a Client after Close
+	ErrClosed = errors.New("broker client closed")
+	// ErrDisconnected is returned while the connection to the broker is down
+	ErrDisconnected = errors.New("not connected to broker")
+)
+
+// Client connects a server instance to a Broker. Besides PubSub and Leaser,
+// it stores versioned values that instances update with compare-and-set. When
+// the connection drops the client reconnects and subscribes again; requests
+// made in the meantime fail with ErrDisconnected.
+type Client struct {
+	addr    string
+	conn    net.Conn // nil while disconnected
+	pending map[uint64]pendingRequest
+	nextID  uint64
+	subs    subscriptions
+	inbox   []frame       // Published messages waiting to be delivered
+	wake    chan struct{} // Signals deliver that the inbox has messages
+	closed  bool
+	done    chan struct{}
+	mu      sync.Mutex
+	writeMu sync.Mutex
+}
+
+// pendingRequest is a request waiting for its reply on a connection
+type pendingRequest struct {
+	conn    net.Conn
+	replies chan frame
+}
+
+// Dial connects to the broker at addr
+func Dial(addr string) (*Client, error) {
+	conn, err := net.DialTimeout("tcp", addr, requestTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to broker %s: %w", addr, err)
+	}
+
+	c := &Client{
+		addr:    addr,
+		conn:    conn,
+		pending: make(map[uint64]pendingRequest),
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	go c.readLoop(conn)
+	go c.deliver()
+	return c, nil
+}
+
+// Close disconnects from the broker
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	close(c.done)
+	if c.conn != nil {
+		return c.conn.Close()
+	}
+	return nil
+}
+
+// Publish sends data to the subscribers of topic on every instance
+func (c *Client) Publish(topic string, data []byte) error {
+	_, err := c.request(frame{Op: opPublish, Key: topic, Value: data})
+	return err
+}
+
+// Subscribe calls fn with every message published to topic. Handlers run one
+// at a time on a goroutine of their own, so they may use the client.
+func (c *Client) Subscribe(topic string, fn func(data []byte)) (func(), error) {
+	c.mu.Lock()
+	first := c.subs.topics[topic] == nil
+	id := c.subs.add(topic, fn)
+	c.mu.Unlock()
+
+	// While disconnected the topic is subscribed again on reconnection
+	if first {
+		if _, err := c.request(frame{Op: opSubscribe, Key: topic}); err != nil && !errors.Is(err, ErrDisconnected) {
+			c.mu.Lock()
+			c.subs.remove(topic, id)
+			c.mu.Unlock()
+			return nil, err
+		}
+	}
+
+	return func() {
+		c.mu.Lock()
+		last := c.subs.remove(topic, id)
+		c.mu.Unlock()
+		if last {
+			c.request(frame{Op: opUnsubscribe, Key: topic})
+		}
+	}, nil
+}
+
+// Get returns a value and its version, or a zero version if key is not set
+func (c *Client) Get(key string) ([]byte, uint64, error) {
+	reply, err := c.request(frame{Op: opGet, Key: key})
+	if err != nil {
+		return nil, 0, err
+	}
+	return reply.Value, reply.Version, nil
+}
+
+// Set stores a value if the stored version is still version, where version 0
+// means the key must not be set. It reports false if another write got there
+// first.
+func (c *Client) Set(key string, value []byte, version uint64) (bool, error) {
+	reply, err := c.request(frame{Op: opSet, Key: key, Value: value, Version: version})
+	if err != nil {
+		return false, err
+	}
+	return reply.OK, nil
+}
+
+// Delete removes a value
+func (c *Client) Delete(key string) error {
+	_, err := c.request(frame{Op: opDelete, Key: key})
+	return err
+}
+
+// Keys returns the keys starting with prefix, sorted
+func (c *Client) Keys(prefix string) ([]string, error) {
+	reply, err := c.request(frame{Op: opKeys, Key: prefix})
+	if err != nil {
+		return nil, err
+	}
+	return reply.Keys, nil
+}
+
+// Acquire takes or renews a lease
+func (c *Client) Acquire(key, owner string, ttl time.Duration) (bool, error) {
+	reply, err := c.request(frame{Op: opAcquire, Key: key, Owner: owner, TTL: ttl.Milliseconds()})
+	if err != nil {
+		return false, err
+	}
+	return reply.OK, nil
+}
+
+// Release gives up a lease
+func (c *Client) Release(key, owner string) error {
+	_, err := c.request(frame{Op: opRelease, Key: key, Owner: owner})
+	return err
+}
+
+// request sends a request and waits for its reply
+func (c *Client) request(f frame) (frame, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return frame{}, ErrClosed
+	}
+	conn := c.conn
+	if conn == nil {
+		c.mu.Unlock()
+		return frame{}, ErrDisconnected
+	}
+	c.nextID++
+	f.ID = c.nextID
+	replies := make(chan frame, 1)
+	c.pending[f.ID] = pendingRequest{conn: conn, replies: replies}
+	c.mu.Unlock()
+
+	c.writeMu.Lock()
+	conn.SetWriteDeadline(time.Now().Add(requestTimeout))
+	err := json.NewEncoder(conn).Encode(f)
+	c.writeMu.Unlock()
+	if err != nil {
+		conn.Close() // readLoop notices and reconnects
+		c.forget(f.ID)
+		return frame{}, fmt.Errorf("broker %s failed: %w", f.Op, err)
+	}
+
+	timer := time.NewTimer(requestTimeout)
+	defer timer.Stop()
+	select {
+	case reply, ok := <-replies:
+		if !ok {
+			return frame{}, ErrDisconnected
+		}
+		if reply.Error != "" {
+			return frame{}, fmt.Errorf("broker %s failed: %s", f.Op, reply.Error)
+		}
+		return reply, nil
+	case <-timer.C:
+		c.forget(f.ID)
+		return frame{}, fmt.Errorf("broker %s timed out", f.Op)
+	}
+}
+
+func (c *Client) forget(id uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pending, id)
+}
+
+// readLoop reads replies and published messages until the connection fails
+func (c *Client) readLoop(conn net.Conn) {
+	dec := json.NewDecoder(bufio.NewReader(conn))
+	for {
+		var f frame
+		if err := dec.Decode(&f); err != nil {
+			c.disconnected(conn, err)
+			return
+		}
+
+		c.mu.Lock()
+		if f.Op == opMessage {
+			c.inbox = append(c.inbox, f)
+			select {
+			case c.wake <- struct{}{}:
+			default:
+			}
+		} else if p, ok := c.pending[f.ID]; ok && p.conn == conn {
+			delete(c.pending, f.ID)
+			p.replies <- f
+		}
+		c.mu.Unlock()
+	}
+}
+
+// deliver hands published messages to the subscribers in order. It runs apart
+// from readLoop so handlers can make requests of their own.
+func (c *Client) deliver() {
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-c.wake:
+		}
+
+		c.mu.Lock()
+		messages := c.inbox
+		c.inbox = nil
+		c.mu.Unlock()
+
+		for _, m := range messages {
+			c.mu.Lock()
+			fns := c.subs.handlers(m.Key)
+			c.mu.Unlock()
+			for _, fn := range fns {
+				fn(m.Value)
+			}
+		}
+	}
+}
+
+// disconnected fails the requests waiting on a broken connection and starts
+// reconnecting. Requests already sent on a newer connection are left alone.
+func (c *Client) disconnected(conn net.Conn, err error) {
+	conn.Close()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for id, p := range c.pending {
+		if p.conn == conn {
+			close(p.replies)
+			delete(c.pending, id)
+		}
+	}
+	if c.closed || c.conn != conn {
+		return
+	}
+	c.conn = nil
+
+	slog.Warn("Broker connection lost", "error", err, "addr", c.addr)
+	go c.redial()
+}
+
+// redial reconnects with exponential backoff and subscribes again
+func (c *Client) redial() {
+	delay := 100 * time.Millisecond
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-time.After(delay):
+		}
+
+		conn, err := net.DialTimeout("tcp", c.addr, requestTimeout)
+		if err != nil {
+			slog.Warn("Broker reconnection failed", "error", err, "addr", c.addr, "retry_in", delay.String())
+			delay = min(2*delay, maxRedialDelay)
+			continue
+		}
+
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			conn.Close()
+			return
+		}
+		c.conn = conn
+		topics := make([]string, 0, len(c.subs.topics))
+		for topic := range c.subs.topics {
+			topics = append(topics, topic)
+		}
+		c.mu.Unlock()
+		go c.readLoop(conn)
+
+		for _, topic := range topics {
+			if _, err := c.request(frame{Op: opSubscribe, Key: topic}); err != nil {
+				slog.Error("Broker failed to subscribe again", "error", err, "topic", topic)
+			}
+		}
+		slog.Info("Broker reconnected", "addr", c.addr, "topics", len(topics))
+		return
+	}
+}
@@ -0,0 +1,150 @@
+// Package cluster lets several server instances serve the same quizzes.
+// Broadcasts travel over a PubSub so every instance can deliver them to its
+// own connections, and a Leaser makes sure a single instance runs the timers
+// of each game. Local implements both for a server running alone; Client
+// shares them between instances through a Broker.
+//
+// The Broker is a stand-in for development and testing: it is a single
+// process with no persistence, replication or authentication. Production
+// clusters should implement PubSub, Leaser and quiz.KV on a real backend
+// instead.
+package cluster
+
+import (
+	"sync"
+	"time"
+)
+
+// PubSub delivers published messages to every subscriber of a topic
+type PubSub interface {
+	// Publish sends data to the subscribers of topic
+	Publish(topic string, data []byte) error
+	// Subscribe calls fn with every message published to topic, in the order
+	// they were published, until cancel is called
+	Subscribe(topic string, fn func(data []byte)) (cancel func(), err error)
+}
+
+// Leaser hands out leases. A lease makes one owner responsible for a key
+// until it is released or expires without being renewed.
+type Leaser interface {
+	// Acquire takes the lease on key for owner, or renews it if owner already
+	// holds it. It reports false while another owner holds the lease.
+	Acquire(key, owner string, ttl time.Duration) (bool, error)
+	// Release gives up a lease held by owner
+	Release(key, owner string) error
+}
+
+// lease is the current holder of a key
+type lease struct {
+	owner   string
+	expires time.Time
+}
+
+// leaseTable holds leases in memory; the caller must synchronise access
+type leaseTable map[string]lease
+
+func (t leaseTable) acquire(key, owner string, ttl time.Duration) bool {
+	now := time.Now()
+	if l, ok := t[key]; ok && l.owner != owner && now.Before(l.expires) {
+		return false
+	}
+	t[key] = lease{owner: owner, expires: now.Add(ttl)}
+	return true
+}
+
+func (t leaseTable) release(key, owner string) {
+	if t[key].owner == owner {
+		delete(t, key)
+	}
+}
+
+// subscriptions holds message handlers by topic; the caller must synchronise access
+type subscriptions struct {
+	topics map[string]map[uint64]func([]byte)
+	nextID uint64
+}
+
+func (s *subscriptions) add(topic string, fn func([]byte)) uint64 {
+	if s.topics == nil {
+		s.topics = make(map[string]map[uint64]func([]byte))
+	}
+	if s.topics[topic] == nil {
+		s.topics[topic] = make(map[uint64]func([]byte))
+	}
+	s.nextID++
+	s.topics[topic][s.nextID] = fn
+	return s.nextID
+}
+
+// remove drops a handler and reports whether the topic has none left
+func (s *subscriptions) remove(topic string, id uint64) bool {
+	delete(s.topics[topic], id)
+	if len(s.topics[topic]) > 0 {
+		return false
+	}
+	delete(s.topics, topic)
+	return true
+}
+
+func (s *subscriptions) handlers(topic string) []func([]byte) {
+	fns := make([]func([]byte), 0, len(s.topics[topic]))
+	for _, fn := range s.topics[topic] {
+		fns = append(fns, fn)
+	}
+	return fns
+}
+
+// Local is the PubSub and Leaser of a server running on its own. Messages are
+// delivered synchronously by Publish.
+type Local struct {
+	subs   subscriptions
+	leases leaseTable
+	mu     sync.Mutex
+}
+
+// NewLocal creates an in-process PubSub and Leaser
+func NewLocal() *Local {
+	return &Local{leases: make(leaseTable)}
+}
+
+// Publish calls the subscribers of topic
+func (l *Local) Publish(topic string, data []byte) error {
+	l.mu.Lock()
+	fns := l.subs.handlers(topic)
+	l.mu.Unlock()
+
+	for _, fn := range fns {
+		fn(data)
+	}
+	return nil
+}
+
+// Subscribe registers fn for the messages published to topic
+func (l *Local) Subscribe(topic string, fn func(data []byte)) (func(), error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	id := l.subs.add(topic, fn)
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.subs.remove(topic, id)
+	}, nil
+}
+
+// Acquire takes or renews a lease
+func (l *Local) Acquire(key, owner string, ttl time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.leases.acquire(key, owner, ttl), nil
+}
+
+// Release gives up a lease
+func (l *Local) Release(key, owner string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.leases.release(key, owner)
+	return nil
+}
@@ -0,0 +1,259 @@
+package cluster
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// startBroker runs a broker on a local port and returns its address
+func startBroker(t *testing.T) (*Broker, string) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	b := NewBroker()
+	go b.Serve(l)
+	t.Cleanup(func() { b.Close() })
+	return b, l.Addr().String()
+}
+
+func dial(t *testing.T, addr string) *Client {
+	t.Helper()
+	c, err := Dial(addr)
+	if err != nil {
+		t.Fatalf("Failed to dial broker: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// collect subscribes to a topic and returns the channel messages arrive on
+func collect(t *testing.T, ps PubSub, topic string) (<-chan string, func()) {
+	t.Helper()
+	messages := make(chan string, 16)
+	cancel, err := ps.Subscribe(topic, func(data []byte) { messages <- string(data) })
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	return messages, cancel
+}
+
+func expectMessage(t *testing.T, messages <-chan string, want string) {
+	t.Helper()
+	select {
+	case got := <-messages:
+		if got != want {
+			t.Errorf("Expected message %q, got %q", want, got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Timed out waiting for %q", want)
+	}
+}
+
+func expectNoMessage(t *testing.T, messages <-chan string) {
+	t.Helper()
+	select {
+	case got := <-messages:
+		t.Errorf("Expected no message, got %q", got)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestLocalPubSub(t *testing.T) {
+	local := NewLocal()
+	messages, cancel := collect(t, local, "quiz")
+	other, _ := collect(t, local, "other")
+
+	local.Publish("quiz", []byte("one"))
+	local.Publish("quiz", []byte("two"))
+	expectMessage(t, messages, "one")
+	expectMessage(t, messages, "two")
+	expectNoMessage(t, other)
+
+	cancel()
+	local.Publish("quiz", []byte("three"))
+	expectNoMessage(t, messages)
+}
+
+func TestBrokerPubSub(t *testing.T) {
+	_, addr := startBroker(t)
+	publisher := dial(t, addr)
+	subscriber := dial(t, addr)
+
+	messages, cancel := collect(t, subscriber, "quiz")
+	own, _ := collect(t, publisher, "quiz")
+
+	for _, msg := range []string{"one", "two", "three"} {
+		if err := publisher.Publish("quiz", []byte(msg)); err != nil {
+			t.Fatalf("Failed to publish: %v", err)
+		}
+	}
+
+	// Every instance gets every message in order, the publisher included
+	for _, msg := range []string{"one", "two", "three"} {
+		expectMessage(t, messages, msg)
+		expectMessage(t, own, msg)
+	}
+
+	cancel()
+	publisher.Publish("quiz", []byte("four"))
+	expectMessage(t, own, "four")
+	expectNoMessage(t, messages)
+}
+
+func TestBrokerValues(t *testing.T) {
+	_, addr := startBroker(t)
+	a := dial(t, addr)
+	b := dial(t, addr)
+
+	if _, version, err := a.Get("session/x"); err != nil || version != 0 {
+		t.Fatalf("Expected missing key, got version %d and error %v", version, err)
+	}
+
+	if ok, err := a.Set("session/x", []byte("1"), 0); err != nil || !ok {
+		t.Fatalf("Expected create to succeed, got %v and error %v", ok, err)
+	}
+	if ok, _ := b.Set("session/x", []byte("2"), 0); ok {
+		t.Error("Expected second create to fail")
+	}
+
+	value, version, _ := b.Get("session/x")
+	if string(value) != "1" || version != 1 {
+		t.Errorf("Expected value 1 at version 1, got %q at version %d", value, version)
+	}
+
+	// Only a writer that saw the current version may replace the value
+	if ok, _ := b.Set("session/x", []byte("2"), version); !ok {
+		t.Error("Expected update at current version to succeed")
+	}
+	if ok, _ := a.Set("session/x", []byte("3"), version); ok {
+		t.Error("Expected update at stale version to fail")
+	}
+
+	a.Set("session/y", []byte("y"), 0)
+	a.Set("other", []byte("z"), 0)
+	keys, err := b.Keys("session/")
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	if len(keys) != 2 || keys[0] != "session/x" || keys[1] != "session/y" {
+		t.Errorf("Expected session keys, got %v", keys)
+	}
+
+	a.Delete("session/x")
+	if _, version, _ := b.Get("session/x"); version != 0 {
+		t.Errorf("Expected deleted key, got version %d", version)
+	}
+}
+
+func TestLeases(t *testing.T) {
+	_, addr := startBroker(t)
+	leasers := map[string]func() (Leaser, Leaser){
+		"local": func() (Leaser, Leaser) {
+			l := NewLocal()
+			return l, l
+		},
+		"broker": func() (Leaser, Leaser) { return dial(t, addr), dial(t, addr) },
+	}
+
+	for name, newLeasers := range leasers {
+		t.Run(name, func(t *testing.T) {
+			a, b := newLeasers()
+			key := "game/" + name
+
+			if ok, err := a.Acquire(key, "a", time.Minute); err != nil || !ok {
+				t.Fatalf("Expected a to acquire the lease, got %v and error %v", ok, err)
+			}
+			if ok, _ := b.Acquire(key, "b", time.Minute); ok {
+				t.Error("Expected b to be refused while a holds the lease")
+			}
+			if ok, _ := a.Acquire(key, "a", time.Minute); !ok {
+				t.Error("Expected a to renew its lease")
+			}
+
+			// Releasing someone else's lease does nothing
+			b.Release(key, "b")
+			if ok, _ := b.Acquire(key, "b", time.Minute); ok {
+				t.Error("Expected b to be refused after releasing a lease it does not hold")
+			}
+
+			a.Release(key, "a")
+			if ok, _ := b.Acquire(key, "b", 50*time.Millisecond); !ok {
+				t.Error("Expected b to acquire the released lease")
+			}
+
+			// A lease that is not renewed lapses
+			time.Sleep(100 * time.Millisecond)
+			if ok, _ := a.Acquire(key, "a", time.Minute); !ok {
+				t.Error("Expected a to acquire the expired lease")
+			}
+		})
+	}
+}
+
+func TestClientReconnect(t *testing.T) {
+	broker, addr := startBroker(t)
+	subscriber := dial(t, addr)
+	messages, _ := collect(t, subscriber, "quiz")
+
+	// Replace the broker with a new one on the same address
+	broker.Close()
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to listen again: %v", err)
+	}
+	restarted := NewBroker()
+	go restarted.Serve(l)
+	defer restarted.Close()
+
+	publisher := dial(t, addr)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		publisher.Publish("quiz", []byte("hello"))
+		select {
+		case msg := <-messages:
+			if msg != "hello" {
+				t.Fatalf("Expected hello, got %q", msg)
+			}
+			return
+		case <-time.After(100 * time.Millisecond):
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Subscriber did not subscribe again after reconnecting")
+		}
+	}
+}
+
+func TestDisconnectKeepsNewerRequests(t *testing.T) {
+	old, _ := net.Pipe()
+	current, _ := net.Pipe()
+	defer current.Close()
+
+	stale := make(chan frame, 1)
+	waiting := make(chan frame, 1)
+	c := &Client{
+		conn: current,
+		pending: map[uint64]pendingRequest{
+			1: {conn: old, replies: stale},
+			2: {conn: current, replies: waiting},
+		},
+	}
+
+	// The old connection's read loop ends after the client reconnected
+	c.disconnected(old, errors.New("connection reset"))
+
+	if _, ok := <-stale; ok {
+		t.Error("Expected the request on the old connection to fail")
+	}
+	if _, ok := c.pending[2]; !ok {
+		t.Error("Expected the request on the new connection to keep waiting")
+	}
+	select {
+	case <-waiting:
+		t.Error("Expected the request on the new connection not to be failed")
+	default:
+	}
+}
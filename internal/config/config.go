@@ -0,0 +1,431 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds the server configuration
+type Config struct {
+	Addr            string        // Address to listen on, e.g. ":8080"
+	TLSCert         string        // TLS certificate file; TLS is enabled when set
+	TLSKey          string        // TLS private key file
+	SessionTTL      time.Duration // Sessions older than this are cleaned up
+	CleanupInterval time.Duration // How often expired sessions are cleaned up
+	MaxSessions     int           // Maximum concurrent sessions, 0 for no limit
+	MaxParticipants int           // Maximum participants per session, 0 for no limit
+	LogLevel        string        // debug, info, warn or error
+	ShutdownTimeout time.Duration // Deadline for draining games and connections on shutdown
+	DrainGames      bool          // Let games in progress finish before shutting down
+	AdminToken      string        // Bearer token for /admin endpoints; they are disabled when empty
+
+	// Running several instances; an instance without a broker runs alone
+	Broker       string // Address of the broker shared by all instances
+	BrokerListen string // Address to run a broker on in this process
+	NodeID       string // Name of this instance, defaults to the host name and process ID
+
+	// Default timings, in seconds, for quizzes that do not set their own
+	TimePerQuestion      int
+	TimeBetweenQuestions int
+	TimeBetweenRounds    int
+	RoundIntroTime       int
+
+	LibraryDir string // Directory saved quizzes are stored in, absolute; empty keeps them in memory
+	CatalogDir string // Overrides the embedded built-in quizzes
+	WebDir     string // Overrides the embedded templates and static files
+
+	ConfigFile  string // JSON file the configuration was read from
+	PrintConfig bool   // Print the effective configuration and exit
+}
+
+// setting describes a configuration option and where it can be set from
+type setting struct {
+	name  string   // Flag name and config file key
+	env   []string // Environment variables, in order of preference
+	usage string
+	path  bool // A file or directory; relative values are resolved against the config file's directory if set there, else the working directory
+	bind  func(fs *flag.FlagSet, c *Config, name, usage string)
+	value func(c *Config) interface{}
+}
+
+var settings = []setting{
+	{
+		name:  "addr",
+		env:   []string{"QUICKWIZ_ADDR"},
+		usage: "address to listen on",
+		bind:  func(fs *flag.FlagSet, c *Config, n, u string) { fs.StringVar(&c.Addr, n, ":8080", u) },
+		value: func(c *Config) interface{} { return c.Addr },
+	},
+	{
+		name:  "tls-cert",
+		env:   []string{"QUICKWIZ_TLS_CERT"},
+		usage: "TLS certificate file (enables HTTPS together with -tls-key)",
+		path:  true,
+		bind:  func(fs *flag.FlagSet, c *Config, n, u string) { fs.StringVar(&c.TLSCert, n, "", u) },
+		value: func(c *Config) interface{} { return c.TLSCert },
+	},
+	{
+		name:  "tls-key",
+		env:   []string{"QUICKWIZ_TLS_KEY"},
+		usage: "TLS private key file",
+		path:  true,
+		bind:  func(fs *flag.FlagSet, c *Config, n, u string) { fs.StringVar(&c.TLSKey, n, "", u) },
+		value: func(c *Config) interface{} { return c.TLSKey },
+	},
+	{
+		name:  "session-ttl",
+		env:   []string{"QUICKWIZ_SESSION_TTL"},
+		usage: "how long sessions are kept before cleanup",
+		bind:  func(fs *flag.FlagSet, c *Config, n, u string) { fs.DurationVar(&c.SessionTTL, n, 24*time.Hour, u) },
+		value: func(c *Config) interface{} { return c.SessionTTL.String() },
+	},
+	{
+		name:  "cleanup-interval",
+		env:   []string{"QUICKWIZ_CLEANUP_INTERVAL"},
+		usage: "how often expired sessions are cleaned up",
+		bind:  func(fs *flag.FlagSet, c *Config, n, u string) { fs.DurationVar(&c.CleanupInterval, n, time.Hour, u) },
+		value: func(c *Config) interface{} { return c.CleanupInterval.String() },
+	},
+	{
+		name:  "max-sessions",
+		env:   []string{"QUICKWIZ_MAX_SESSIONS"},
+		usage: "maximum concurrent quiz sessions (0 for no limit)",
+		bind:  func(fs *flag.FlagSet, c *Config, n, u string) { fs.IntVar(&c.MaxSessions, n, 0, u) },
+		value: func(c *Config) interface{} { return c.MaxSessions },
+	},
+	{
+		name:  "max-participants",
+		env:   []string{"QUICKWIZ_MAX_PARTICIPANTS"},
+		usage: "maximum participants per session (0 for no limit)",
+		bind:  func(fs *flag.FlagSet, c *Config, n, u string) { fs.IntVar(&c.MaxParticipants, n, 0, u) },
+		value: func(c *Config) interface{} { return c.MaxParticipants },
+	},
+	{
+		name:  "log-level",
+		env:   []string{"QUICKWIZ_LOG_LEVEL"},
+		usage: "log level: debug, info, warn or error",
+		bind:  func(fs *flag.FlagSet, c *Config, n, u string) { fs.StringVar(&c.LogLevel, n, "info", u) },
+		value: func(c *Config) interface{} { return c.LogLevel },
+	},
+	{
+		name:  "shutdown-timeout",
+		env:   []string{"QUICKWIZ_SHUTDOWN_TIMEOUT"},
+		usage: "how long to wait for games and connections to finish on shutdown",
+		bind: func(fs *flag.FlagSet, c *Config, n, u string) {
+			fs.DurationVar(&c.ShutdownTimeout, n, 30*time.Second, u)
+		},
+		value: func(c *Config) interface{} { return c.ShutdownTimeout.String() },
+	},
+	{
+		name:  "drain-games",
+		env:   []string{"QUICKWIZ_DRAIN_GAMES"},
+		usage: "let games in progress finish before shutting down",
+		bind:  func(fs *flag.FlagSet, c *Config, n, u string) { fs.BoolVar(&c.DrainGames, n, true, u) },
+		value: func(c *Config) interface{} { return c.DrainGames },
+	},
+	{
+		name:  "admin-token",
+		env:   []string{"QUICKWIZ_ADMIN_TOKEN"},
+		usage: "bearer token for the /admin endpoints (disabled when empty)",
+		bind:  func(fs *flag.FlagSet, c *Config, n, u string) { fs.StringVar(&c.AdminToken, n, "", u) },
+		value: func(c *Config) interface{} { return redact(c.AdminToken) },
+	},
+	{
+		name:  "broker",
+		env:   []string{"QUICKWIZ_BROKER"},
+		usage: "address of the broker shared by all instances (empty to run alone)",
+		bind:  func(fs *flag.FlagSet, c *Config, n, u string) { fs.StringVar(&c.Broker, n, "", u) },
+		value: func(c *Config) interface{} { return c.Broker },
+	},
+	{
+		name:  "broker-listen",
+		env:   []string{"QUICKWIZ_BROKER_LISTEN"},
+		usage: "address to run a broker on for the other instances (for development and testing)",
+		bind:  func(fs *flag.FlagSet, c *Config, n, u string) { fs.StringVar(&c.BrokerListen, n, "", u) },
+		value: func(c *Config) interface{} { return c.BrokerListen },
+	},
+	{
+		name:  "node-id",
+		env:   []string{"QUICKWIZ_NODE_ID"},
+		usage: "name of this instance in a cluster (defaults to host name and process ID)",
+		bind:  func(fs *flag.FlagSet, c *Config, n, u string) { fs.StringVar(&c.NodeID, n, "", u) },
+		value: func(c *Config) interface{} { return c.NodeID },
+	},
+	{
+		name:  "time-per-question",
+		env:   []string{"QUICKWIZ_TIME_PER_QUESTION"},
+		usage: "default seconds per question",
+		bind:  func(fs *flag.FlagSet, c *Config, n, u string) { fs.IntVar(&c.TimePerQuestion, n, 30, u) },
+		value: func(c *Config) interface{} { return c.TimePerQuestion },
+	},
+	{
+		name:  "time-between-questions",
+		env:   []string{"QUICKWIZ_TIME_BETWEEN_QUESTIONS"},
+		usage: "default seconds between questions",
+		bind:  func(fs *flag.FlagSet, c *Config, n, u string) { fs.IntVar(&c.TimeBetweenQuestions, n, 5, u) },
+		value: func(c *Config) interface{} { return c.TimeBetweenQuestions },
+	},
+	{
+		name:  "time-between-rounds",
+		env:   []string{"QUICKWIZ_TIME_BETWEEN_ROUNDS"},
+		usage: "default seconds the round summary is shown",
+		bind:  func(fs *flag.FlagSet, c *Config, n, u string) { fs.IntVar(&c.TimeBetweenRounds, n, 10, u) },
+		value: func(c *Config) interface{} { return c.TimeBetweenRounds },
+	},
+	{
+		name:  "round-intro-time",
+		env:   []string{"QUICKWIZ_ROUND_INTRO_TIME"},
+		usage: "default seconds the round intro is shown",
+		bind:  func(fs *flag.FlagSet, c *Config, n, u string) { fs.IntVar(&c.RoundIntroTime, n, 5, u) },
+		value: func(c *Config) interface{} { return c.RoundIntroTime },
+	},
+	{
+		name:  "library-dir",
+		env:   []string{"QUICKWIZ_LIBRARY_DIR", "LIBRARY_DIR"},
+		usage: "directory saved quizzes are stored in (empty to keep them in memory)",
+		path:  true,
+		bind:  func(fs *flag.FlagSet, c *Config, n, u string) { fs.StringVar(&c.LibraryDir, n, "", u) },
+		value: func(c *Config) interface{} { return c.LibraryDir },
+	},
+	{
+		name:  "catalog-dir",
+		env:   []string{"QUICKWIZ_CATALOG_DIR", "CATALOG_DIR"},
+		usage: "directory of built-in quizzes (defaults to the embedded ones)",
+		path:  true,
+		bind:  func(fs *flag.FlagSet, c *Config, n, u string) { fs.StringVar(&c.CatalogDir, n, "", u) },
+		value: func(c *Config) interface{} { return c.CatalogDir },
+	},
+	{
+		name:  "web-dir",
+		env:   []string{"QUICKWIZ_WEB_DIR", "WEB_DIR"},
+		usage: "directory of templates and static files (defaults to the embedded ones)",
+		path:  true,
+		bind:  func(fs *flag.FlagSet, c *Config, n, u string) { fs.StringVar(&c.WebDir, n, "", u) },
+		value: func(c *Config) interface{} { return c.WebDir },
+	},
+}
+
+// Load builds the configuration from, in increasing order of precedence, the
+// defaults, the config file, environment variables and command-line flags.
+// The config file is a JSON object keyed by flag name and is read from
+// -config or QUICKWIZ_CONFIG.
+func Load(args []string, getenv func(string) string) (*Config, error) {
+	c := &Config{}
+	fs := flag.NewFlagSet("quickwiz", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	for _, s := range settings {
+		s.bind(fs, c, s.name, s.usage)
+	}
+	fs.StringVar(&c.ConfigFile, "config", getenv("QUICKWIZ_CONFIG"), "JSON config file")
+	fs.BoolVar(&c.PrintConfig, "print-config", false, "print the effective configuration and exit")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.SetOutput(os.Stderr)
+			fs.PrintDefaults()
+		}
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	// Flags given on the command line win over the file and the environment
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	if c.ConfigFile != "" {
+		values, err := readFile(c.ConfigFile)
+		if err != nil {
+			return nil, err
+		}
+		base, err := filepath.Abs(filepath.Dir(c.ConfigFile))
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve config file directory: %w", err)
+		}
+		for key, value := range values {
+			if fs.Lookup(key) == nil || key == "config" || key == "print-config" {
+				return nil, fmt.Errorf("config file: unknown setting '%s'", key)
+			}
+			if explicit[key] {
+				continue
+			}
+			if isPath(key) && value != "" && !filepath.IsAbs(value) {
+				value = filepath.Join(base, value)
+			}
+			if err := fs.Set(key, value); err != nil {
+				return nil, fmt.Errorf("config file: invalid %s: %w", key, err)
+			}
+		}
+	}
+
+	for _, s := range settings {
+		if explicit[s.name] {
+			continue
+		}
+		for _, env := range s.env {
+			if value := getenv(env); value != "" {
+				if err := fs.Set(s.name, value); err != nil {
+					return nil, fmt.Errorf("invalid %s: %w", env, err)
+				}
+				break
+			}
+		}
+	}
+
+	// Paths from flags and the environment are relative to the working
+	// directory; make them absolute so they do not depend on it later
+	for _, s := range settings {
+		f := fs.Lookup(s.name)
+		if !s.path || f.Value.String() == "" || filepath.IsAbs(f.Value.String()) {
+			continue
+		}
+		abs, err := filepath.Abs(f.Value.String())
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", s.name, err)
+		}
+		fs.Set(s.name, abs)
+	}
+
+	// PORT is set by container platforms; honour it unless an address was given
+	if port := getenv("PORT"); port != "" && !explicit["addr"] && getenv("QUICKWIZ_ADDR") == "" {
+		c.Addr = ":" + port
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Validate checks that the configuration is usable
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Addr == "" {
+		errs = append(errs, errors.New("addr must not be empty"))
+	}
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		errs = append(errs, errors.New("tls-cert and tls-key must be set together"))
+	}
+	if c.SessionTTL <= 0 {
+		errs = append(errs, errors.New("session-ttl must be positive"))
+	}
+	if c.CleanupInterval <= 0 {
+		errs = append(errs, errors.New("cleanup-interval must be positive"))
+	}
+	if c.ShutdownTimeout < 0 {
+		errs = append(errs, errors.New("shutdown-timeout must not be negative"))
+	}
+	if c.MaxSessions < 0 {
+		errs = append(errs, errors.New("max-sessions must not be negative"))
+	}
+	if c.MaxParticipants < 0 {
+		errs = append(errs, errors.New("max-participants must not be negative"))
+	}
+	if c.TimePerQuestion <= 0 {
+		errs = append(errs, errors.New("time-per-question must be positive"))
+	}
+	if c.TimeBetweenQuestions < 0 || c.TimeBetweenRounds < 0 || c.RoundIntroTime < 0 {
+		errs = append(errs, errors.New("time-between-questions, time-between-rounds and round-intro-time must not be negative"))
+	}
+	if _, err := parseLevel(c.LogLevel); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// TLS reports whether the server should serve HTTPS
+func (c *Config) TLS() bool {
+	return c.TLSCert != ""
+}
+
+// SlogLevel returns the configured log level
+func (c *Config) SlogLevel() slog.Level {
+	level, _ := parseLevel(c.LogLevel)
+	return level
+}
+
+// Dump writes the effective configuration in the config file format, with
+// secrets redacted
+func (c *Config) Dump(w io.Writer) error {
+	values := make(map[string]interface{}, len(settings))
+	for _, s := range settings {
+		values[s.name] = s.value(c)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(values)
+}
+
+// isPath reports whether a setting names a file or directory
+func isPath(name string) bool {
+	for _, s := range settings {
+		if s.name == name {
+			return s.path
+		}
+	}
+	return false
+}
+
+// readFile reads a JSON config file into flag values
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to decode config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string, len(raw))
+	for key, value := range raw {
+		switch v := value.(type) {
+		case string:
+			values[key] = v
+		case float64:
+			values[key] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			values[key] = strconv.FormatBool(v)
+		default:
+			return nil, fmt.Errorf("config file: %s must be a string, number or boolean", key)
+		}
+	}
+	return values, nil
+}
+
+// redact hides secrets when the configuration is printed
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "REDACTED"
+}
+
+// parseLevel parses a log level name
+func parseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("unknown log-level '%s'", name)
+}
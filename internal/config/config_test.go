@@ -0,0 +1,232 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// env returns a getenv function backed by a map
+func env(values map[string]string) func(string) string {
+	return func(key string) string { return values[key] }
+}
+
+func TestLoadDefaults(t *testing.T) {
+	c, err := Load(nil, env(nil))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if c.Addr != ":8080" {
+		t.Errorf("Expected addr ':8080', got '%s'", c.Addr)
+	}
+	if c.SessionTTL != 24*time.Hour || c.CleanupInterval != time.Hour {
+		t.Errorf("Expected 24h TTL and 1h cleanup, got %v and %v", c.SessionTTL, c.CleanupInterval)
+	}
+	if c.TimePerQuestion != 30 || c.TimeBetweenQuestions != 5 {
+		t.Errorf("Expected default timings 30/5, got %d/%d", c.TimePerQuestion, c.TimeBetweenQuestions)
+	}
+	if c.LibraryDir != "" {
+		t.Errorf("Expected the library to be kept in memory, got '%s'", c.LibraryDir)
+	}
+	if c.TLS() {
+		t.Error("Expected TLS to be disabled by default")
+	}
+	if !c.DrainGames || c.ShutdownTimeout != 30*time.Second {
+		t.Errorf("Expected games to be drained for up to 30s, got %v and %v", c.DrainGames, c.ShutdownTimeout)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "quickwiz.json")
+	os.WriteFile(file, []byte(`{
+		"addr": ":9000",
+		"max-sessions": 100,
+		"max-participants": 50,
+		"session-ttl": "2h",
+		"log-level": "debug",
+		"drain-games": false
+	}`), 0o644)
+
+	c, err := Load(
+		[]string{"-config", file, "-max-participants", "10"},
+		env(map[string]string{
+			"QUICKWIZ_MAX_SESSIONS":     "20",
+			"QUICKWIZ_LOG_LEVEL":        "warn",
+			"QUICKWIZ_MAX_PARTICIPANTS": "30",
+			"LIBRARY_DIR":               "/data/library",
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if c.Addr != ":9000" {
+		t.Errorf("Expected addr from file, got '%s'", c.Addr)
+	}
+	if c.SessionTTL != 2*time.Hour {
+		t.Errorf("Expected session TTL from file, got %v", c.SessionTTL)
+	}
+	if c.MaxSessions != 20 {
+		t.Errorf("Expected environment to override file, got %d", c.MaxSessions)
+	}
+	if c.MaxParticipants != 10 {
+		t.Errorf("Expected flag to override environment and file, got %d", c.MaxParticipants)
+	}
+	if c.LogLevel != "warn" {
+		t.Errorf("Expected log level 'warn', got '%s'", c.LogLevel)
+	}
+	if c.DrainGames {
+		t.Error("Expected drain-games false from file")
+	}
+	if c.LibraryDir != "/data/library" {
+		t.Errorf("Expected legacy LIBRARY_DIR to be honoured, got '%s'", c.LibraryDir)
+	}
+}
+
+func TestLoadPaths(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "etc")
+	os.Mkdir(dir, 0o755)
+	file := filepath.Join(dir, "quickwiz.json")
+	os.WriteFile(file, []byte(`{
+		"library-dir": "library",
+		"catalog-dir": "../quizzes",
+		"web-dir": "web",
+		"tls-cert": "certs/cert.pem",
+		"tls-key": "/keys/key.pem"
+	}`), 0o644)
+
+	// Paths in the config file are relative to it, not the working directory
+	c, err := Load([]string{"-config", file}, env(nil))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	want := map[string]string{
+		"library-dir": filepath.Join(dir, "library"),
+		"catalog-dir": filepath.Join(filepath.Dir(dir), "quizzes"),
+		"web-dir":     filepath.Join(dir, "web"),
+		"tls-cert":    filepath.Join(dir, "certs", "cert.pem"),
+		"tls-key":     "/keys/key.pem",
+	}
+	got := map[string]string{
+		"library-dir": c.LibraryDir,
+		"catalog-dir": c.CatalogDir,
+		"web-dir":     c.WebDir,
+		"tls-cert":    c.TLSCert,
+		"tls-key":     c.TLSKey,
+	}
+	for name := range want {
+		if got[name] != want[name] {
+			t.Errorf("Expected %s '%s', got '%s'", name, want[name], got[name])
+		}
+	}
+
+	// Flags and the environment are relative to the working directory
+	wd, _ := os.Getwd()
+	c, err = Load([]string{"-library-dir", "data/library"}, env(map[string]string{"CATALOG_DIR": "quizzes"}))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if want := filepath.Join(wd, "data", "library"); c.LibraryDir != want {
+		t.Errorf("Expected library dir '%s', got '%s'", want, c.LibraryDir)
+	}
+	if want := filepath.Join(wd, "quizzes"); c.CatalogDir != want {
+		t.Errorf("Expected catalog dir '%s', got '%s'", want, c.CatalogDir)
+	}
+}
+
+func TestLoadPort(t *testing.T) {
+	c, err := Load(nil, env(map[string]string{"PORT": "3000"}))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if c.Addr != ":3000" {
+		t.Errorf("Expected addr ':3000' from PORT, got '%s'", c.Addr)
+	}
+
+	c, err = Load([]string{"-addr", "127.0.0.1:4000"}, env(map[string]string{"PORT": "3000"}))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if c.Addr != "127.0.0.1:4000" {
+		t.Errorf("Expected -addr to override PORT, got '%s'", c.Addr)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+		file string
+	}{
+		{name: "TLS cert without key", args: []string{"-tls-cert", "cert.pem"}},
+		{name: "negative max sessions", args: []string{"-max-sessions", "-1"}},
+		{name: "zero session TTL", args: []string{"-session-ttl", "0s"}},
+		{name: "unknown log level", env: map[string]string{"QUICKWIZ_LOG_LEVEL": "loud"}},
+		{name: "bad duration in environment", env: map[string]string{"QUICKWIZ_CLEANUP_INTERVAL": "often"}},
+		{name: "zero time per question", args: []string{"-time-per-question", "0"}},
+		{name: "unknown flag", args: []string{"-verbose"}},
+		{name: "unknown file key", file: `{"port": 8080}`},
+		{name: "bad file value", file: `{"max-sessions": "many"}`},
+		{name: "malformed file", file: `{"addr":`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			if tt.file != "" {
+				file := filepath.Join(t.TempDir(), "quickwiz.json")
+				os.WriteFile(file, []byte(tt.file), 0o644)
+				args = append(args, "-config", file)
+			}
+			if _, err := Load(args, env(tt.env)); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestDumpRoundTrip(t *testing.T) {
+	c, err := Load([]string{"-max-sessions", "5", "-session-ttl", "90m", "-tls-cert", "c.pem", "-tls-key", "k.pem"}, env(nil))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := c.Dump(&buf); err != nil {
+		t.Fatalf("Failed to dump config: %v", err)
+	}
+	if !json.Valid(buf.Bytes()) || !strings.Contains(buf.String(), `"session-ttl": "1h30m0s"`) {
+		t.Errorf("Unexpected dump: %s", buf.String())
+	}
+
+	// The dump is a valid config file that reproduces the configuration
+	file := filepath.Join(t.TempDir(), "quickwiz.json")
+	os.WriteFile(file, buf.Bytes(), 0o644)
+	loaded, err := Load([]string{"-config", file}, env(nil))
+	if err != nil {
+		t.Fatalf("Failed to load dumped config: %v", err)
+	}
+	loaded.ConfigFile = ""
+	if *loaded != *c {
+		t.Errorf("Expected %+v, got %+v", *c, *loaded)
+	}
+}
+
+func TestDumpRedactsSecrets(t *testing.T) {
+	c, err := Load([]string{"-admin-token", "s3cret"}, env(nil))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	var buf bytes.Buffer
+	c.Dump(&buf)
+	if strings.Contains(buf.String(), "s3cret") {
+		t.Errorf("Expected admin token to be redacted, got %s", buf.String())
+	}
+}
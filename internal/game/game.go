@@ -0,0 +1,315 @@
+// Package game runs the game loop of a started quiz session: the countdown,
+// then for each question its round intro, wagers, timer, answer reveal and
+// round summary, until the quiz finishes.
+package game
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/rkrmr33/quickwiz/internal/clock"
+	"github.com/rkrmr33/quickwiz/internal/models"
+	"github.com/rkrmr33/quickwiz/internal/quiz"
+)
+
+// Output is where a game sends its messages
+type Output interface {
+	// Broadcast sends a message to every connection of the quiz
+	Broadcast(msg models.WebSocketMessage)
+	// BroadcastQuestion sends every connection the current question, with the
+	// options in the order its participant sees them
+	BroadcastQuestion()
+	// BroadcastWagerPhase sends every connection the wager phase of the
+	// current question, with what its participant may stake
+	BroadcastWagerPhase()
+	// BroadcastLeaderboard sends every connection the standings so far, with
+	// where its participant stands
+	BroadcastLeaderboard()
+}
+
+// Game owns the state transitions of a started session. It runs on a single
+// goroutine driven by its clock and by the events sent to it, so transitions
+// never race: the answer is revealed exactly once, whether the time ran out
+// or everyone answered.
+type Game struct {
+	code     string
+	manager  *quiz.Manager
+	out      Output
+	clock    clock.Clock
+	answered chan struct{} // Someone answered; notifications are coalesced
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// New creates the game loop of a started session, timed by the manager's clock
+func New(code string, manager *quiz.Manager, out Output) *Game {
+	return &Game{
+		code:     code,
+		manager:  manager,
+		out:      out,
+		clock:    manager.Clock(),
+		answered: make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Run plays the game until the quiz finishes or Stop is called
+func (g *Game) Run() {
+	defer close(g.done)
+
+	if !g.countdown() {
+		return
+	}
+	for g.playQuestion() {
+	}
+}
+
+// Resume plays a game taken over from another server instance, from where
+// its session is, until the quiz finishes or Stop is called. The step in
+// progress starts over with its full time.
+func (g *Game) Resume() {
+	defer close(g.done)
+
+	session, err := g.manager.GetSession(g.code)
+	if err != nil {
+		return
+	}
+	switch session.State {
+	case models.StateAnswer:
+		if !g.afterReveal() {
+			return
+		}
+	case models.StateQuestion, models.StateWager:
+		// Answers are timed from when the replayed step starts
+		if err := g.manager.RestartQuestion(g.code); err != nil {
+			slog.Error("Error restarting question", "error", err, "code", g.code)
+			return
+		}
+	}
+	for g.playQuestion() {
+	}
+}
+
+// Answered tells the game that a participant answered or wagered, so the game
+// moves on as soon as everyone has. Answers and wagers are also checked every
+// second, which catches those recorded by other server instances.
+func (g *Game) Answered() {
+	select {
+	case g.answered <- struct{}{}:
+	default:
+	}
+}
+
+// Stop ends the game loop where it is, leaving the session as it was
+func (g *Game) Stop() {
+	g.stopOnce.Do(func() { close(g.stop) })
+}
+
+// Done is closed when Run returns
+func (g *Game) Done() <-chan struct{} {
+	return g.done
+}
+
+// countdown counts down to the first question
+func (g *Game) countdown() bool {
+	// Countdown from 3 to 1
+	for i := 3; i >= 1; i-- {
+		g.out.Broadcast(models.NewMessage(models.Countdown{Count: i}))
+		if !g.sleep(time.Second) {
+			return false
+		}
+	}
+
+	// Send "GO!" signal
+	g.out.Broadcast(models.NewMessage(models.Countdown{Count: 0}))
+	return g.sleep(500 * time.Millisecond)
+}
+
+// playQuestion runs the current round's intro if the session is between
+// rounds, then the question, and reports whether another question follows
+func (g *Game) playQuestion() bool {
+	session, err := g.manager.GetSession(g.code)
+	if err != nil {
+		return false
+	}
+
+	if session.State == models.StateRoundIntro {
+		intro, err := g.manager.GetRoundIntro(g.code)
+		if err != nil {
+			slog.Error("Error getting round intro", "error", err, "code", g.code)
+			return false
+		}
+
+		g.out.Broadcast(models.NewMessage(intro))
+		if !g.countDown(seconds(intro.TimeRemaining), nil) {
+			return false
+		}
+
+		if err := g.manager.BeginQuestion(g.code); err != nil {
+			slog.Error("Error beginning question", "error", err, "code", g.code)
+			return false
+		}
+	}
+
+	// Take wagers before the options are shown, unless a resumed game has
+	// already shown them
+	if session.Quiz.Wagers && session.State != models.StateQuestion && !g.takeWagers() {
+		return false
+	}
+
+	// Send question to all participants, each with their own option order
+	g.out.BroadcastQuestion()
+
+	// Wait for time or all answers
+	timePerQuestion, _ := g.manager.CurrentTimings(g.code)
+	allAnswered := func() bool { return g.manager.CheckAllAnswered(g.code) }
+	if !g.countDown(seconds(timePerQuestion), allAnswered) {
+		return false
+	}
+
+	return g.reveal()
+}
+
+// takeWagers shows the current question without its options until everyone
+// has wagered or the wager time runs out, then opens it for answers
+func (g *Game) takeWagers() bool {
+	phase, err := g.manager.GetWagerPhase(g.code, "")
+	if err != nil {
+		slog.Error("Error getting wager phase", "error", err, "code", g.code)
+		return false
+	}
+
+	g.out.BroadcastWagerPhase()
+	allWagered := func() bool { return g.manager.CheckAllWagered(g.code) }
+	if !g.countDown(seconds(phase.TimeRemaining), allWagered) {
+		return false
+	}
+
+	if err := g.manager.OpenAnswers(g.code); err != nil {
+		slog.Error("Error opening answers", "error", err, "code", g.code)
+		return false
+	}
+	return true
+}
+
+// reveal reveals the answer and moves on, reporting whether another question
+// follows
+func (g *Game) reveal() bool {
+	slog.Info("Revealing answer", "code", g.code)
+
+	reveal, err := g.manager.RevealAnswer(g.code)
+	if err != nil {
+		slog.Error("Error revealing answer", "error", err, "code", g.code)
+		return false
+	}
+
+	// Broadcast answer reveal, then the standings it led to
+	g.out.Broadcast(models.NewMessage(reveal))
+	g.out.BroadcastLeaderboard()
+
+	slog.Info("Answer revealed successfully", "code", g.code)
+	return g.afterReveal()
+}
+
+// afterReveal waits while the answer is shown and moves on, reporting whether
+// another question follows
+func (g *Game) afterReveal() bool {
+	// Wait before next question based on quiz settings, sending timer updates
+	_, timeBetweenQuestions := g.manager.CurrentTimings(g.code)
+	if !g.countDown(seconds(timeBetweenQuestions), nil) {
+		return false
+	}
+
+	// Capture the round standings before moving on resets them
+	roundSummary, err := g.manager.GetRoundSummary(g.code)
+	if err != nil {
+		slog.Error("Error getting round summary", "error", err, "code", g.code)
+		return false
+	}
+
+	hasNext, err := g.manager.NextQuestion(g.code)
+	if err != nil {
+		slog.Error("Error moving to next question", "error", err, "code", g.code)
+		return false
+	}
+
+	if !hasNext {
+		// Quiz finished
+		leaderboard, _ := g.manager.GetLeaderboard(g.code)
+		g.out.Broadcast(models.NewMessage(models.QuizFinished{
+			Leaderboard: leaderboard,
+		}))
+		return false
+	}
+
+	// Show the round summary between rounds
+	if roundSummary != nil {
+		g.out.Broadcast(models.NewMessage(roundSummary))
+		return g.countDown(seconds(roundSummary.TimeRemaining), nil)
+	}
+
+	return true
+}
+
+// countDown waits for d of game time, broadcasting the time left every
+// second. The clock stops while the quiz is paused. If early is set, the wait
+// ends once it reports true; it is checked every second and whenever someone
+// answers. countDown reports false if the game was stopped.
+func (g *Game) countDown(d time.Duration, early func() bool) bool {
+	tick := g.clock.NewTimer(time.Second)
+	defer func() { tick.Stop() }()
+
+	var elapsed time.Duration
+	for {
+		select {
+		case <-g.stop:
+			return false
+		case <-g.answered:
+			if early != nil && early() {
+				return true
+			}
+		case <-tick.C():
+			if !g.manager.IsPaused(g.code) {
+				elapsed += time.Second
+				if elapsed >= d || (early != nil && early()) {
+					return true
+				}
+
+				remaining := int((d - elapsed).Seconds())
+				g.out.Broadcast(models.NewMessage(models.TimeUpdate{TimeRemaining: remaining}))
+			}
+			// Start the next tick only once the messages of this one are out,
+			// so a pending timer means the game is waiting
+			tick = g.clock.NewTimer(time.Second)
+		}
+	}
+}
+
+// sleep waits for d of game time without broadcasting it, reporting false if
+// the game was stopped. Like countDown, it checks every second for a pause
+// and the clock stops while the quiz is paused.
+func (g *Game) sleep(d time.Duration) bool {
+	var elapsed time.Duration
+	for elapsed < d {
+		step := min(d-elapsed, time.Second)
+		t := g.clock.NewTimer(step)
+		select {
+		case <-t.C():
+		case <-g.stop:
+			t.Stop()
+			return false
+		}
+
+		if !g.manager.IsPaused(g.code) {
+			elapsed += step
+		}
+	}
+	return true
+}
+
+func seconds(n int) time.Duration {
+	return time.Duration(n) * time.Second
+}
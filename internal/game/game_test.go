@@ -0,0 +1,301 @@
+package game
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rkrmr33/quickwiz/internal/clock"
+	"github.com/rkrmr33/quickwiz/internal/models"
+	"github.com/rkrmr33/quickwiz/internal/quiz"
+)
+
+// recorder is an Output keeping the types of the messages sent
+type recorder struct {
+	types []string
+	mu    sync.Mutex
+}
+
+func (r *recorder) Broadcast(msg models.WebSocketMessage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.types = append(r.types, msg.Type)
+}
+
+func (r *recorder) BroadcastQuestion() {
+	r.Broadcast(models.WebSocketMessage{Type: "question"})
+}
+
+func (r *recorder) BroadcastWagerPhase() {
+	r.Broadcast(models.WebSocketMessage{Type: "wager_phase"})
+}
+
+func (r *recorder) BroadcastLeaderboard() {
+	r.Broadcast(models.WebSocketMessage{Type: "leaderboard"})
+}
+
+func (r *recorder) sent() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.types...)
+}
+
+func (r *recorder) count(msgType string) int {
+	n := 0
+	for _, t := range r.sent() {
+		if t == msgType {
+			n++
+		}
+	}
+	return n
+}
+
+// newGame starts a two-question quiz with two players and returns its game
+func newGame(t *testing.T) (*Game, *quiz.Manager, *clock.Fake, *recorder) {
+	t.Helper()
+	c := clock.NewFake(time.Unix(0, 0))
+	manager := quiz.NewManagerWithOptions(quiz.Options{Clock: c})
+	code, err := manager.CreateSession(models.Quiz{
+		Title:                "Timed",
+		TimePerQuestion:      3,
+		TimeBetweenQuestions: 2,
+		Questions: []models.Question{
+			{Text: "Q1?", Options: []string{"A", "B"}, Answer: "A"},
+			{Text: "Q2?", Options: []string{"A", "B"}, Answer: "B"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	manager.AddParticipant(code, "host", "Host", true)
+	manager.AddParticipant(code, "p1", "Alice", false)
+	manager.AddParticipant(code, "p2", "Bob", false)
+	if err := manager.StartQuiz(code); err != nil {
+		t.Fatalf("Failed to start quiz: %v", err)
+	}
+
+	out := &recorder{}
+	g := New(code, manager, out)
+	go g.Run()
+	t.Cleanup(g.Stop)
+	return g, manager, c, out
+}
+
+// advanceUntil moves the clock in half-second steps while the game waits on
+// it, until done reports true
+func advanceUntil(t *testing.T, c *clock.Fake, done func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out advancing the game")
+		}
+		if c.Pending() == 0 {
+			time.Sleep(time.Millisecond)
+			continue
+		}
+		c.Advance(500 * time.Millisecond)
+	}
+}
+
+func finished(g *Game) func() bool {
+	return func() bool {
+		select {
+		case <-g.Done():
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+// waitFor waits for the game to send a message without moving the clock
+func waitFor(t *testing.T, out *recorder, msgType string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for out.count(msgType) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d %s messages, got %v", n, msgType, out.sent())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestGamePlaysToEnd(t *testing.T) {
+	g, _, c, out := newGame(t)
+	start := c.Now()
+
+	advanceUntil(t, c, finished(g))
+
+	want := []string{
+		"countdown", "countdown", "countdown", "countdown",
+		"question", "time_update", "time_update", "answer_reveal", "leaderboard", "time_update",
+		"question", "time_update", "time_update", "answer_reveal", "leaderboard", "time_update",
+		"quiz_finished",
+	}
+	got := out.sent()
+	if len(got) != len(want) {
+		t.Fatalf("Expected messages %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Expected messages %v, got %v", want, got)
+		}
+	}
+
+	// 3.5s countdown, then 3s per question and 2s between questions
+	if elapsed := c.Now().Sub(start); elapsed != 13500*time.Millisecond {
+		t.Errorf("Expected the game to take 13.5s, took %v", elapsed)
+	}
+}
+
+func TestGameRevealsOnceWhenAllAnswered(t *testing.T) {
+	g, manager, c, out := newGame(t)
+
+	advanceUntil(t, c, func() bool { return out.count("question") == 1 })
+	questionAt := c.Now()
+
+	manager.SubmitAnswer(g.code, "p1", "A")
+	g.Answered()
+	manager.SubmitAnswer(g.code, "p2", "A")
+	g.Answered()
+	g.Answered()
+
+	// The answer is revealed at once, without waiting for the next tick
+	waitFor(t, out, "answer_reveal", 1)
+	if c.Now() != questionAt {
+		t.Errorf("Expected the reveal without the clock moving, it moved %v", c.Now().Sub(questionAt))
+	}
+
+	advanceUntil(t, c, finished(g))
+	if n := out.count("answer_reveal"); n != 2 {
+		t.Errorf("Expected one reveal per question, got %d", n)
+	}
+
+	session, _ := manager.GetSession(g.code)
+	if session.Participants["p1"].Score != 1 || session.Participants["p2"].Score != 1 {
+		t.Errorf("Expected Alice and Bob on 1 point, got %d and %d",
+			session.Participants["p1"].Score, session.Participants["p2"].Score)
+	}
+}
+
+func TestGamePauseStopsClock(t *testing.T) {
+	g, manager, c, out := newGame(t)
+
+	advanceUntil(t, c, func() bool { return out.count("question") == 1 })
+	if err := manager.Pause(g.code); err != nil {
+		t.Fatalf("Failed to pause: %v", err)
+	}
+
+	before := len(out.sent())
+	for i := 0; i < 20; i++ {
+		c.BlockUntil(1)
+		c.Advance(500 * time.Millisecond)
+	}
+	if sent := out.sent(); len(sent) != before {
+		t.Errorf("Expected no messages while paused, got %v", sent[before:])
+	}
+
+	manager.Resume(g.code)
+	advanceUntil(t, c, func() bool { return out.count("answer_reveal") == 1 })
+}
+
+func TestGamePauseHoldsCountdown(t *testing.T) {
+	g, manager, c, out := newGame(t)
+
+	waitFor(t, out, "countdown", 1)
+	if err := manager.Pause(g.code); err != nil {
+		t.Fatalf("Failed to pause: %v", err)
+	}
+
+	for i := 0; i < 20; i++ {
+		c.BlockUntil(1)
+		c.Advance(500 * time.Millisecond)
+	}
+	if sent := out.sent(); len(sent) != 1 {
+		t.Errorf("Expected the countdown to hold while paused, got %v", sent)
+	}
+
+	manager.Resume(g.code)
+	advanceUntil(t, c, func() bool { return out.count("question") == 1 })
+	if n := out.count("countdown"); n != 4 {
+		t.Errorf("Expected the countdown to carry on after resuming, got %v", out.sent())
+	}
+}
+
+func TestGameStop(t *testing.T) {
+	g, manager, c, out := newGame(t)
+
+	advanceUntil(t, c, func() bool { return out.count("question") == 1 })
+	g.Stop()
+
+	select {
+	case <-g.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Game did not stop")
+	}
+
+	// The session is left where it was
+	session, _ := manager.GetSession(g.code)
+	if session.State != models.StateQuestion {
+		t.Errorf("Expected the session to stay in the question state, got %s", session.State)
+	}
+}
+
+func TestGameResume(t *testing.T) {
+	g, manager, c, out := newGame(t)
+
+	// The instance running the game goes away after the first reveal
+	advanceUntil(t, c, func() bool { return out.count("answer_reveal") == 1 })
+	g.Stop()
+	<-g.Done()
+
+	resumed := &recorder{}
+	g = New(g.code, manager, resumed)
+	go g.Resume()
+	t.Cleanup(g.Stop)
+	advanceUntil(t, c, finished(g))
+
+	want := []string{
+		"time_update",
+		"question", "time_update", "time_update", "answer_reveal", "leaderboard", "time_update",
+		"quiz_finished",
+	}
+	got := resumed.sent()
+	if len(got) != len(want) {
+		t.Fatalf("Expected messages %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Expected messages %v, got %v", want, got)
+		}
+	}
+}
+
+func TestGameResumeRestartsQuestion(t *testing.T) {
+	g, manager, c, out := newGame(t)
+
+	// The instance running the game goes away a second into the first question
+	advanceUntil(t, c, func() bool { return out.count("time_update") == 1 })
+	g.Stop()
+	<-g.Done()
+	c.Advance(5 * time.Second)
+
+	resumed := &recorder{}
+	g = New(g.code, manager, resumed)
+	resumedAt := c.Now()
+	go g.Resume()
+	t.Cleanup(g.Stop)
+	waitFor(t, resumed, "question", 1)
+
+	// The replayed question is timed from when it was shown again
+	session, _ := manager.GetSession(g.code)
+	if !session.QuestionStarted.Equal(resumedAt) {
+		t.Errorf("Expected the question to restart at %v, started at %v", resumedAt, session.QuestionStarted)
+	}
+
+	advanceUntil(t, c, func() bool { return resumed.count("answer_reveal") == 1 })
+	if elapsed := c.Now().Sub(resumedAt); elapsed != 3*time.Second {
+		t.Errorf("Expected the question to get its full 3s again, got %v", elapsed)
+	}
+}
@@ -0,0 +1,459 @@
+package game
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rkrmr33/quickwiz/internal/clock"
+	"github.com/rkrmr33/quickwiz/internal/models"
+	"github.com/rkrmr33/quickwiz/internal/quiz"
+)
+
+// simStep is how far the simulated clock moves at a time, and so the
+// resolution of scripted answer times
+const simStep = 100 * time.Millisecond
+
+// answerAt scripts an answer given some time after a question is shown. In
+// quizzes with wagers, a stake is placed as soon as the wager phase starts.
+type answerAt struct {
+	Question    int // Index of the question, from 0
+	At          time.Duration
+	Participant string
+	Answer      string
+	Stake       int
+}
+
+// sentAt is a message the game sent and the game time it was sent at
+type sentAt struct {
+	At  time.Duration // Since the quiz started
+	Msg models.WebSocketMessage
+}
+
+// simulation plays a whole game against a fake clock from a script of
+// answers, recording every message the game sends
+type simulation struct {
+	t        *testing.T
+	clock    *clock.Fake
+	manager  *quiz.Manager
+	code     string
+	game     *Game
+	start    time.Time
+	sent     []sentAt
+	rejected []answerAt // Scripted answers the manager refused
+	viewer   string     // Player whose view of per-participant messages is recorded
+	mu       sync.Mutex
+}
+
+// simulate joins the players, starts the quiz and plays it to the end
+func simulate(t *testing.T, q models.Quiz, players []string, answers []answerAt) *simulation {
+	t.Helper()
+	c := clock.NewFake(time.Unix(0, 0))
+	manager := quiz.NewManagerWithOptions(quiz.Options{Clock: c})
+	code, err := manager.CreateSession(q)
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	for _, name := range players {
+		if err := manager.AddParticipant(code, name, name, false); err != nil {
+			t.Fatalf("Failed to add %s: %v", name, err)
+		}
+	}
+	if err := manager.StartQuiz(code); err != nil {
+		t.Fatalf("Failed to start quiz: %v", err)
+	}
+
+	s := &simulation{t: t, clock: c, manager: manager, code: code, start: c.Now(), viewer: players[0]}
+	s.game = New(code, manager, s)
+	go s.game.Run()
+	t.Cleanup(s.game.Stop)
+
+	s.run(answers)
+	return s
+}
+
+func (s *simulation) Broadcast(msg models.WebSocketMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, sentAt{At: s.clock.Now().Sub(s.start), Msg: msg})
+}
+
+func (s *simulation) BroadcastQuestion() {
+	s.Broadcast(models.WebSocketMessage{Type: "question"})
+}
+
+func (s *simulation) BroadcastWagerPhase() {
+	s.Broadcast(models.WebSocketMessage{Type: "wager_phase"})
+}
+
+// BroadcastLeaderboard records the leaderboard as the first player sees it
+func (s *simulation) BroadcastLeaderboard() {
+	standings, err := s.manager.GetLeaderboardUpdate(s.code, s.viewer)
+	if err != nil {
+		s.t.Errorf("Failed to get leaderboard: %v", err)
+		return
+	}
+	s.Broadcast(models.NewMessage(standings))
+}
+
+// run moves the clock in small steps, giving the scripted answers when they
+// come due, until the game ends
+func (s *simulation) run(answers []answerAt) {
+	s.t.Helper()
+	answers = append([]answerAt(nil), answers...)
+	sort.SliceStable(answers, func(i, j int) bool {
+		if answers[i].Question != answers[j].Question {
+			return answers[i].Question < answers[j].Question
+		}
+		return answers[i].At < answers[j].At
+	})
+
+	deadline := time.Now().Add(5 * time.Second)
+	question, wagers := -1, -1
+	var shownAt time.Time
+	for s.settle(deadline) {
+		if n := len(s.all("wager_phase")); n-1 > wagers {
+			wagers = n - 1
+			s.placeWagers(wagers, answers, deadline)
+		}
+		if n := len(s.all("question")); n-1 > question {
+			question = n - 1
+			shownAt = s.clock.Now()
+		}
+
+		for len(answers) > 0 && answers[0].Question <= question && !s.clock.Now().Before(shownAt.Add(answers[0].At)) {
+			a := answers[0]
+			answers = answers[1:]
+
+			reveals := len(s.all("answer_reveal"))
+			if err := s.manager.SubmitAnswer(s.code, a.Participant, a.Answer); err != nil {
+				s.rejected = append(s.rejected, a)
+				continue
+			}
+			s.game.Answered()
+
+			// Everyone answered, so the game reveals without the clock moving
+			if s.manager.CheckAllAnswered(s.code) {
+				for len(s.all("answer_reveal")) == reveals {
+					s.checkDeadline(deadline)
+					time.Sleep(time.Millisecond)
+				}
+			}
+		}
+
+		s.clock.Advance(simStep)
+	}
+}
+
+// placeWagers places the stakes scripted for a question
+func (s *simulation) placeWagers(question int, answers []answerAt, deadline time.Time) {
+	questions := len(s.all("question"))
+	for _, a := range answers {
+		if a.Question != question || a.Stake == 0 {
+			continue
+		}
+		if err := s.manager.PlaceWager(s.code, a.Participant, a.Stake); err != nil {
+			s.t.Errorf("Failed to place %s's wager on question %d: %v", a.Participant, question+1, err)
+		}
+		s.game.Answered()
+	}
+
+	// Everyone wagered, so the game shows the options without the clock moving
+	if s.manager.CheckAllWagered(s.code) {
+		for len(s.all("question")) == questions {
+			s.checkDeadline(deadline)
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+// settle waits until the game is waiting on the clock, reporting false once
+// the game has ended
+func (s *simulation) settle(deadline time.Time) bool {
+	for s.clock.Pending() == 0 {
+		select {
+		case <-s.game.Done():
+			return false
+		default:
+		}
+		s.checkDeadline(deadline)
+		time.Sleep(time.Millisecond)
+	}
+	return true
+}
+
+func (s *simulation) checkDeadline(deadline time.Time) {
+	if time.Now().After(deadline) {
+		s.t.Fatalf("Simulation stuck after %v of game time", s.clock.Now().Sub(s.start))
+	}
+}
+
+// all returns the messages of a type in the order they were sent
+func (s *simulation) all(msgType string) []sentAt {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var matching []sentAt
+	for _, m := range s.sent {
+		if m.Msg.Type == msgType {
+			matching = append(matching, m)
+		}
+	}
+	return matching
+}
+
+// elapsed returns the game time from the start of the quiz to its end
+func (s *simulation) elapsed() time.Duration {
+	return s.clock.Now().Sub(s.start)
+}
+
+func scores(participants []models.ParticipantInfo) map[string]int {
+	byName := make(map[string]int, len(participants))
+	for _, p := range participants {
+		byName[p.Name] = p.Score
+	}
+	return byName
+}
+
+func TestSimulateGame(t *testing.T) {
+	q := models.Quiz{
+		Title:                "Simulated",
+		TimePerQuestion:      5,
+		TimeBetweenQuestions: 3,
+		QuickestAnswerBonus:  true,
+		Questions: []models.Question{
+			{Text: "Q1?", Options: []string{"A", "B"}, Answer: "A"},
+			{Text: "Q2?", Options: []string{"A", "B"}, Answer: "B"},
+		},
+	}
+	s := simulate(t, q, []string{"alice", "bob", "carol"}, []answerAt{
+		// Everyone answers the first question within two seconds
+		{Question: 0, At: time.Second, Participant: "alice", Answer: "A"},
+		{Question: 0, At: 2 * time.Second, Participant: "bob", Answer: "B"},
+		{Question: 0, At: 500 * time.Millisecond, Participant: "carol", Answer: "A"},
+		// Only alice answers the second in time; bob is too late
+		{Question: 1, At: 4 * time.Second, Participant: "alice", Answer: "B"},
+		{Question: 1, At: 6 * time.Second, Participant: "bob", Answer: "B"},
+	})
+
+	// The countdown takes 3.5s, the first question ends when bob answers and
+	// the second when its time runs out
+	questions := s.all("question")
+	reveals := s.all("answer_reveal")
+	if len(questions) != 2 || len(reveals) != 2 {
+		t.Fatalf("Expected 2 questions and 2 reveals, got %d and %d", len(questions), len(reveals))
+	}
+	wantTimes := []struct{ shown, revealed time.Duration }{
+		{3500 * time.Millisecond, 5500 * time.Millisecond},
+		{8500 * time.Millisecond, 13500 * time.Millisecond},
+	}
+	for i, w := range wantTimes {
+		if questions[i].At != w.shown || reveals[i].At != w.revealed {
+			t.Errorf("Expected question %d shown at %v and revealed at %v, got %v and %v",
+				i+1, w.shown, w.revealed, questions[i].At, reveals[i].At)
+		}
+	}
+	if s.elapsed() != 16500*time.Millisecond {
+		t.Errorf("Expected the game to take 16.5s, took %v", s.elapsed())
+	}
+
+	// Carol was quickest to the first question, alice alone got the second
+	first := reveals[0].Msg.Payload.(*models.AnswerReveal)
+	for _, p := range first.Participants {
+		if p.QuickestAnswerFlag != (p.Name == "carol") {
+			t.Errorf("Expected only carol to be quickest, %s flagged %v", p.Name, p.QuickestAnswerFlag)
+		}
+	}
+	if len(s.rejected) != 1 || s.rejected[0].Participant != "bob" {
+		t.Errorf("Expected bob's late answer to be rejected, rejected %v", s.rejected)
+	}
+
+	finished := s.all("quiz_finished")
+	if len(finished) != 1 {
+		t.Fatalf("Expected one quiz_finished, got %d", len(finished))
+	}
+	leaderboard := finished[0].Msg.Payload.(models.QuizFinished).Leaderboard
+	want := map[string]int{"alice": 3, "carol": 2, "bob": 0}
+	got := scores(leaderboard)
+	for name, score := range want {
+		if got[name] != score {
+			t.Errorf("Expected %s on %d points, got %d", name, score, got[name])
+		}
+	}
+	if leaderboard[0].Name != "alice" {
+		t.Errorf("Expected alice to lead, got %s", leaderboard[0].Name)
+	}
+
+	// Each reveal is followed by the standings, with the places each player
+	// moved since the previous question
+	standings := s.all("leaderboard")
+	if len(standings) != 2 {
+		t.Fatalf("Expected 2 leaderboards, got %d", len(standings))
+	}
+	wantStandings := []string{
+		"1 carol (0), 2 alice (0), 3 bob (0); you 2 alice",
+		"1 alice (1), 2 carol (-1), 3 bob (0); you 1 alice",
+	}
+	for i, sent := range standings {
+		if sent.At != reveals[i].At {
+			t.Errorf("Expected leaderboard %d with its reveal at %v, got %v", i+1, reveals[i].At, sent.At)
+		}
+		update := sent.Msg.Payload.(*models.Leaderboard)
+		var places []string
+		for _, p := range update.Top {
+			places = append(places, fmt.Sprintf("%d %s (%d)", p.Rank, p.Name, p.RankChange))
+		}
+		got := fmt.Sprintf("%s; you %d %s", strings.Join(places, ", "), update.You.Rank, update.You.Name)
+		if got != wantStandings[i] {
+			t.Errorf("Expected leaderboard %d to be %q, got %q", i+1, wantStandings[i], got)
+		}
+	}
+}
+
+func TestSimulateTimeouts(t *testing.T) {
+	q := models.Quiz{
+		Title:                "Silent",
+		TimePerQuestion:      3,
+		TimeBetweenQuestions: 2,
+		Questions: []models.Question{
+			{Text: "Q1?", Options: []string{"A", "B"}, Answer: "A"},
+		},
+	}
+	s := simulate(t, q, []string{"alice", "bob"}, nil)
+
+	// Nobody answers, so the time runs out second by second
+	var remaining []int
+	for _, m := range s.all("time_update") {
+		remaining = append(remaining, m.Msg.Payload.(models.TimeUpdate).TimeRemaining)
+	}
+	want := []int{2, 1, 1}
+	if len(remaining) != len(want) {
+		t.Fatalf("Expected time updates %v, got %v", want, remaining)
+	}
+	for i := range want {
+		if remaining[i] != want[i] {
+			t.Fatalf("Expected time updates %v, got %v", want, remaining)
+		}
+	}
+
+	reveals := s.all("answer_reveal")
+	if len(reveals) != 1 || reveals[0].At != 6500*time.Millisecond {
+		t.Fatalf("Expected one reveal at 6.5s, got %v", reveals)
+	}
+	for _, p := range reveals[0].Msg.Payload.(*models.AnswerReveal).Participants {
+		if p.Score != 0 || p.Answer != "" || p.AnswerSubmissionTime != 0 {
+			t.Errorf("Expected %s to score nothing without answering, got %+v", p.Name, p)
+		}
+	}
+}
+
+func TestSimulateWagers(t *testing.T) {
+	q := models.Quiz{
+		Title:                "Wagers",
+		TimePerQuestion:      5,
+		TimeBetweenQuestions: 2,
+		Wagers:               true,
+		WagerTime:            5,
+		Questions: []models.Question{
+			{Text: "Q1?", Options: []string{"A", "B"}, Answer: "A"},
+			{Text: "Q2?", Options: []string{"A", "B"}, Answer: "B"},
+		},
+	}
+	s := simulate(t, q, []string{"alice", "bob"}, []answerAt{
+		// Nobody has points to stake on the first question, so no one wagers
+		{Question: 0, At: time.Second, Participant: "alice", Answer: "A"},
+		{Question: 0, At: time.Second, Participant: "bob", Answer: "A"},
+		// Both stake their point on the second
+		{Question: 1, At: time.Second, Participant: "alice", Answer: "B", Stake: 1},
+		{Question: 1, At: time.Second, Participant: "bob", Answer: "A", Stake: 1},
+	})
+
+	// The first wager phase runs its full time, the second ends once both wagered
+	phases, questions := s.all("wager_phase"), s.all("question")
+	if len(phases) != 2 || len(questions) != 2 {
+		t.Fatalf("Expected 2 wager phases and 2 questions, got %d and %d", len(phases), len(questions))
+	}
+	wantTimes := []struct{ wager, shown time.Duration }{
+		{3500 * time.Millisecond, 8500 * time.Millisecond},
+		{11500 * time.Millisecond, 11500 * time.Millisecond},
+	}
+	for i, w := range wantTimes {
+		if phases[i].At != w.wager || questions[i].At != w.shown {
+			t.Errorf("Expected question %d to take wagers at %v and show options at %v, got %v and %v",
+				i+1, w.wager, w.shown, phases[i].At, questions[i].At)
+		}
+	}
+
+	// Answer times count from the options being shown
+	reveals := s.all("answer_reveal")
+	last := reveals[len(reveals)-1].Msg.Payload.(*models.AnswerReveal)
+	wantWagers := map[string]int{"alice": 1, "bob": -1}
+	for _, p := range last.Participants {
+		if p.AnswerSubmissionTime != 1 || p.Wager != wantWagers[p.Name] {
+			t.Errorf("Expected %s to answer in 1s and win %d on the stake, got %vs and %d",
+				p.Name, wantWagers[p.Name], p.AnswerSubmissionTime, p.Wager)
+		}
+	}
+
+	finished := s.all("quiz_finished")
+	if len(finished) != 1 {
+		t.Fatalf("Expected one quiz_finished, got %d", len(finished))
+	}
+	got := scores(finished[0].Msg.Payload.(models.QuizFinished).Leaderboard)
+	if got["alice"] != 3 || got["bob"] != 0 {
+		t.Errorf("Expected alice on 3 and bob on 0, got %d and %d", got["alice"], got["bob"])
+	}
+}
+
+func TestSimulateSuddenDeath(t *testing.T) {
+	q := models.Quiz{
+		Title:                "Sudden Death",
+		TimePerQuestion:      5,
+		TimeBetweenQuestions: 2,
+		Questions: []models.Question{
+			{Text: "Q1?", Options: []string{"A", "B"}, Answer: "A"},
+		},
+		SuddenDeath: []models.Question{
+			{Text: "Tiebreak?", Options: []string{"A", "B"}, Answer: "B"},
+		},
+	}
+	s := simulate(t, q, []string{"alice", "bob", "carol"}, []answerAt{
+		{Question: 0, At: time.Second, Participant: "alice", Answer: "A"},
+		{Question: 0, At: time.Second, Participant: "bob", Answer: "A"},
+		{Question: 0, At: time.Second, Participant: "carol", Answer: "B"},
+		// Only alice and bob are tied for first, so carol sits the tiebreak out
+		{Question: 1, At: 500 * time.Millisecond, Participant: "carol", Answer: "B"},
+		{Question: 1, At: time.Second, Participant: "alice", Answer: "B"},
+		{Question: 1, At: time.Second, Participant: "bob", Answer: "A"},
+	})
+
+	if len(s.rejected) != 1 || s.rejected[0].Participant != "carol" {
+		t.Errorf("Expected only carol's tiebreak answer to be rejected, got %+v", s.rejected)
+	}
+
+	// The tiebreak follows the last question and ends once both answered
+	questions, reveals := s.all("question"), s.all("answer_reveal")
+	if len(questions) != 2 || len(reveals) != 2 {
+		t.Fatalf("Expected 2 questions and 2 reveals, got %d and %d", len(questions), len(reveals))
+	}
+	if questions[1].At != 6500*time.Millisecond || reveals[1].At != 7500*time.Millisecond {
+		t.Errorf("Expected the tiebreak at 6.5s revealed at 7.5s, got %v and %v", questions[1].At, reveals[1].At)
+	}
+	if n := len(reveals[1].Msg.Payload.(*models.AnswerReveal).Participants); n != 2 {
+		t.Errorf("Expected the tiebreak reveal to show 2 players, got %d", n)
+	}
+
+	finished := s.all("quiz_finished")
+	if len(finished) != 1 {
+		t.Fatalf("Expected one quiz_finished, got %d", len(finished))
+	}
+	var standings []string
+	for _, p := range finished[0].Msg.Payload.(models.QuizFinished).Leaderboard {
+		standings = append(standings, fmt.Sprintf("%d %s", p.Rank, p.Name))
+	}
+	if got := strings.Join(standings, ", "); got != "1 alice, 2 bob, 3 carol" {
+		t.Errorf("Expected alice to win the tiebreak, got %s", got)
+	}
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rkrmr33/quickwiz/internal/catalog"
+)
+
+// CatalogHandler searches the built-in quiz catalog.
+// Supports ?q=, ?category=, ?difficulty= and repeated ?tag= filters.
+func (h *Handler) CatalogHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	quizzes := h.catalog.Search(catalog.Filter{
+		Query:      query.Get("q"),
+		Category:   query.Get("category"),
+		Difficulty: query.Get("difficulty"),
+		Tags:       query["tag"],
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"quizzes": quizzes,
+		"total":   len(quizzes),
+	})
+}
+
+// CatalogTagsHandler returns every catalog tag with its number of quizzes
+func (h *Handler) CatalogTagsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tags": h.catalog.Tags(),
+	})
+}
+
+// CatalogQuizHandler returns a single catalog quiz with its markdown
+func (h *Handler) CatalogQuizHandler(w http.ResponseWriter, r *http.Request) {
+	id := cleanCode(mux.Vars(r)["id"])
+
+	info, markdown, err := h.catalog.Get(id)
+	if err != nil {
+		if errors.Is(err, catalog.ErrNotFound) {
+			http.Error(w, "Quiz not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"quiz":     info,
+		"markdown": markdown,
+	})
+}
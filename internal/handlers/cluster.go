@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/rkrmr33/quickwiz/internal/game"
+	"github.com/rkrmr33/quickwiz/internal/models"
+)
+
+const (
+	// broadcastTopic carries the broadcasts of every quiz between server instances
+	broadcastTopic = "broadcasts"
+	// gameLeaseTTL is how long a game's lease outlives an instance that stops renewing it
+	gameLeaseTTL = 15 * time.Second
+)
+
+// errGameRunning is returned when claiming a game that already runs
+var errGameRunning = errors.New("quiz already started")
+
+// broadcastEvent is a broadcast relayed to every server instance, each of
+// which delivers it to its own connections
+type broadcastEvent struct {
+	Code    string          `json:"code"`
+	Type    string          `json:"type"`
+	Message json.RawMessage `json:"message,omitempty"` // Empty for messages built per participant
+}
+
+// broadcast sends a message to every connection of a quiz, on every instance
+func (h *Handler) broadcast(code string, msg models.WebSocketMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		slog.Error("Broadcast failed to encode message", "error", err, "code", code, "msg_type", msg.Type)
+		return
+	}
+	h.publish(broadcastEvent{Code: code, Type: msg.Type, Message: data})
+}
+
+// broadcastQuestion sends the current question to every connection. Options
+// may be shuffled per participant, so each instance builds the update of each
+// of its connections.
+func (h *Handler) broadcastQuestion(code string) {
+	h.publish(broadcastEvent{Code: code, Type: models.QuestionUpdate{}.MessageType()})
+}
+
+// broadcastWagerPhase sends the wager phase of the current question to every
+// connection, each with what its participant may stake
+func (h *Handler) broadcastWagerPhase(code string) {
+	h.publish(broadcastEvent{Code: code, Type: models.WagerPhase{}.MessageType()})
+}
+
+// broadcastLeaderboard sends the standings to every connection, each with
+// where its participant stands
+func (h *Handler) broadcastLeaderboard(code string) {
+	h.publish(broadcastEvent{Code: code, Type: models.Leaderboard{}.MessageType()})
+}
+
+func (h *Handler) publish(event broadcastEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		slog.Error("Broadcast failed to encode event", "error", err, "code", event.Code, "msg_type", event.Type)
+		return
+	}
+	if err := h.opts.PubSub.Publish(broadcastTopic, data); err != nil {
+		slog.Error("Broadcast failed to publish", "error", err, "code", event.Code, "msg_type", event.Type)
+	}
+}
+
+// receiveBroadcast delivers a broadcast published by any instance to the
+// connections of this one
+func (h *Handler) receiveBroadcast(data []byte) {
+	var event broadcastEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		slog.Error("Broadcast failed to decode event", "error", err)
+		return
+	}
+
+	if len(event.Message) > 0 {
+		h.hub.BroadcastEncoded(event.Code, event.Type, event.Message)
+		return
+	}
+
+	h.hub.BroadcastEach(event.Code, func(participantID string) (models.WebSocketMessage, bool) {
+		var payload models.Payload
+		var err error
+		switch event.Type {
+		case models.WagerPhase{}.MessageType():
+			payload, err = h.quizManager.GetWagerPhase(event.Code, participantID)
+		case models.Leaderboard{}.MessageType():
+			payload, err = h.quizManager.GetLeaderboardUpdate(event.Code, participantID)
+		default:
+			payload, err = h.quizManager.GetQuestionUpdate(event.Code, participantID)
+		}
+		if err != nil {
+			slog.Error("Error building participant message", "error", err, "code", event.Code, "msg_type", event.Type, "participant_id", participantID)
+			return models.WebSocketMessage{}, false
+		}
+		return models.NewMessage(payload), true
+	})
+}
+
+// claimGame takes a game's lease for this instance and registers its game
+// loop, so no instance runs the game twice. It fails with errGameRunning
+// while the game runs here or on another instance.
+func (h *Handler) claimGame(code string) (*game.Game, error) {
+	g := game.New(code, h.quizManager, sessionOutput{h: h, code: code})
+	h.gamesMu.Lock()
+	if h.games[code] != nil {
+		h.gamesMu.Unlock()
+		return nil, errGameRunning
+	}
+	h.games[code] = g
+	h.gamesMu.Unlock()
+
+	acquired, err := h.opts.Leases.Acquire(gameLeaseKey(code), h.opts.NodeID, gameLeaseTTL)
+	if err == nil && !acquired {
+		err = errGameRunning
+	}
+	if err != nil {
+		h.gamesMu.Lock()
+		delete(h.games, code)
+		h.gamesMu.Unlock()
+		return nil, err
+	}
+	return g, nil
+}
+
+// releaseGame unregisters a claimed game and gives up its lease
+func (h *Handler) releaseGame(code string) {
+	h.gamesMu.Lock()
+	delete(h.games, code)
+	h.gamesMu.Unlock()
+	if err := h.opts.Leases.Release(gameLeaseKey(code), h.opts.NodeID); err != nil {
+		slog.Warn("Game lease not released", "error", err, "code", code)
+	}
+}
+
+// runGame runs a claimed game loop, or resumes it from where its session is.
+// The lease is renewed until the game ends and lapses if this instance dies.
+func (h *Handler) runGame(code string, g *game.Game, resume bool) {
+	stop := make(chan struct{})
+	go h.renewLease(gameLeaseKey(code), stop, g)
+	defer func() {
+		close(stop)
+		h.releaseGame(code)
+	}()
+
+	if resume {
+		slog.Info("Game taken over", "code", code, "node_id", h.opts.NodeID)
+		g.Resume()
+		return
+	}
+	g.Run()
+}
+
+// WatchGames resumes started games that no instance runs, such as those of an
+// instance that died, until ctx is done. Each is taken over once its lease
+// lapses.
+func (h *Handler) WatchGames(ctx context.Context) {
+	ticker := time.NewTicker(gameLeaseTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// A draining instance would not see a taken over game to its end
+		if h.quizManager.Draining() {
+			continue
+		}
+		codes, err := h.quizManager.ActiveCodes()
+		if err != nil {
+			slog.Warn("Game watcher failed to list games", "error", err)
+			continue
+		}
+		for _, code := range codes {
+			g, err := h.claimGame(code)
+			if errors.Is(err, errGameRunning) {
+				continue
+			}
+			if err != nil {
+				slog.Warn("Game watcher failed to claim game", "error", err, "code", code)
+				continue
+			}
+			go h.runGame(code, g, true)
+		}
+	}
+}
+
+func gameLeaseKey(code string) string {
+	return "game/" + code
+}
+
+// renewLease keeps a game's lease until stop is closed, stopping the game if
+// another instance takes the lease over
+func (h *Handler) renewLease(key string, stop <-chan struct{}, g *game.Game) {
+	ticker := time.NewTicker(gameLeaseTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		renewed, err := h.opts.Leases.Acquire(key, h.opts.NodeID, gameLeaseTTL)
+		if err != nil {
+			slog.Warn("Game lease renewal failed", "error", err, "key", key)
+			continue // Retry until the lease actually lapses
+		}
+		if !renewed {
+			slog.Error("Game stopped after losing its lease", "key", key, "node_id", h.opts.NodeID)
+			g.Stop()
+			return
+		}
+	}
+}
+
+// notifyAnswered tells the game of a quiz that someone answered or wagered,
+// if this instance runs it
+func (h *Handler) notifyAnswered(code string) {
+	h.gamesMu.Lock()
+	g := h.games[code]
+	h.gamesMu.Unlock()
+
+	if g != nil {
+		g.Answered()
+	}
+}
+
+// activeGames returns the number of games whose timers this instance runs
+func (h *Handler) activeGames() int {
+	h.gamesMu.Lock()
+	defer h.gamesMu.Unlock()
+
+	return len(h.games)
+}
+
+// sessionOutput sends the messages of a game to the connections of its quiz
+type sessionOutput struct {
+	h    *Handler
+	code string
+}
+
+func (o sessionOutput) Broadcast(msg models.WebSocketMessage) { o.h.broadcast(o.code, msg) }
+func (o sessionOutput) BroadcastQuestion()                    { o.h.broadcastQuestion(o.code) }
+func (o sessionOutput) BroadcastWagerPhase()                  { o.h.broadcastWagerPhase(o.code) }
+func (o sessionOutput) BroadcastLeaderboard()                 { o.h.broadcastLeaderboard(o.code) }
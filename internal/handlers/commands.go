@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rkrmr33/quickwiz/internal/hub"
+	"github.com/rkrmr33/quickwiz/internal/models"
+)
+
+var (
+	errQuizNotFound = errors.New("quiz not found")
+	errNotCreator   = errors.New("only the quiz creator can do that")
+	errTooFast      = errors.New("reacting too fast")
+)
+
+// reactionInterval is the minimum time between two reactions from one participant
+const reactionInterval = 500 * time.Millisecond
+
+// reactions are the emoji players may react with
+var reactions = map[string]bool{
+	"👍": true, "👏": true, "😂": true, "😮": true,
+	"🔥": true, "❤️": true, "🎉": true, "🤔": true,
+}
+
+// handleCommand runs a command read from a WebSocket and replies on the same
+// connection. Commands always act as the participant the connection belongs
+// to, never as an ID in the payload.
+func (h *Handler) handleCommand(client *hub.Client, code string, data []byte) {
+	var msg models.ClientMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		slog.Warn("WebSocketCommand invalid message", "error", err, "code", code, "participant_id", client.ParticipantID())
+		client.Send(models.NewMessage(models.CommandError{
+			ID:     msg.ID,
+			Error:  fmt.Sprintf("invalid message: %v", err),
+			Status: http.StatusBadRequest,
+		}))
+		return
+	}
+
+	reply := h.runCommand(code, client.ParticipantID(), msg)
+	if ack, ok := reply.(models.CommandAck); ok && ack.ID == "" {
+		return // Nothing to acknowledge
+	}
+	client.Send(models.NewMessage(reply))
+}
+
+// CommandHandler runs a command sent over HTTP, for clients receiving updates
+// over Server-Sent Events. The reply is the message a WebSocket client would
+// have received.
+func (h *Handler) CommandHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	code := cleanCode(vars["code"])
+
+	var req struct {
+		models.ClientMessage
+		ParticipantID string `json:"participant_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Command failed to decode request body", "error", err, "code", code)
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	reply := h.runCommand(code, req.ParticipantID, req.ClientMessage)
+
+	w.Header().Set("Content-Type", "application/json")
+	if cmdErr, ok := reply.(models.CommandError); ok {
+		w.WriteHeader(cmdErr.Status)
+	}
+	json.NewEncoder(w).Encode(models.NewMessage(reply))
+}
+
+// runCommand runs a client command as a participant and returns the reply
+func (h *Handler) runCommand(code, participantID string, msg models.ClientMessage) models.Payload {
+	slog.Debug("Command received", "type", msg.Type, "id", msg.ID, "code", code, "participant_id", participantID)
+
+	var err error
+	switch msg.Type {
+	case "ping":
+		return models.Pong{ID: msg.ID}
+	case "answer":
+		err = h.answerCommand(code, participantID, msg.Payload)
+	case "wager":
+		err = h.wagerCommand(code, participantID, msg.Payload)
+	case "start":
+		err = h.startQuiz(code, participantID)
+	case "pause":
+		err = h.pauseCommand(code, participantID, msg.Payload)
+	case "react":
+		err = h.reactCommand(code, participantID, msg.Payload)
+	default:
+		err = fmt.Errorf("unknown message type %q", msg.Type)
+	}
+
+	if err != nil {
+		return models.CommandError{ID: msg.ID, Error: err.Error(), Status: commandStatus(err)}
+	}
+	return models.CommandAck{ID: msg.ID}
+}
+
+func (h *Handler) answerCommand(code, participantID string, payload json.RawMessage) error {
+	var cmd models.AnswerCommand
+	if err := decodePayload(payload, &cmd); err != nil {
+		return err
+	}
+	return h.submitAnswer(code, participantID, cmd)
+}
+
+func (h *Handler) wagerCommand(code, participantID string, payload json.RawMessage) error {
+	var cmd models.WagerCommand
+	if err := decodePayload(payload, &cmd); err != nil {
+		return err
+	}
+
+	if err := h.quizManager.PlaceWager(code, participantID, cmd.Stake); err != nil {
+		slog.Warn("PlaceWager failed", "error", err, "code", code, "participant_id", participantID, "stake", cmd.Stake)
+		return err
+	}
+
+	slog.Info("PlaceWager wager placed", "code", code, "participant_id", participantID, "stake", cmd.Stake)
+	h.notifyAnswered(code)
+	return nil
+}
+
+func (h *Handler) pauseCommand(code, participantID string, payload json.RawMessage) error {
+	cmd := models.PauseCommand{Paused: true}
+	if err := decodePayload(payload, &cmd); err != nil {
+		return err
+	}
+
+	session, err := h.quizManager.GetSession(code)
+	if err != nil {
+		return errQuizNotFound
+	}
+	if session.CreatorID != participantID {
+		slog.Warn("PauseQuiz unauthorized attempt", "participant_id", participantID, "code", code)
+		return errNotCreator
+	}
+
+	if cmd.Paused {
+		err = h.quizManager.Pause(code)
+	} else {
+		err = h.quizManager.Resume(code)
+	}
+	if err != nil {
+		slog.Error("PauseQuiz failed", "error", err, "paused", cmd.Paused, "code", code)
+		return err
+	}
+
+	slog.Info("PauseQuiz quiz paused state changed", "paused", cmd.Paused, "code", code)
+	h.broadcast(code, models.NewMessage(models.PauseChanged{Paused: cmd.Paused}))
+	return nil
+}
+
+func (h *Handler) reactCommand(code, participantID string, payload json.RawMessage) error {
+	var cmd models.ReactCommand
+	if err := decodePayload(payload, &cmd); err != nil {
+		return err
+	}
+	if !reactions[cmd.Emoji] {
+		return fmt.Errorf("unsupported reaction %q", cmd.Emoji)
+	}
+
+	session, err := h.quizManager.GetSession(code)
+	if err != nil {
+		return errQuizNotFound
+	}
+	participant, exists := session.Participants[participantID]
+	if !exists {
+		return fmt.Errorf("participant not found")
+	}
+
+	if !h.presence.allowReaction(code, participantID, reactionInterval) {
+		return errTooFast
+	}
+	h.broadcast(code, models.NewMessage(models.Reaction{
+		ParticipantID: participant.ID,
+		Name:          participant.Name,
+		Emoji:         cmd.Emoji,
+	}))
+	return nil
+}
+
+// decodePayload decodes a command payload; a missing payload leaves v unchanged
+func decodePayload(payload json.RawMessage, v interface{}) error {
+	if len(payload) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(payload, v); err != nil {
+		return fmt.Errorf("invalid payload: %w", err)
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"log/slog"
@@ -13,34 +14,76 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
 
+	"github.com/rkrmr33/quickwiz/internal/catalog"
+	"github.com/rkrmr33/quickwiz/internal/cluster"
+	"github.com/rkrmr33/quickwiz/internal/game"
+	"github.com/rkrmr33/quickwiz/internal/hub"
+	"github.com/rkrmr33/quickwiz/internal/library"
 	"github.com/rkrmr33/quickwiz/internal/models"
 	"github.com/rkrmr33/quickwiz/internal/parser"
+	"github.com/rkrmr33/quickwiz/internal/protocol"
 	"github.com/rkrmr33/quickwiz/internal/quiz"
 )
 
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
+	Subprotocols:    protocol.Subprotocols(),
 	CheckOrigin: func(r *http.Request) bool {
 		return true // Allow all origins in development
 	},
 }
 
+// Options configures a Handler
+type Options struct {
+	Timings    parser.Timings // Defaults for quizzes that do not set their own timings
+	AdminToken string         // Bearer token for admin endpoints; they are disabled when empty
+
+	// Sharing quizzes with other server instances; by default the server runs alone
+	PubSub cluster.PubSub // Relays broadcasts between instances
+	Leases cluster.Leaser // Elects the instance that runs each game
+	NodeID string         // Names this instance when holding leases
+}
+
 // Handler manages HTTP requests
 type Handler struct {
 	quizManager *quiz.Manager
+	library     *library.Library
+	catalog     *catalog.Catalog
 	templates   *template.Template
-	connections map[string]map[*websocket.Conn]string // quizCode -> conn -> participantID
-	connMu      sync.RWMutex
+	opts        Options
+	started     time.Time
+	hub         *hub.Hub
+	presence    *presence
+	games       map[string]*game.Game // Games whose timers this instance runs
+	gamesMu     sync.Mutex
 }
 
 // NewHandler creates a new HTTP handler
-func NewHandler(quizManager *quiz.Manager, templates *template.Template) *Handler {
-	return &Handler{
+func NewHandler(quizManager *quiz.Manager, quizLibrary *library.Library, quizCatalog *catalog.Catalog, templates *template.Template, opts Options) *Handler {
+	if opts.PubSub == nil || opts.Leases == nil {
+		local := cluster.NewLocal()
+		opts.PubSub, opts.Leases = local, local
+	}
+	if opts.NodeID == "" {
+		opts.NodeID = "local"
+	}
+
+	h := &Handler{
 		quizManager: quizManager,
+		library:     quizLibrary,
+		catalog:     quizCatalog,
 		templates:   templates,
-		connections: make(map[string]map[*websocket.Conn]string),
+		opts:        opts,
+		started:     time.Now(),
+		hub:         hub.New(hub.Options{}),
+		presence:    newPresence(),
+		games:       make(map[string]*game.Game),
 	}
+	if _, err := opts.PubSub.Subscribe(broadcastTopic, h.receiveBroadcast); err != nil {
+		slog.Error("Failed to subscribe to broadcasts", "error", err, "node_id", opts.NodeID)
+	}
+	return h
 }
 
 // HomeHandler serves the home page
@@ -65,7 +108,10 @@ func (h *Handler) CreateQuizHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		Markdown string `json:"markdown"`
+		Markdown  string `json:"markdown"`
+		QuizID    string `json:"quiz_id"`    // Start from a saved quiz instead of markdown
+		Version   int    `json:"version"`    // Saved quiz version (0 = latest)
+		CatalogID string `json:"catalog_id"` // Start from a built-in catalog quiz
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		slog.Error("CreateQuiz failed to decode JSON body", "error", err)
@@ -74,12 +120,34 @@ func (h *Handler) CreateQuizHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	markdown := req.Markdown
 
+	// Load the markdown from the library when starting from a saved quiz
+	if req.QuizID != "" {
+		saved, err := h.library.Get(cleanCode(req.QuizID), req.Version)
+		if err != nil {
+			slog.Error("CreateQuiz failed to load saved quiz", "error", err, "quiz_id", req.QuizID)
+			writeLibraryError(w, err)
+			return
+		}
+		slog.Info("CreateQuiz using saved quiz", "quiz_id", saved.ID, "version", saved.Version)
+		markdown = saved.Markdown
+	} else if req.CatalogID != "" {
+		_, catalogMarkdown, err := h.catalog.Get(cleanCode(req.CatalogID))
+		if err != nil {
+			slog.Error("CreateQuiz failed to load catalog quiz", "error", err, "catalog_id", req.CatalogID)
+			http.Error(w, "Quiz not found", http.StatusNotFound)
+			return
+		}
+		slog.Info("CreateQuiz using catalog quiz", "catalog_id", req.CatalogID)
+		markdown = catalogMarkdown
+	}
+
 	slog.Info("CreateQuiz markdown received", "length", len(markdown))
 
 	// Parse markdown
-	quiz, err := parser.ParseQuizMarkdown(markdown)
+	quiz, err := parser.ParseQuizMarkdownWithDefaults(markdown, h.opts.Timings)
 	if err != nil {
 		slog.Error("CreateQuiz failed to parse markdown", "error", err)
+		parser.CountFailure(parser.SourceCreate)
 		http.Error(w, fmt.Sprintf("Failed to parse quiz: %v", err), http.StatusBadRequest)
 		return
 	}
@@ -93,11 +161,21 @@ func (h *Handler) CreateQuizHandler(w http.ResponseWriter, r *http.Request) {
 	code, err := h.quizManager.CreateSession(*quiz)
 	if err != nil {
 		slog.Error("CreateQuiz failed to create session", "error", err)
-		http.Error(w, fmt.Sprintf("Failed to create session: %v", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("Failed to create session: %v", err), limitStatus(err, http.StatusInternalServerError))
 		return
 	}
 
-	slog.Info("CreateQuiz quiz created successfully", "code", code)
+	// The session may already be gone if it was removed right after creation
+	session, err := h.quizManager.GetSession(code)
+	if err != nil {
+		slog.Error("CreateQuiz session not found after creation", "error", err, "code", code)
+		http.Error(w, "Quiz not found", http.StatusNotFound)
+		return
+	}
+	slog.Info("CreateQuiz quiz created successfully",
+		"code", code,
+		"questions", len(session.Quiz.Questions),
+		"seed", session.Seed)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
@@ -136,11 +214,17 @@ func (h *Handler) GetQuizHandler(w http.ResponseWriter, r *http.Request) {
 
 	participants := make([]map[string]interface{}, 0, len(participantList))
 	for _, p := range participantList {
-		participants = append(participants, map[string]interface{}{
+		info := map[string]interface{}{
 			"id":          p.ID,
 			"name":        p.Name,
 			"isSpectator": p.IsSpectator,
-		})
+		}
+		online, lastSeen := h.presence.status(code, p.ID)
+		info["online"] = online
+		if !lastSeen.IsZero() {
+			info["lastSeen"] = lastSeen
+		}
+		participants = append(participants, info)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -148,10 +232,19 @@ func (h *Handler) GetQuizHandler(w http.ResponseWriter, r *http.Request) {
 		"code":             code,
 		"title":            session.Quiz.Title,
 		"questionCount":    len(session.Quiz.Questions),
+		"roundCount":       len(session.Quiz.Rounds),
+		"currentRound":     session.CurrentRound,
+		"author":           session.Quiz.Author,
+		"description":      session.Quiz.Description,
+		"tags":             session.Quiz.Tags,
+		"language":         session.Quiz.Language,
+		"difficulty":       session.Quiz.Difficulty,
 		"participantCount": len(session.Participants),
+		"onlineCount":      h.presence.onlineCount(code),
 		"participants":     participants,
 		"creatorId":        session.CreatorID,
 		"state":            session.State,
+		"paused":           session.Paused,
 	})
 }
 
@@ -232,7 +325,7 @@ func (h *Handler) JoinQuizHandler(w http.ResponseWriter, r *http.Request) {
 		err = h.quizManager.AddParticipant(code, participantID, req.Name, req.IsSpectator)
 		if err != nil {
 			slog.Error("JoinQuiz failed to add participant", "error", err, "name", req.Name, "code", code)
-			http.Error(w, fmt.Sprintf("Failed to join quiz: %v", err), http.StatusBadRequest)
+			http.Error(w, fmt.Sprintf("Failed to join quiz: %v", err), limitStatus(err, http.StatusBadRequest))
 			return
 		}
 		slog.Info("JoinQuiz participant joined successfully", "name", req.Name, "is_spectator", req.IsSpectator, "participant_id", participantID, "code", code)
@@ -251,15 +344,12 @@ func (h *Handler) JoinQuizHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Only broadcast participant joined if this is a new participant (not rejoining)
 	if !isRejoining {
-		h.broadcast(code, models.WebSocketMessage{
-			Type: "participant_joined",
-			Payload: models.ParticipantJoined{
-				ID:               participantID,
-				Name:             req.Name,
-				IsSpectator:      participant.IsSpectator,
-				ParticipantCount: len(session.Participants),
-			},
-		})
+		h.broadcast(code, models.NewMessage(models.ParticipantJoined{
+			ID:               participantID,
+			Name:             req.Name,
+			IsSpectator:      participant.IsSpectator,
+			ParticipantCount: len(session.Participants),
+		}))
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -310,33 +400,54 @@ func (h *Handler) StartQuizHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := h.startQuiz(code, req.ParticipantID); err != nil {
+		status := commandStatus(err)
+		switch status {
+		case http.StatusNotFound:
+			http.Error(w, "Quiz not found", status)
+		case http.StatusForbidden:
+			http.Error(w, "Only the quiz creator can start the quiz", status)
+		default:
+			http.Error(w, fmt.Sprintf("Failed to start quiz: %v", err), status)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// startQuiz starts a quiz on behalf of its creator and runs the game loop
+func (h *Handler) startQuiz(code, participantID string) error {
 	// Check if the participant is the creator
 	session, err := h.quizManager.GetSession(code)
 	if err != nil {
 		slog.Error("StartQuiz failed to get session", "error", err, "code", code)
-		http.Error(w, "Quiz not found", http.StatusNotFound)
-		return
+		return errQuizNotFound
 	}
 
-	if session.CreatorID != req.ParticipantID {
-		slog.Warn("StartQuiz unauthorized attempt", "participant_id", req.ParticipantID, "creator_id", session.CreatorID, "code", code)
-		http.Error(w, "Only the quiz creator can start the quiz", http.StatusForbidden)
-		return
+	if session.CreatorID != participantID {
+		slog.Warn("StartQuiz unauthorized attempt", "participant_id", participantID, "creator_id", session.CreatorID, "code", code)
+		return errNotCreator
 	}
 
-	err = h.quizManager.StartQuiz(code)
+	// Hold the game before starting it so no instance takes it over meanwhile
+	g, err := h.claimGame(code)
 	if err != nil {
+		slog.Error("StartQuiz failed to claim game", "error", err, "code", code, "node_id", h.opts.NodeID)
+		return err
+	}
+	if err := h.quizManager.StartQuiz(code); err != nil {
+		h.releaseGame(code)
 		slog.Error("StartQuiz failed to start quiz", "error", err, "code", code)
-		http.Error(w, fmt.Sprintf("Failed to start quiz: %v", err), http.StatusBadRequest)
-		return
+		return err
 	}
 
-	slog.Info("StartQuiz quiz started successfully", "code", code, "creator_id", req.ParticipantID)
+	slog.Info("StartQuiz quiz started successfully", "code", code, "creator_id", participantID)
 
 	// Start countdown and then question timer
-	go h.runCountdownAndStart(code)
+	go h.runGame(code, g, false)
 
-	w.WriteHeader(http.StatusOK)
+	return nil
 }
 
 // SubmitAnswerHandler handles answer submission
@@ -353,8 +464,8 @@ func (h *Handler) SubmitAnswerHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
+		models.AnswerCommand
 		ParticipantID string `json:"participant_id"`
-		Answer        string `json:"answer"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -363,34 +474,45 @@ func (h *Handler) SubmitAnswerHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	slog.Info("SubmitAnswer processing answer", "code", code, "participant_id", req.ParticipantID, "answer", req.Answer)
-
-	err := h.quizManager.SubmitAnswer(code, req.ParticipantID, req.Answer)
-	if err != nil {
-		slog.Error("SubmitAnswer failed to submit answer", "error", err, "code", code, "participant_id", req.ParticipantID)
-		http.Error(w, fmt.Sprintf("Failed to submit answer: %v", err), http.StatusBadRequest)
+	if err := h.submitAnswer(code, req.ParticipantID, req.AnswerCommand); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to submit answer: %v", err), commandStatus(err))
 		return
 	}
 
-	slog.Info("SubmitAnswer answer submitted successfully", "code", code, "participant_id", req.ParticipantID)
+	w.WriteHeader(http.StatusOK)
+}
 
-	// Broadcast answer count update
-	answeredCount, totalParticipants := h.quizManager.GetAnswerCount(code)
-	h.broadcast(code, models.WebSocketMessage{
-		Type: "answer_count_update",
-		Payload: models.AnswerCountUpdate{
-			ParticipantID:     req.ParticipantID,
-			AnsweredCount:     answeredCount,
-			TotalParticipants: totalParticipants,
-		},
-	})
+// submitAnswer records a participant's answer, by option index when given.
+// The instance running the game reveals the answer once everyone has answered.
+func (h *Handler) submitAnswer(code, participantID string, cmd models.AnswerCommand) error {
+	slog.Info("SubmitAnswer processing answer", "code", code, "participant_id", participantID, "answer", cmd.Answer)
 
-	// Check if all answered
-	if h.quizManager.CheckAllAnswered(code) {
-		go h.revealAnswer(code)
+	var err error
+	if cmd.Confidence != 0 {
+		err = h.quizManager.SetConfidence(code, participantID, cmd.Confidence)
+	}
+	if err == nil && cmd.OptionIndex != nil {
+		err = h.quizManager.SubmitAnswerIndex(code, participantID, *cmd.OptionIndex)
+	} else if err == nil {
+		err = h.quizManager.SubmitAnswer(code, participantID, cmd.Answer)
+	}
+	if err != nil {
+		slog.Error("SubmitAnswer failed to submit answer", "error", err, "code", code, "participant_id", participantID)
+		return err
 	}
 
-	w.WriteHeader(http.StatusOK)
+	slog.Info("SubmitAnswer answer submitted successfully", "code", code, "participant_id", participantID)
+
+	// Broadcast answer count update
+	answeredCount, totalParticipants := h.quizManager.GetAnswerCount(code)
+	h.broadcast(code, models.NewMessage(models.AnswerCountUpdate{
+		ParticipantID:     participantID,
+		AnsweredCount:     answeredCount,
+		TotalParticipants: totalParticipants,
+	}))
+	h.notifyAnswered(code)
+
+	return nil
 }
 
 // WebSocketHandler handles WebSocket connections
@@ -409,219 +531,87 @@ func (h *Handler) WebSocketHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Reject unsupported versions before upgrading so the client sees why
+	requested := r.URL.Query().Get("protocol")
+	if _, err := protocol.Negotiate("", requested); err != nil {
+		slog.Warn("WebSocket unsupported protocol version", "error", err, "code", code, "participant_id", participantID)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		slog.Error("WebSocket upgrade error", "error", err, "code", code, "participant_id", participantID)
 		return
 	}
+	version, _ := protocol.Negotiate(conn.Subprotocol(), requested)
 
-	slog.Info("WebSocket connection established", "code", code, "participant_id", participantID)
-
-	// Register connection
-	h.connMu.Lock()
-	if h.connections[code] == nil {
-		h.connections[code] = make(map[*websocket.Conn]string)
-	}
-	h.connections[code][conn] = participantID
-	h.connMu.Unlock()
-
-	// Send current state
-	session, _ := h.quizManager.GetSession(code)
-	if session.State == models.StateQuestion {
-		h.sendQuestionUpdate(conn, code)
-	}
-
-	// Handle disconnection
-	defer func() {
-		h.connMu.Lock()
-		delete(h.connections[code], conn)
-		h.connMu.Unlock()
-		conn.Close()
-	}()
-
-	// Keep connection alive (read messages to detect disconnection)
-	for {
-		if _, _, err := conn.ReadMessage(); err != nil {
-			break
-		}
-	}
-}
+	slog.Info("WebSocket connection established", "code", code, "participant_id", participantID, "protocol_version", version)
 
-// Helper methods
+	// Register connection; all writes go through the client's writer goroutine
+	client := h.hub.RegisterWebSocket(conn, code, participantID, version)
 
-func (h *Handler) runCountdownAndStart(code string) {
-	// Countdown from 3 to 1
-	for i := 3; i >= 1; i-- {
-		h.broadcast(code, models.WebSocketMessage{
-			Type: "countdown",
-			Payload: map[string]int{
-				"count": i,
-			},
-		})
-		time.Sleep(1 * time.Second)
-	}
-
-	// Send "GO!" signal
-	h.broadcast(code, models.WebSocketMessage{
-		Type: "countdown",
-		Payload: map[string]int{
-			"count": 0,
-		},
+	// Handle commands until the connection closes or stops answering pings
+	h.serveClient(client, code, func(data []byte) {
+		h.handleCommand(client, code, data)
 	})
-	time.Sleep(500 * time.Millisecond)
-
-	// Now start the actual quiz
-	h.runQuestionTimer(code)
 }
 
-func (h *Handler) runQuestionTimer(code string) {
+// serveClient greets a newly registered client, sends it the current state of
+// the quiz and listens to it until it disconnects
+func (h *Handler) serveClient(client *hub.Client, code string, onMessage func(data []byte)) {
+	participantID := client.ParticipantID()
+
+	// The quiz may have been removed since the connection was accepted
 	session, err := h.quizManager.GetSession(code)
 	if err != nil {
+		slog.Warn("Client connected to a removed quiz", "error", err, "code", code, "participant_id", participantID)
+		client.Close(websocket.CloseNormalClosure, "quiz not found")
 		return
 	}
 
-	// Send question to all participants
-	h.broadcast(code, models.WebSocketMessage{
-		Type:    "question",
-		Payload: h.buildQuestionUpdate(session),
-	})
-
-	// Wait for time or all answers
-	duration := time.Duration(session.Quiz.TimePerQuestion) * time.Second
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
-
-	startTime := time.Now()
-	for {
-		<-ticker.C
-		elapsed := time.Since(startTime)
+	if client.Version() >= protocol.Version2 {
+		client.Send(models.NewMessage(models.Welcome{
+			ProtocolVersion: client.Version(),
+			ParticipantID:   participantID,
+		}))
+	}
+	h.markOnline(code, participantID)
 
-		if elapsed >= duration {
-			h.revealAnswer(code)
-			break
+	// Send current state
+	if session.State == models.StateQuestion {
+		h.sendQuestionUpdate(client, code, participantID)
+	} else if session.State == models.StateWager {
+		if phase, err := h.quizManager.GetWagerPhase(code, participantID); err == nil {
+			client.Send(models.NewMessage(phase))
 		}
-
-		// Check if all answered
-		if h.quizManager.CheckAllAnswered(code) {
-			h.revealAnswer(code)
-			break
+	} else if session.State == models.StateAnswer {
+		if standings, err := h.quizManager.GetLeaderboardUpdate(code, participantID); err == nil {
+			client.Send(models.NewMessage(standings))
 		}
-
-		// Send time update
-		remaining := int(duration.Seconds() - elapsed.Seconds())
-		h.broadcast(code, models.WebSocketMessage{
-			Type: "time_update",
-			Payload: map[string]int{
-				"time_remaining": remaining,
-			},
-		})
-	}
-}
-
-func (h *Handler) revealAnswer(code string) {
-	slog.Info("Revealing answer", "code", code)
-
-	reveal, err := h.quizManager.RevealAnswer(code)
-	if err != nil {
-		slog.Error("Error revealing answer", "error", err, "code", code)
-		return
-	}
-
-	// Get session to access time_between_questions setting
-	session, err := h.quizManager.GetSession(code)
-	if err != nil {
-		slog.Error("Error getting session", "error", err, "code", code)
-		return
-	}
-
-	// Broadcast answer reveal
-	h.broadcast(code, models.WebSocketMessage{
-		Type:    "answer_reveal",
-		Payload: reveal,
-	})
-
-	slog.Info("Answer revealed successfully", "code", code)
-
-	// Wait before next question based on quiz settings, sending timer updates
-	duration := time.Duration(session.Quiz.TimeBetweenQuestions) * time.Second
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
-
-	startTime := time.Now()
-	for {
-		<-ticker.C
-		elapsed := time.Since(startTime)
-
-		if elapsed >= duration {
-			break
+	} else if session.State == models.StateRoundIntro {
+		if intro, err := h.quizManager.GetRoundIntro(code); err == nil {
+			client.Send(models.NewMessage(intro))
 		}
-
-		// Send time update during answer reveal
-		remaining := int(duration.Seconds() - elapsed.Seconds())
-		h.broadcast(code, models.WebSocketMessage{
-			Type: "time_update",
-			Payload: map[string]int{
-				"time_remaining": remaining,
-			},
-		})
 	}
 
-	hasNext, err := h.quizManager.NextQuestion(code)
-	if err != nil {
-		slog.Error("Error moving to next question", "error", err, "code", code)
-		return
+	if session.Paused {
+		client.Send(models.NewMessage(models.PauseChanged{Paused: true}))
 	}
 
-	if !hasNext {
-		// Quiz finished
-		leaderboard, _ := h.quizManager.GetLeaderboard(code)
-		h.broadcast(code, models.WebSocketMessage{
-			Type: "quiz_finished",
-			Payload: models.QuizFinished{
-				Leaderboard: leaderboard,
-			},
-		})
-	} else {
-		// Start next question
-		go h.runQuestionTimer(code)
-	}
+	client.Listen(onMessage)
+	h.markOffline(code, participantID)
 }
 
-func (h *Handler) buildQuestionUpdate(session *models.QuizSession) models.QuestionUpdate {
-	q := session.Quiz.Questions[session.CurrentQuestion]
-	return models.QuestionUpdate{
-		QuestionNumber: session.CurrentQuestion + 1,
-		TotalQuestions: len(session.Quiz.Questions),
-		Text:           q.Text,
-		Options:        q.Options,
-		TimeRemaining:  session.Quiz.TimePerQuestion,
-	}
-}
+// Helper methods
 
-func (h *Handler) sendQuestionUpdate(conn *websocket.Conn, code string) {
-	session, err := h.quizManager.GetSession(code)
+func (h *Handler) sendQuestionUpdate(client *hub.Client, code, participantID string) {
+	update, err := h.quizManager.GetQuestionUpdate(code, participantID)
 	if err != nil {
 		return
 	}
 
-	msg := models.WebSocketMessage{
-		Type:    "question",
-		Payload: h.buildQuestionUpdate(session),
-	}
-
-	conn.WriteJSON(msg)
-}
-
-func (h *Handler) broadcast(code string, msg models.WebSocketMessage) {
-	h.connMu.RLock()
-	defer h.connMu.RUnlock()
-
-	conns := h.connections[code]
-	for conn := range conns {
-		if err := conn.WriteJSON(msg); err != nil {
-			slog.Error("Error broadcasting message", "error", err, "code", code, "msg_type", msg.Type)
-		}
-	}
+	client.Send(models.NewMessage(update))
 }
 
 func generateParticipantID() string {
@@ -631,3 +621,30 @@ func generateParticipantID() string {
 func cleanCode(s string) string {
 	return strings.TrimSpace(strings.ToLower(s))
 }
+
+// commandStatus maps an error from a player or host command to an HTTP status
+func commandStatus(err error) int {
+	switch {
+	case errors.Is(err, errQuizNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errNotCreator), errors.Is(err, quiz.ErrNotInSuddenDeath):
+		return http.StatusForbidden
+	case errors.Is(err, quiz.ErrPaused):
+		return http.StatusConflict
+	case errors.Is(err, errTooFast):
+		return http.StatusTooManyRequests
+	}
+	return limitStatus(err, http.StatusBadRequest)
+}
+
+// limitStatus maps errors from the configured server limits to an HTTP status,
+// falling back to the given status for other errors
+func limitStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, quiz.ErrTooManySessions), errors.Is(err, quiz.ErrShuttingDown):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, quiz.ErrSessionFull):
+		return http.StatusConflict
+	}
+	return fallback
+}
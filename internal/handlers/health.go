@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/rkrmr33/quickwiz/internal/catalog"
+	"github.com/rkrmr33/quickwiz/internal/models"
+)
+
+// HealthzHandler reports that the process is alive. It does no other checks
+// so a busy or draining server is not restarted.
+func (h *Handler) HealthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("ok\n"))
+}
+
+// ReadyzHandler reports whether the server should receive new traffic. It
+// fails while shutting down or when the quiz library cannot be written.
+func (h *Handler) ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	if h.quizManager.Draining() {
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	if err := h.library.Check(); err != nil {
+		slog.Warn("Readyz library check failed", "error", err)
+		http.Error(w, "library unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Write([]byte("ready\n"))
+}
+
+// AdminStatusHandler returns an overview of the server for on-call. It
+// requires the configured admin token as a bearer token and is disabled when
+// no token is configured.
+func (h *Handler) AdminStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	counts := h.quizManager.SessionCounts()
+	sessions := 0
+	for _, count := range counts {
+		sessions += count
+	}
+	players, spectators := h.quizManager.ParticipantCounts()
+
+	libraryStatus := models.StoreStatus{Healthy: true, Quizzes: h.library.Count()}
+	if err := h.library.Check(); err != nil {
+		libraryStatus.Healthy = false
+		libraryStatus.Error = err.Error()
+	}
+
+	draining := h.quizManager.Draining()
+	status := models.ServerStatus{
+		Ready:           !draining && libraryStatus.Healthy,
+		Draining:        draining,
+		StartedAt:       h.started,
+		UptimeSeconds:   int64(time.Since(h.started).Seconds()),
+		Sessions:        sessions,
+		SessionsByState: counts,
+		ActiveGames:     h.quizManager.ActiveGames(),
+		Players:         players,
+		Spectators:      spectators,
+		Connections:     h.hub.Count(),
+		Goroutines:      runtime.NumGoroutine(),
+		Memory: models.MemoryStatus{
+			HeapAlloc: mem.HeapAlloc,
+			HeapInuse: mem.HeapInuse,
+			Sys:       mem.Sys,
+			NumGC:     mem.NumGC,
+		},
+		Library:        libraryStatus,
+		CatalogQuizzes: len(h.catalog.Search(catalog.Filter{})),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// authorizeAdmin checks the bearer token of an admin request, writing an
+// error response if it is missing or wrong
+func (h *Handler) authorizeAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if h.opts.AdminToken == "" {
+		http.NotFound(w, r)
+		return false
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.opts.AdminToken)) != 1 {
+		slog.Warn("Admin request unauthorized", "path", r.URL.Path, "remote_addr", r.RemoteAddr)
+		w.Header().Set("WWW-Authenticate", `Bearer realm="quickwiz admin"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
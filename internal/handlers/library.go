@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rkrmr33/quickwiz/internal/library"
+)
+
+// ListQuizzesHandler returns all saved quizzes
+func (h *Handler) ListQuizzesHandler(w http.ResponseWriter, r *http.Request) {
+	quizzes := h.library.List()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"quizzes": quizzes,
+	})
+}
+
+// SaveQuizHandler stores a new quiz in the library
+func (h *Handler) SaveQuizHandler(w http.ResponseWriter, r *http.Request) {
+	slog.Info("SaveQuiz request received", "remote_addr", r.RemoteAddr)
+
+	var req struct {
+		Markdown string `json:"markdown"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("SaveQuiz failed to decode JSON body", "error", err)
+		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	saved, err := h.library.Create(req.Markdown)
+	if err != nil {
+		slog.Error("SaveQuiz failed to save quiz", "error", err)
+		http.Error(w, fmt.Sprintf("Failed to save quiz: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	slog.Info("SaveQuiz quiz saved successfully", "id", saved.ID, "title", saved.Title)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(saved)
+}
+
+// GetSavedQuizHandler returns a saved quiz, optionally at a given ?version=
+func (h *Handler) GetSavedQuizHandler(w http.ResponseWriter, r *http.Request) {
+	id := cleanCode(mux.Vars(r)["id"])
+
+	version, err := versionParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	saved, err := h.library.Get(id, version)
+	if err != nil {
+		writeLibraryError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(saved)
+}
+
+// UpdateSavedQuizHandler stores a new version of a saved quiz. If the request
+// includes the version it was based on, stale updates are rejected.
+func (h *Handler) UpdateSavedQuizHandler(w http.ResponseWriter, r *http.Request) {
+	id := cleanCode(mux.Vars(r)["id"])
+
+	slog.Info("UpdateSavedQuiz request received", "id", id, "remote_addr", r.RemoteAddr)
+
+	var req struct {
+		Markdown string `json:"markdown"`
+		Version  int    `json:"version"` // Version the edit is based on (optional)
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("UpdateSavedQuiz failed to decode JSON body", "error", err, "id", id)
+		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	saved, err := h.library.Update(id, req.Markdown, req.Version)
+	if err != nil {
+		slog.Error("UpdateSavedQuiz failed to update quiz", "error", err, "id", id)
+		writeLibraryError(w, err)
+		return
+	}
+
+	slog.Info("UpdateSavedQuiz quiz updated successfully", "id", id, "version", saved.Version)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(saved)
+}
+
+// DeleteSavedQuizHandler removes a saved quiz and its history
+func (h *Handler) DeleteSavedQuizHandler(w http.ResponseWriter, r *http.Request) {
+	id := cleanCode(mux.Vars(r)["id"])
+
+	if err := h.library.Delete(id); err != nil {
+		slog.Error("DeleteSavedQuiz failed to delete quiz", "error", err, "id", id)
+		writeLibraryError(w, err)
+		return
+	}
+
+	slog.Info("DeleteSavedQuiz quiz deleted", "id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SavedQuizVersionsHandler returns the version history of a saved quiz
+func (h *Handler) SavedQuizVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	id := cleanCode(mux.Vars(r)["id"])
+
+	versions, err := h.library.Versions(id)
+	if err != nil {
+		writeLibraryError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":       id,
+		"versions": versions,
+	})
+}
+
+// versionParam reads the optional ?version= query parameter (0 = latest)
+func versionParam(r *http.Request) (int, error) {
+	value := r.URL.Query().Get("version")
+	if value == "" {
+		return 0, nil
+	}
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("invalid version '%s'", value)
+	}
+	return version, nil
+}
+
+// writeLibraryError maps library errors to HTTP status codes
+func writeLibraryError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, library.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, library.ErrVersionConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
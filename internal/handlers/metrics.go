@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"github.com/rkrmr33/quickwiz/internal/hub"
+	"github.com/rkrmr33/quickwiz/internal/metrics"
+)
+
+// RegisterMetrics registers gauges describing the handler's connections. It
+// must be called at most once per registry.
+func (h *Handler) RegisterMetrics(reg *metrics.Registry) {
+	reg.NewGaugeFunc("quickwiz_websocket_connections", "Open WebSocket connections.", func() float64 {
+		return float64(h.hub.CountTransport(hub.TransportWebSocket))
+	})
+	reg.NewGaugeFunc("quickwiz_sse_connections", "Open Server-Sent Events streams.", func() float64 {
+		return float64(h.hub.CountTransport(hub.TransportSSE))
+	})
+}
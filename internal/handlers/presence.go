@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/rkrmr33/quickwiz/internal/models"
+)
+
+// presenceEntry is the connection state of one participant
+type presenceEntry struct {
+	conns        int       // Open connections; a participant may have several tabs
+	lastSeen     time.Time // When the last connection closed, zero while online
+	lastReaction time.Time // When the participant last reacted
+}
+
+// presence tracks which participants currently have a live connection to
+// this instance. It is not shared: in a cluster, each instance only knows the
+// connections it holds, so online status and counts cover those alone.
+type presence struct {
+	entries map[string]map[string]*presenceEntry // quizCode -> participantID -> entry
+	mu      sync.Mutex
+}
+
+func newPresence() *presence {
+	return &presence{entries: make(map[string]map[string]*presenceEntry)}
+}
+
+// entry returns a participant's entry, creating it if needed; the caller must hold the lock
+func (p *presence) entry(code, participantID string) *presenceEntry {
+	if p.entries[code] == nil {
+		p.entries[code] = make(map[string]*presenceEntry)
+	}
+	e := p.entries[code][participantID]
+	if e == nil {
+		e = &presenceEntry{}
+		p.entries[code][participantID] = e
+	}
+	return e
+}
+
+// connect records a new connection and reports whether the participant just came online
+func (p *presence) connect(code, participantID string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e := p.entry(code, participantID)
+	e.conns++
+	e.lastSeen = time.Time{}
+	return e.conns == 1
+}
+
+// disconnect records a closed connection and reports whether the participant went offline
+func (p *presence) disconnect(code, participantID string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e := p.entries[code][participantID]
+	if e == nil || e.conns == 0 {
+		return false
+	}
+	e.conns--
+	if e.conns > 0 {
+		return false
+	}
+	e.lastSeen = time.Now()
+	return true
+}
+
+// status returns whether a participant is online and, if not, when they were last seen
+func (p *presence) status(code, participantID string) (bool, time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e := p.entries[code][participantID]
+	if e == nil {
+		return false, time.Time{}
+	}
+	return e.conns > 0, e.lastSeen
+}
+
+// onlineCount returns the number of participants of a quiz that are online
+func (p *presence) onlineCount(code string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	count := 0
+	for _, e := range p.entries[code] {
+		if e.conns > 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// allowReaction reports whether a participant may react now, at most once per
+// interval across all their connections
+func (p *presence) allowReaction(code, participantID string, interval time.Duration) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e := p.entry(code, participantID)
+	if time.Since(e.lastReaction) < interval {
+		return false
+	}
+	e.lastReaction = time.Now()
+	return true
+}
+
+// prune forgets quizzes for which keep returns false
+func (p *presence) prune(keep func(code string) bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for code := range p.entries {
+		if !keep(code) {
+			delete(p.entries, code)
+		}
+	}
+}
+
+// markOnline records a new connection, announcing the participant if they just came online
+func (h *Handler) markOnline(code, participantID string) {
+	if participantID == "" || !h.presence.connect(code, participantID) {
+		return
+	}
+	h.broadcastPresence(code, participantID, true)
+}
+
+// markOffline records a closed connection, announcing the participant if they went offline
+func (h *Handler) markOffline(code, participantID string) {
+	if participantID == "" || !h.presence.disconnect(code, participantID) {
+		return
+	}
+	h.broadcastPresence(code, participantID, false)
+}
+
+func (h *Handler) broadcastPresence(code, participantID string, online bool) {
+	session, err := h.quizManager.GetSession(code)
+	if err != nil {
+		return
+	}
+	participant, exists := session.Participants[participantID]
+	if !exists {
+		return
+	}
+
+	slog.Info("Participant presence changed", "code", code, "participant_id", participantID, "online", online)
+	h.broadcast(code, models.NewMessage(models.PresenceChanged{
+		ParticipantID: participantID,
+		Name:          participant.Name,
+		Online:        online,
+		OnlineCount:   h.presence.onlineCount(code),
+	}))
+}
+
+// PrunePresence forgets the presence of quizzes whose sessions no longer exist
+func (h *Handler) PrunePresence() {
+	h.presence.prune(func(code string) bool {
+		_, err := h.quizManager.GetSession(code)
+		return err == nil
+	})
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/rkrmr33/quickwiz/internal/game"
+	"github.com/rkrmr33/quickwiz/internal/models"
+)
+
+const (
+	// drainPollInterval is how often Shutdown checks whether games have finished
+	drainPollInterval = 500 * time.Millisecond
+	// handOverTimeout bounds stopping the games left at shutdown and releasing
+	// their leases
+	handOverTimeout = 5 * time.Second
+	// closeTimeout bounds writing the messages queued for each connection
+	// before it closes, once the drain is over
+	closeTimeout = 5 * time.Second
+)
+
+// Shutdown tells every connected client that the server is going away. If
+// drainGames is set, it waits for the games this instance runs to finish or
+// for ctx to expire. Games still running are then stopped and their leases
+// released, so another instance resumes them, before all client connections
+// are closed. The quiz manager should be drained first so no new games start
+// in the meantime.
+//
+// No snapshot of the sessions is taken: a shared store saves every change as
+// it is made, and sessions kept in memory end with the process.
+func (h *Handler) Shutdown(ctx context.Context, drainGames bool) {
+	draining := drainGames && h.activeGames() > 0
+	message := "The server is restarting. Please rejoin in a moment."
+	if draining {
+		message = "The server is restarting after this game."
+	}
+
+	for _, code := range h.hub.Codes() {
+		// Only this instance is going away, so only its connections are told
+		h.hub.Broadcast(code, models.NewMessage(models.ServerShutdown{
+			Message:  message,
+			Draining: draining,
+		}))
+	}
+
+	if draining {
+		slog.Info("Shutdown waiting for games to finish", "active_games", h.activeGames())
+		ticker := time.NewTicker(drainPollInterval)
+		defer ticker.Stop()
+
+	wait:
+		for h.activeGames() > 0 {
+			select {
+			case <-ctx.Done():
+				slog.Warn("Shutdown deadline reached with games in progress", "active_games", h.activeGames())
+				break wait
+			case <-ticker.C:
+			}
+		}
+	}
+
+	h.handOverGames()
+
+	// ctx may have expired draining, so flushing gets its own time
+	closeCtx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+	closed := h.hub.CloseAll(closeCtx, "server shutting down")
+	slog.Info("Shutdown closed client connections", "count", closed)
+}
+
+// handOverGames stops the games this instance runs, leaving their sessions
+// where they are, and waits for their leases to be released so another
+// instance can take them over without waiting for the leases to lapse
+func (h *Handler) handOverGames() {
+	h.gamesMu.Lock()
+	games := make([]*game.Game, 0, len(h.games))
+	for _, g := range h.games {
+		games = append(games, g)
+	}
+	h.gamesMu.Unlock()
+	if len(games) == 0 {
+		return
+	}
+
+	slog.Info("Shutdown handing over games in progress", "active_games", len(games))
+	for _, g := range games {
+		g.Stop()
+	}
+
+	deadline := time.Now().Add(handOverTimeout)
+	for h.activeGames() > 0 {
+		if time.Now().After(deadline) {
+			slog.Warn("Shutdown could not release every game lease", "active_games", h.activeGames())
+			return
+		}
+		time.Sleep(drainPollInterval / 10)
+	}
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rkrmr33/quickwiz/internal/protocol"
+)
+
+// SSEHandler streams the same messages as WebSocketHandler as Server-Sent
+// Events, for clients behind proxies that break WebSockets. SSE only flows
+// from the server, so these clients send commands to CommandHandler.
+func (h *Handler) SSEHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	code := cleanCode(vars["code"])
+	participantID := r.URL.Query().Get("participant_id")
+
+	slog.Info("SSE connection request", "code", code, "participant_id", participantID, "remote_addr", r.RemoteAddr)
+
+	// Verify session exists
+	if _, err := h.quizManager.GetSession(code); err != nil {
+		slog.Error("SSE quiz not found", "error", err, "code", code)
+		http.Error(w, "Quiz not found", http.StatusNotFound)
+		return
+	}
+
+	// EventSource cannot set headers, so the version always comes from the query string
+	version, err := protocol.Negotiate("", r.URL.Query().Get("protocol"))
+	if err != nil {
+		slog.Warn("SSE unsupported protocol version", "error", err, "code", code, "participant_id", participantID)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The stream's headers are already written when this fails, so there is
+	// no status left to report
+	client, err := h.hub.RegisterSSE(w, r, code, participantID, version)
+	if err != nil {
+		slog.Error("SSE stream error", "error", err, "code", code, "participant_id", participantID)
+		return
+	}
+
+	slog.Info("SSE connection established", "code", code, "participant_id", participantID, "protocol_version", version)
+
+	// Stream until the client goes away or the hub closes the stream
+	h.serveClient(client, code, nil)
+}
@@ -0,0 +1,414 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/rkrmr33/quickwiz/internal/models"
+	"github.com/rkrmr33/quickwiz/internal/protocol"
+)
+
+const (
+	// DefaultQueueSize is the number of messages buffered per connection
+	DefaultQueueSize = 64
+	// DefaultWriteTimeout bounds how long a single write may take
+	DefaultWriteTimeout = 10 * time.Second
+	// DefaultPingInterval is how often connections are pinged
+	DefaultPingInterval = 25 * time.Second
+	// DefaultPongTimeout is how long a connection may stay silent before it is
+	// considered dead; it must be longer than the ping interval
+	DefaultPongTimeout = 60 * time.Second
+	// DefaultMaxMessageSize limits messages read from clients
+	DefaultMaxMessageSize = 4096
+
+	// closeTimeout bounds writing the close frame when a client is closed
+	closeTimeout = time.Second
+)
+
+// Options configures a Hub
+type Options struct {
+	QueueSize      int           // Messages buffered per connection before it is evicted
+	WriteTimeout   time.Duration // Deadline for each write to a connection
+	PingInterval   time.Duration // How often to ping each connection
+	PongTimeout    time.Duration // Silence after which a connection is dropped
+	MaxMessageSize int64         // Largest message accepted from a client
+}
+
+// Hub tracks the connections of every quiz, whatever their transport. Each
+// connection has a single writer goroutine fed by a bounded queue, so
+// broadcasting never blocks on a slow client and writes to a connection are
+// never concurrent. Clients whose queue fills up are evicted.
+type Hub struct {
+	opts    Options
+	clients map[string]map[*Client]bool // quizCode -> clients
+	mu      sync.RWMutex
+}
+
+// New creates a hub. Zero options fall back to the defaults.
+func New(opts Options) *Hub {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = DefaultQueueSize
+	}
+	if opts.WriteTimeout <= 0 {
+		opts.WriteTimeout = DefaultWriteTimeout
+	}
+	if opts.PingInterval <= 0 {
+		opts.PingInterval = DefaultPingInterval
+	}
+	if opts.PongTimeout <= opts.PingInterval {
+		opts.PongTimeout = max(DefaultPongTimeout, 2*opts.PingInterval)
+	}
+	if opts.MaxMessageSize <= 0 {
+		opts.MaxMessageSize = DefaultMaxMessageSize
+	}
+	return &Hub{
+		opts:    opts,
+		clients: make(map[string]map[*Client]bool),
+	}
+}
+
+// outgoing is a queued frame
+type outgoing struct {
+	msgType string
+	data    []byte
+	close   bool // Write a close frame after everything queued before it
+}
+
+// Client is a registered connection
+type Client struct {
+	hub           *Hub
+	transport     Transport
+	code          string
+	participantID string
+	version       int    // Negotiated protocol version
+	seq           uint64 // Last sequence number written, owned by writeLoop
+	send          chan outgoing
+	done          chan struct{} // Closed when the client is closed
+	stopped       chan struct{} // Closed when writeLoop has returned
+	closeOnce     sync.Once
+}
+
+// RegisterWebSocket adds a WebSocket connection to a quiz
+func (h *Hub) RegisterWebSocket(conn *websocket.Conn, code, participantID string, version int) *Client {
+	websocketsOpened.Inc()
+	return h.Register(newWebSocket(conn, h.opts), code, participantID, version)
+}
+
+// RegisterSSE starts a Server-Sent Events stream on the response and adds it
+// to a quiz. The stream ends when Listen returns, so the HTTP handler must
+// call Listen before returning. On error the response has already been
+// started and can only be abandoned.
+func (h *Hub) RegisterSSE(w http.ResponseWriter, r *http.Request, code, participantID string, version int) (*Client, error) {
+	t, err := newSSE(w, r)
+	if err != nil {
+		return nil, err
+	}
+	sseOpened.Inc()
+	return h.Register(t, code, participantID, version), nil
+}
+
+// Register adds a connection speaking the given protocol version to a quiz and
+// starts its writer goroutine
+func (h *Hub) Register(t Transport, code, participantID string, version int) *Client {
+	c := &Client{
+		hub:           h,
+		transport:     t,
+		code:          code,
+		participantID: participantID,
+		version:       version,
+		send:          make(chan outgoing, h.opts.QueueSize),
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+
+	h.mu.Lock()
+	if h.clients[code] == nil {
+		h.clients[code] = make(map[*Client]bool)
+	}
+	h.clients[code][c] = true
+	h.mu.Unlock()
+
+	go c.writeLoop()
+	return c
+}
+
+// ParticipantID returns the participant the connection belongs to
+func (c *Client) ParticipantID() string {
+	return c.participantID
+}
+
+// Version returns the protocol version of the connection
+func (c *Client) Version() int {
+	return c.version
+}
+
+// Transport returns the name of the connection's transport
+func (c *Client) Transport() string {
+	return c.transport.Name()
+}
+
+// Send queues a message for the client. It never blocks; if the queue is full
+// the client is too slow to keep up and is evicted. Messages newer than the
+// client's protocol version are dropped.
+func (c *Client) Send(msg models.WebSocketMessage) bool {
+	if !protocol.Supports(c.version, msg.Type) {
+		return false
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		slog.Error("Hub failed to encode message", "error", err, "msg_type", msg.Type)
+		return false
+	}
+	if !c.enqueue(outgoing{msgType: msg.Type, data: data}) {
+		broadcastFailures.WithLabelValues(msg.Type).Inc()
+		return false
+	}
+	return true
+}
+
+// Close closes the connection immediately and removes it from the hub
+func (c *Client) Close(code int, reason string) {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.hub.remove(c)
+		c.transport.Close(code, reason)
+	})
+}
+
+// Done is closed once the client has been closed
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// enqueue adds a frame to the send queue, evicting the client if it is full
+func (c *Client) enqueue(o outgoing) bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+
+	select {
+	case c.send <- o:
+		return true
+	default:
+		slog.Warn("Hub evicting slow client", "code", c.code, "participant_id", c.participantID, "queued", len(c.send))
+		evictions.Inc()
+		c.Close(websocket.CloseTryAgainLater, "client too slow")
+		return false
+	}
+}
+
+// Listen reads messages from the client until the connection fails, is
+// closed or stays silent longer than the pong timeout, passing each one to
+// onMessage. It closes the client and waits for its writer to stop before
+// returning, so the connection is no longer used afterwards.
+func (c *Client) Listen(onMessage func(data []byte)) {
+	defer func() {
+		c.Close(websocket.CloseNormalClosure, "")
+		<-c.stopped
+	}()
+
+	for {
+		data, err := c.transport.Read()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				slog.Info("Hub connection lost", "error", err, "code", c.code, "participant_id", c.participantID, "transport", c.transport.Name())
+			}
+			return
+		}
+		if onMessage != nil {
+			onMessage(data)
+		}
+	}
+}
+
+// writeLoop is the only goroutine that writes data frames to the connection,
+// numbering them on protocol versions that carry sequence numbers. It also
+// pings the client so dead connections are noticed by Listen.
+func (c *Client) writeLoop() {
+	defer close(c.stopped)
+
+	ticker := time.NewTicker(c.hub.opts.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			if err := c.transport.Ping(time.Now().Add(c.hub.opts.WriteTimeout)); err != nil {
+				slog.Info("Hub ping failed", "error", err, "code", c.code, "participant_id", c.participantID)
+				c.Close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case o := <-c.send:
+			if o.close {
+				c.Close(websocket.CloseGoingAway, string(o.data))
+				return
+			}
+
+			data := o.data
+			if protocol.Sequenced(c.version) {
+				c.seq++
+				data = withSeq(data, c.seq)
+			}
+
+			if err := c.transport.Write(data, time.Now().Add(c.hub.opts.WriteTimeout)); err != nil {
+				slog.Warn("Hub write failed", "error", err, "code", c.code, "participant_id", c.participantID, "msg_type", o.msgType)
+				broadcastFailures.WithLabelValues(o.msgType).Inc()
+				c.Close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		}
+	}
+}
+
+// withSeq adds a sequence number to an encoded message. Messages are encoded
+// once per broadcast, so the number is spliced in rather than re-encoding.
+func withSeq(data []byte, seq uint64) []byte {
+	out := make([]byte, 0, len(data)+24)
+	out = append(out, `{"seq":`...)
+	out = strconv.AppendUint(out, seq, 10)
+	out = append(out, ',')
+	return append(out, data[1:]...)
+}
+
+// remove unregisters a client
+func (h *Hub) remove(c *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.clients[c.code], c)
+	if len(h.clients[c.code]) == 0 {
+		delete(h.clients, c.code)
+	}
+}
+
+// Clients returns a snapshot of the clients connected to a quiz
+func (h *Hub) Clients(code string) []*Client {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	clients := make([]*Client, 0, len(h.clients[code]))
+	for c := range h.clients[code] {
+		clients = append(clients, c)
+	}
+	return clients
+}
+
+// Broadcast sends a message to every client of a quiz whose protocol version
+// has it
+func (h *Hub) Broadcast(code string, msg models.WebSocketMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		slog.Error("Hub failed to encode message", "error", err, "msg_type", msg.Type)
+		return
+	}
+	h.BroadcastEncoded(code, msg.Type, data)
+}
+
+// BroadcastEncoded sends an already encoded message to every client of a
+// quiz, such as one relayed from another server instance
+func (h *Hub) BroadcastEncoded(code, msgType string, data []byte) {
+	start := time.Now()
+	defer func() { broadcastDuration.Observe(time.Since(start).Seconds()) }()
+
+	for _, c := range h.Clients(code) {
+		if !protocol.Supports(c.version, msgType) {
+			continue
+		}
+		if !c.enqueue(outgoing{msgType: msgType, data: data}) {
+			broadcastFailures.WithLabelValues(msgType).Inc()
+		}
+	}
+}
+
+// BroadcastEach sends every client of a quiz its own message, built by fn
+// from the client's participant ID. Clients for which fn returns false, or
+// whose protocol version lacks the message, are skipped.
+func (h *Hub) BroadcastEach(code string, fn func(participantID string) (models.WebSocketMessage, bool)) {
+	start := time.Now()
+	defer func() { broadcastDuration.Observe(time.Since(start).Seconds()) }()
+
+	for _, c := range h.Clients(code) {
+		if msg, ok := fn(c.participantID); ok {
+			c.Send(msg)
+		}
+	}
+}
+
+// Codes returns the quizzes with connected clients
+func (h *Hub) Codes() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	codes := make([]string, 0, len(h.clients))
+	for code := range h.clients {
+		codes = append(codes, code)
+	}
+	return codes
+}
+
+// Count returns the number of connected clients
+func (h *Hub) Count() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	count := 0
+	for _, clients := range h.clients {
+		count += len(clients)
+	}
+	return count
+}
+
+// CountTransport returns the number of connected clients using a transport
+func (h *Hub) CountTransport(name string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	count := 0
+	for _, clients := range h.clients {
+		for c := range clients {
+			if c.transport.Name() == name {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// CloseAll closes every client after the messages already queued for it have
+// been written, waiting until they are closed or ctx expires. It returns the
+// number of clients closed.
+func (h *Hub) CloseAll(ctx context.Context, reason string) int {
+	h.mu.RLock()
+	var clients []*Client
+	for _, quizClients := range h.clients {
+		for c := range quizClients {
+			clients = append(clients, c)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, c := range clients {
+		c.enqueue(outgoing{close: true, data: []byte(reason)})
+	}
+	for _, c := range clients {
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			c.Close(websocket.CloseGoingAway, reason)
+		}
+	}
+	return len(clients)
+}
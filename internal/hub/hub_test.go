@@ -0,0 +1,330 @@
+package hub
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/rkrmr33/quickwiz/internal/models"
+	"github.com/rkrmr33/quickwiz/internal/protocol"
+)
+
+// received is a message as decoded by a client
+type received struct {
+	Seq     uint64          `json:"seq"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// newTestServer serves WebSockets registered with the hub under the quiz
+// code, participant ID and protocol version given in the query string
+func newTestServer(t *testing.T, h *Hub) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		version, err := strconv.Atoi(r.URL.Query().Get("v"))
+		if err != nil {
+			version = protocol.Current
+		}
+		h.RegisterWebSocket(conn, r.URL.Query().Get("code"), r.URL.Query().Get("pid"), version).Listen(nil)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func dial(t *testing.T, srv *httptest.Server, code, pid string) *websocket.Conn {
+	return dialVersion(t, srv, code, pid, protocol.Current)
+}
+
+func dialVersion(t *testing.T, srv *httptest.Server, code, pid string, version int) *websocket.Conn {
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/?code=" + code + "&pid=" + pid + "&v=" + strconv.Itoa(version)
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// waitFor polls until cond holds or the test times out
+func waitFor(t *testing.T, what string, cond func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestBroadcastConcurrent(t *testing.T) {
+	h := New(Options{QueueSize: 1024})
+	srv := newTestServer(t, h)
+
+	conns := []*websocket.Conn{dial(t, srv, "abc", "p1"), dial(t, srv, "abc", "p2")}
+	other := dial(t, srv, "xyz", "p3")
+	waitFor(t, "clients to register", func() bool { return h.Count() == 3 })
+
+	// Broadcasting from many goroutines at once must not interleave writes
+	const senders, perSender = 10, 20
+	var wg sync.WaitGroup
+	for i := 0; i < senders; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perSender; j++ {
+				h.Broadcast("abc", models.NewMessage(models.TimeUpdate{TimeRemaining: j}))
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, conn := range conns {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		for i := 0; i < senders*perSender; i++ {
+			var msg received
+			if err := conn.ReadJSON(&msg); err != nil {
+				t.Fatalf("Failed to read message %d: %v", i, err)
+			}
+			if msg.Type != "time_update" {
+				t.Fatalf("Unexpected message type %s", msg.Type)
+			}
+		}
+	}
+
+	// Other quizzes receive nothing
+	other.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	if _, _, err := other.ReadMessage(); err == nil {
+		t.Error("Expected no message for another quiz")
+	}
+}
+
+func TestBroadcastEach(t *testing.T) {
+	h := New(Options{})
+	srv := newTestServer(t, h)
+
+	p1 := dial(t, srv, "abc", "p1")
+	p2 := dial(t, srv, "abc", "p2")
+	waitFor(t, "clients to register", func() bool { return h.Count() == 2 })
+
+	h.BroadcastEach("abc", func(pid string) (models.WebSocketMessage, bool) {
+		return models.NewMessage(models.QuestionUpdate{Text: pid}), pid == "p1"
+	})
+	h.Broadcast("abc", models.NewMessage(models.Countdown{}))
+
+	var msg received
+	var question models.QuestionUpdate
+	p1.ReadJSON(&msg)
+	json.Unmarshal(msg.Payload, &question)
+	if msg.Type != "question" || question.Text != "p1" {
+		t.Errorf("Expected p1's own question, got %+v", msg)
+	}
+	p2.ReadJSON(&msg)
+	if msg.Type != "countdown" {
+		t.Errorf("Expected p2 to be skipped for the question, got %+v", msg)
+	}
+}
+
+func TestBroadcastSkipsNewerMessages(t *testing.T) {
+	h := New(Options{})
+	srv := newTestServer(t, h)
+
+	v1 := dialVersion(t, srv, "abc", "p1", protocol.Version1)
+	v2 := dialVersion(t, srv, "abc", "p2", protocol.Version2)
+	waitFor(t, "clients to register", func() bool { return h.Count() == 2 })
+
+	h.Broadcast("abc", models.NewMessage(models.WagerPhase{}))
+	h.BroadcastEach("abc", func(pid string) (models.WebSocketMessage, bool) {
+		return models.NewMessage(models.Leaderboard{}), true
+	})
+	h.Broadcast("abc", models.NewMessage(models.Countdown{}))
+
+	var msg received
+	for _, want := range []string{"wager_phase", "leaderboard", "countdown"} {
+		v2.ReadJSON(&msg)
+		if msg.Type != want {
+			t.Errorf("Expected %s on version 2, got %+v", want, msg)
+		}
+	}
+	v1.ReadJSON(&msg)
+	if msg.Type != "countdown" {
+		t.Errorf("Expected version 1 to skip messages it does not know, got %+v", msg)
+	}
+}
+
+func TestSlowClientEvicted(t *testing.T) {
+	h := New(Options{QueueSize: 8, WriteTimeout: 200 * time.Millisecond})
+	srv := newTestServer(t, h)
+
+	slow := dial(t, srv, "abc", "slow")
+	fast := dial(t, srv, "abc", "fast")
+	waitFor(t, "clients to register", func() bool { return h.Count() == 2 })
+	_ = slow // Never read, so its writes back up
+
+	// Keep reading on the fast client
+	go func() {
+		for {
+			if _, _, err := fast.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	// Large messages fill the socket buffers of the slow client quickly
+	text := strings.Repeat("x", 256*1024)
+	for i := 0; i < 500 && h.Count() == 2; i++ {
+		start := time.Now()
+		h.Broadcast("abc", models.NewMessage(models.QuestionUpdate{Text: text}))
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Fatalf("Broadcast blocked on the slow client for %v", elapsed)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	waitFor(t, "slow client to be evicted", func() bool { return h.Count() == 1 })
+	clients := h.Clients("abc")
+	if len(clients) != 1 || clients[0].ParticipantID() != "fast" {
+		t.Errorf("Expected only the fast client to remain, got %d clients", len(clients))
+	}
+}
+
+func TestCloseAllFlushesQueuedMessages(t *testing.T) {
+	h := New(Options{})
+	srv := newTestServer(t, h)
+
+	conn := dial(t, srv, "abc", "p1")
+	waitFor(t, "client to register", func() bool { return h.Count() == 1 })
+
+	h.Broadcast("abc", models.NewMessage(models.ServerShutdown{}))
+	if closed := h.CloseAll(context.Background(), "bye"); closed != 1 {
+		t.Errorf("Expected 1 client closed, got %d", closed)
+	}
+
+	var msg received
+	if err := conn.ReadJSON(&msg); err != nil || msg.Type != "server_shutdown" {
+		t.Fatalf("Expected queued message before close, got %+v, %v", msg, err)
+	}
+	_, _, err := conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("Expected going away close frame, got %v", err)
+	}
+	if h.Count() != 0 {
+		t.Errorf("Expected no clients after CloseAll, got %d", h.Count())
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	h := New(Options{PingInterval: 50 * time.Millisecond, PongTimeout: 200 * time.Millisecond})
+	srv := newTestServer(t, h)
+
+	// Reading lets the client answer pings, so it stays connected
+	alive := dial(t, srv, "abc", "alive")
+	go func() {
+		for {
+			if _, _, err := alive.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	// A client that never reads never answers pings
+	dial(t, srv, "abc", "dead")
+
+	waitFor(t, "dead client to time out", func() bool { return h.Count() == 1 })
+	time.Sleep(300 * time.Millisecond)
+
+	clients := h.Clients("abc")
+	if len(clients) != 1 || clients[0].ParticipantID() != "alive" {
+		t.Errorf("Expected only the responsive client to remain, got %d clients", len(clients))
+	}
+}
+
+func TestSequenceNumbers(t *testing.T) {
+	h := New(Options{})
+	srv := newTestServer(t, h)
+
+	v1 := dialVersion(t, srv, "abc", "p1", protocol.Version1)
+	v2 := dialVersion(t, srv, "abc", "p2", protocol.Version2)
+	waitFor(t, "clients to register", func() bool { return h.Count() == 2 })
+
+	for i := 3; i >= 0; i-- {
+		h.Broadcast("abc", models.NewMessage(models.Countdown{Count: i}))
+	}
+
+	for i := 3; i >= 0; i-- {
+		var msg received
+		if err := v2.ReadJSON(&msg); err != nil {
+			t.Fatalf("Failed to read message: %v", err)
+		}
+		var countdown models.Countdown
+		json.Unmarshal(msg.Payload, &countdown)
+		if want := uint64(4 - i); msg.Seq != want || countdown.Count != i {
+			t.Errorf("Expected seq %d with count %d, got seq %d with count %d", want, i, msg.Seq, countdown.Count)
+		}
+	}
+
+	// Version 1 clients get messages as they always did
+	_, data, err := v1.ReadMessage()
+	if err != nil {
+		t.Fatalf("Failed to read message: %v", err)
+	}
+	if want := `{"type":"countdown","payload":{"count":3}}`; string(data) != want {
+		t.Errorf("Expected %s, got %s", want, data)
+	}
+}
+
+func TestSSE(t *testing.T) {
+	h := New(Options{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client, err := h.RegisterSSE(w, r, "abc", r.URL.Query().Get("pid"), protocol.Current)
+		if err != nil {
+			t.Errorf("Failed to register stream: %v", err)
+			return
+		}
+		client.Listen(nil)
+	}))
+	t.Cleanup(srv.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/?pid=p1", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Expected an event stream, got %s", ct)
+	}
+	waitFor(t, "stream to register", func() bool { return h.CountTransport(TransportSSE) == 1 })
+
+	h.Broadcast("abc", models.NewMessage(models.Countdown{Count: 3}))
+
+	lines := bufio.NewScanner(resp.Body)
+	for lines.Scan() {
+		data, ok := strings.CutPrefix(lines.Text(), "data: ")
+		if !ok {
+			continue // retry hint and blank separators
+		}
+		if want := `{"seq":1,"type":"countdown","payload":{"count":3}}`; data != want {
+			t.Errorf("Expected %s, got %s", want, data)
+		}
+		break
+	}
+
+	// The stream is dropped once the client goes away
+	cancel()
+	waitFor(t, "stream to be removed", func() bool { return h.Count() == 0 })
+}
@@ -0,0 +1,17 @@
+package hub
+
+import "github.com/rkrmr33/quickwiz/internal/metrics"
+
+var (
+	broadcastFailures = metrics.NewCounterVec("quickwiz_broadcast_failures_total",
+		"Messages that could not be delivered, by message type.", "type")
+	broadcastDuration = metrics.NewHistogram("quickwiz_broadcast_duration_seconds",
+		"Time taken to send a message to every connection of a quiz.",
+		[]float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1})
+	websocketsOpened = metrics.NewCounter("quickwiz_websocket_connections_opened_total",
+		"WebSocket connections accepted.")
+	sseOpened = metrics.NewCounter("quickwiz_sse_connections_opened_total",
+		"Server-Sent Events streams accepted.")
+	evictions = metrics.NewCounter("quickwiz_websocket_evictions_total",
+		"Connections closed because they could not keep up.")
+)
@@ -0,0 +1,149 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Transport names
+const (
+	TransportWebSocket = "websocket"
+	TransportSSE       = "sse"
+)
+
+// Transport carries messages between the hub and one client. The hub calls
+// Write and Ping from a single goroutine and Read from another; Close may be
+// called from anywhere.
+type Transport interface {
+	// Name identifies the kind of transport
+	Name() string
+	// Read blocks until the next message from the client. It returns io.EOF
+	// once the connection has been closed normally.
+	Read() ([]byte, error)
+	// Write sends one encoded message
+	Write(data []byte, deadline time.Time) error
+	// Ping keeps the connection alive and checks the client is still there
+	Ping(deadline time.Time) error
+	// Close ends the connection, telling the client why if the transport can
+	Close(code int, reason string)
+}
+
+// webSocket is a Transport over a WebSocket connection
+type webSocket struct {
+	conn        *websocket.Conn
+	pongTimeout time.Duration
+}
+
+func newWebSocket(conn *websocket.Conn, opts Options) *webSocket {
+	ws := &webSocket{conn: conn, pongTimeout: opts.PongTimeout}
+	conn.SetReadLimit(opts.MaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(opts.PongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(ws.pongTimeout))
+	})
+	return ws
+}
+
+func (ws *webSocket) Name() string { return TransportWebSocket }
+
+func (ws *webSocket) Read() ([]byte, error) {
+	_, data, err := ws.conn.ReadMessage()
+	if err != nil {
+		if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	ws.conn.SetReadDeadline(time.Now().Add(ws.pongTimeout))
+	return data, nil
+}
+
+func (ws *webSocket) Write(data []byte, deadline time.Time) error {
+	ws.conn.SetWriteDeadline(deadline)
+	return ws.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+func (ws *webSocket) Ping(deadline time.Time) error {
+	return ws.conn.WriteControl(websocket.PingMessage, nil, deadline)
+}
+
+func (ws *webSocket) Close(code int, reason string) {
+	// 1006 means the connection dropped, so there is no one to send a close frame to
+	if code != websocket.CloseAbnormalClosure {
+		ws.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(closeTimeout))
+	}
+	ws.conn.Close()
+}
+
+// sse is a Transport streaming Server-Sent Events on an HTTP response. It
+// only carries messages to the client; clients send commands over HTTP.
+type sse struct {
+	w         io.Writer
+	rc        *http.ResponseController
+	ctx       context.Context // Cancelled when the client goes away
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// sseRetry is how long browsers wait before reconnecting a dropped stream
+const sseRetry = 3 * time.Second
+
+func newSSE(w http.ResponseWriter, r *http.Request) (*sse, error) {
+	s := &sse{
+		w:      w,
+		rc:     http.NewResponseController(w),
+		ctx:    r.Context(),
+		closed: make(chan struct{}),
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Stop nginx from buffering the stream
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", sseRetry.Milliseconds())
+	if err := s.rc.Flush(); err != nil {
+		return nil, fmt.Errorf("streaming not supported: %w", err)
+	}
+	return s, nil
+}
+
+func (s *sse) Name() string { return TransportSSE }
+
+func (s *sse) Read() ([]byte, error) {
+	select {
+	case <-s.ctx.Done():
+	case <-s.closed:
+	}
+	return nil, io.EOF
+}
+
+func (s *sse) Write(data []byte, deadline time.Time) error {
+	return s.send(deadline, "data: %s\n\n", data)
+}
+
+func (s *sse) Ping(deadline time.Time) error {
+	return s.send(deadline, ": ping\n\n")
+}
+
+func (s *sse) send(deadline time.Time, format string, args ...interface{}) error {
+	if err := s.rc.SetWriteDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.w, format, args...); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// Close ends the stream once Listen returns; the response cannot be written
+// to from here because the writer goroutine may be using it
+func (s *sse) Close(code int, reason string) {
+	s.closeOnce.Do(func() { close(s.closed) })
+}
@@ -0,0 +1,352 @@
+package library
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rkrmr33/quickwiz/internal/models"
+	"github.com/rkrmr33/quickwiz/internal/parser"
+)
+
+var (
+	// ErrNotFound is returned when a quiz or version does not exist
+	ErrNotFound = errors.New("quiz not found")
+	// ErrVersionConflict is returned when an update is based on a stale version
+	ErrVersionConflict = errors.New("quiz was modified by someone else")
+)
+
+// record is a stored quiz with its full version history
+type record struct {
+	ID        string               `json:"id"`
+	CreatedAt time.Time            `json:"created_at"`
+	Versions  []models.QuizVersion `json:"versions"`
+
+	latest *summary // The parsed latest version, nil if it no longer parses
+}
+
+// summary is what listings show of a parsed quiz, kept so they do not parse
+// every quiz again
+type summary struct {
+	questionCount int
+	tags          []string
+}
+
+// Library stores quizzes so recurring games do not need their markdown pasted
+// again. Quizzes are kept in memory and, if a directory is configured,
+// persisted as one JSON file per quiz.
+type Library struct {
+	dir     string
+	timings parser.Timings
+	records map[string]*record
+	mu      sync.RWMutex
+}
+
+// NewLibrary creates a quiz library that validates quizzes with the default
+// timings. If dir is empty, quizzes are only kept in memory; otherwise
+// existing quizzes are loaded from dir.
+func NewLibrary(dir string) (*Library, error) {
+	return NewLibraryWithTimings(dir, parser.DefaultTimings())
+}
+
+// NewLibraryWithTimings creates a quiz library that validates quizzes with the
+// given default timings, so it accepts the same quizzes as creating a session
+func NewLibraryWithTimings(dir string, timings parser.Timings) (*Library, error) {
+	l := &Library{
+		dir:     dir,
+		timings: timings,
+		records: make(map[string]*record),
+	}
+
+	if dir == "" {
+		return l, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create library directory: %w", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list library directory: %w", err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		var rec record
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", file, err)
+		}
+		if rec.ID == "" || len(rec.Versions) == 0 {
+			return nil, fmt.Errorf("invalid library file %s", file)
+		}
+		if quiz, err := l.parse(rec.Versions[len(rec.Versions)-1].Markdown); err == nil {
+			rec.latest = summarize(quiz)
+		}
+		l.records[rec.ID] = &rec
+	}
+
+	return l, nil
+}
+
+// Create validates and stores a new quiz as version 1
+func (l *Library) Create(markdown string) (*models.SavedQuiz, error) {
+	quiz, err := l.parse(markdown)
+	if err != nil {
+		parser.CountFailure(parser.SourceLibrary)
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	id := generateID()
+	for l.records[id] != nil {
+		id = generateID()
+	}
+
+	now := time.Now()
+	rec := &record{
+		ID:        id,
+		CreatedAt: now,
+		Versions: []models.QuizVersion{{
+			Version:   1,
+			Title:     quiz.Title,
+			Markdown:  markdown,
+			CreatedAt: now,
+		}},
+		latest: summarize(quiz),
+	}
+
+	if err := l.save(rec); err != nil {
+		return nil, err
+	}
+	l.records[id] = rec
+
+	return savedQuiz(rec, len(rec.Versions), rec.latest), nil
+}
+
+// Get returns a quiz at the given version, or the latest version if version is 0
+func (l *Library) Get(id string, version int) (*models.SavedQuiz, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	rec, exists := l.records[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	if version == 0 {
+		version = len(rec.Versions)
+	}
+	if version < 0 || version > len(rec.Versions) {
+		return nil, fmt.Errorf("version %d: %w", version, ErrNotFound)
+	}
+
+	if version == len(rec.Versions) && rec.latest != nil {
+		return savedQuiz(rec, version, rec.latest), nil
+	}
+
+	quiz, err := l.parse(rec.Versions[version-1].Markdown)
+	if err != nil {
+		return nil, fmt.Errorf("stored quiz no longer parses: %w", err)
+	}
+
+	return savedQuiz(rec, version, summarize(quiz)), nil
+}
+
+// List returns the latest version of every quiz, most recently updated first.
+// Markdown is omitted to keep the listing small.
+func (l *Library) List() []models.SavedQuiz {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	quizzes := make([]models.SavedQuiz, 0, len(l.records))
+	for _, rec := range l.records {
+		if rec.latest == nil {
+			continue
+		}
+		saved := savedQuiz(rec, len(rec.Versions), rec.latest)
+		saved.Markdown = ""
+		quizzes = append(quizzes, *saved)
+	}
+
+	sort.Slice(quizzes, func(i, j int) bool {
+		if !quizzes[i].UpdatedAt.Equal(quizzes[j].UpdatedAt) {
+			return quizzes[i].UpdatedAt.After(quizzes[j].UpdatedAt)
+		}
+		return quizzes[i].ID < quizzes[j].ID
+	})
+
+	return quizzes
+}
+
+// Update stores new markdown as the next version of a quiz. If baseVersion is
+// non-zero, the update is rejected unless it matches the latest version.
+func (l *Library) Update(id, markdown string, baseVersion int) (*models.SavedQuiz, error) {
+	quiz, err := l.parse(markdown)
+	if err != nil {
+		parser.CountFailure(parser.SourceLibrary)
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rec, exists := l.records[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	if baseVersion != 0 && baseVersion != len(rec.Versions) {
+		return nil, fmt.Errorf("%w: based on version %d, latest is %d", ErrVersionConflict, baseVersion, len(rec.Versions))
+	}
+
+	updated := *rec
+	updated.Versions = append(append([]models.QuizVersion(nil), rec.Versions...), models.QuizVersion{
+		Version:   len(rec.Versions) + 1,
+		Title:     quiz.Title,
+		Markdown:  markdown,
+		CreatedAt: time.Now(),
+	})
+	updated.latest = summarize(quiz)
+
+	if err := l.save(&updated); err != nil {
+		return nil, err
+	}
+	l.records[id] = &updated
+
+	return savedQuiz(&updated, len(updated.Versions), updated.latest), nil
+}
+
+// Delete removes a quiz and all of its versions
+func (l *Library) Delete(id string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, exists := l.records[id]; !exists {
+		return ErrNotFound
+	}
+
+	if l.dir != "" {
+		if err := os.Remove(l.path(id)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete quiz: %w", err)
+		}
+	}
+
+	delete(l.records, id)
+	return nil
+}
+
+// Versions returns the version history of a quiz, oldest first, without markdown
+func (l *Library) Versions(id string) ([]models.QuizVersion, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	rec, exists := l.records[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	versions := make([]models.QuizVersion, len(rec.Versions))
+	for i, v := range rec.Versions {
+		v.Markdown = ""
+		versions[i] = v
+	}
+	return versions, nil
+}
+
+// Check verifies that quizzes can be persisted by writing and removing a
+// probe file. In-memory libraries are always healthy.
+func (l *Library) Check() error {
+	if l.dir == "" {
+		return nil
+	}
+
+	probe, err := os.CreateTemp(l.dir, ".check-*")
+	if err != nil {
+		return fmt.Errorf("library directory is not writable: %w", err)
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
+
+// Count returns the number of stored quizzes
+func (l *Library) Count() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return len(l.records)
+}
+
+// save persists a record; the caller must hold the write lock
+func (l *Library) save(rec *record) error {
+	if l.dir == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode quiz: %w", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a partial quiz
+	tmp := l.path(rec.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to save quiz: %w", err)
+	}
+	if err := os.Rename(tmp, l.path(rec.ID)); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to save quiz: %w", err)
+	}
+	return nil
+}
+
+// parse parses quiz markdown with the library's timings
+func (l *Library) parse(markdown string) (*models.Quiz, error) {
+	return parser.ParseQuizMarkdownWithDefaults(markdown, l.timings)
+}
+
+// path returns the file a quiz is persisted to
+func (l *Library) path(id string) string {
+	return filepath.Join(l.dir, id+".json")
+}
+
+// summarize keeps what listings show of a parsed quiz
+func summarize(quiz *models.Quiz) *summary {
+	return &summary{
+		questionCount: len(quiz.Questions),
+		tags:          quiz.Tags,
+	}
+}
+
+// savedQuiz builds the API view of a record at a version
+func savedQuiz(rec *record, version int, sum *summary) *models.SavedQuiz {
+	v := rec.Versions[version-1]
+	return &models.SavedQuiz{
+		ID:            rec.ID,
+		Title:         v.Title,
+		Version:       v.Version,
+		Markdown:      v.Markdown,
+		QuestionCount: sum.questionCount,
+		Tags:          sum.tags,
+		CreatedAt:     rec.CreatedAt,
+		UpdatedAt:     rec.Versions[len(rec.Versions)-1].CreatedAt,
+	}
+}
+
+// generateID generates a random 8-character quiz ID
+func generateID() string {
+	bytes := make([]byte, 4)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
@@ -0,0 +1,186 @@
+package library
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+const testMarkdown = `# Library Quiz
+
+### What is 2 + 2?
+- 3
+- 4
+* Answer: 4`
+
+func TestCreateAndGet(t *testing.T) {
+	lib, err := NewLibrary("")
+	if err != nil {
+		t.Fatalf("Failed to create library: %v", err)
+	}
+
+	saved, err := lib.Create(testMarkdown)
+	if err != nil {
+		t.Fatalf("Failed to save quiz: %v", err)
+	}
+	if saved.ID == "" {
+		t.Error("Expected non-empty ID")
+	}
+	if saved.Title != "Library Quiz" || saved.Version != 1 || saved.QuestionCount != 1 {
+		t.Errorf("Unexpected saved quiz: %+v", saved)
+	}
+
+	got, err := lib.Get(saved.ID, 0)
+	if err != nil {
+		t.Fatalf("Failed to get quiz: %v", err)
+	}
+	if got.Markdown != testMarkdown {
+		t.Errorf("Expected stored markdown to round-trip, got %q", got.Markdown)
+	}
+
+	if _, err := lib.Get("missing", 0); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestCreateInvalidMarkdown(t *testing.T) {
+	lib, _ := NewLibrary("")
+
+	if _, err := lib.Create("# No questions"); err == nil {
+		t.Error("Expected error for invalid markdown, got nil")
+	}
+	if len(lib.List()) != 0 {
+		t.Error("Expected invalid quiz not to be stored")
+	}
+}
+
+func TestUpdateVersions(t *testing.T) {
+	lib, _ := NewLibrary("")
+	saved, _ := lib.Create(testMarkdown)
+
+	updatedMarkdown := strings.Replace(testMarkdown, "Library Quiz", "Renamed Quiz", 1)
+	updated, err := lib.Update(saved.ID, updatedMarkdown, 1)
+	if err != nil {
+		t.Fatalf("Failed to update quiz: %v", err)
+	}
+	if updated.Version != 2 || updated.Title != "Renamed Quiz" {
+		t.Errorf("Expected version 2 titled 'Renamed Quiz', got %+v", updated)
+	}
+
+	// Stale updates are rejected
+	if _, err := lib.Update(saved.ID, testMarkdown, 1); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("Expected ErrVersionConflict, got %v", err)
+	}
+
+	// Old versions remain available
+	v1, err := lib.Get(saved.ID, 1)
+	if err != nil {
+		t.Fatalf("Failed to get version 1: %v", err)
+	}
+	if v1.Title != "Library Quiz" {
+		t.Errorf("Expected version 1 title 'Library Quiz', got '%s'", v1.Title)
+	}
+
+	versions, _ := lib.Versions(saved.ID)
+	if len(versions) != 2 {
+		t.Fatalf("Expected 2 versions, got %d", len(versions))
+	}
+	if versions[0].Markdown != "" {
+		t.Error("Expected version history to omit markdown")
+	}
+}
+
+func TestListFollowsUpdates(t *testing.T) {
+	lib, _ := NewLibrary("")
+	saved, _ := lib.Create(testMarkdown)
+
+	longer := testMarkdown + "\n\n### What is 3 + 3?\n- 6\n- 7\n* Answer: 6"
+	if _, err := lib.Update(saved.ID, longer, 0); err != nil {
+		t.Fatalf("Failed to update quiz: %v", err)
+	}
+
+	list := lib.List()
+	if len(list) != 1 {
+		t.Fatalf("Expected 1 quiz, got %d", len(list))
+	}
+	if list[0].Version != 2 || list[0].QuestionCount != 2 {
+		t.Errorf("Expected the listing to show version 2 with 2 questions, got %+v", list[0])
+	}
+	if list[0].Markdown != "" {
+		t.Error("Expected the listing to omit markdown")
+	}
+
+	// Older versions are described as they were
+	v1, _ := lib.Get(saved.ID, 1)
+	if v1.QuestionCount != 1 {
+		t.Errorf("Expected version 1 to have 1 question, got %d", v1.QuestionCount)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	lib, _ := NewLibrary("")
+	saved, _ := lib.Create(testMarkdown)
+
+	if err := lib.Delete(saved.ID); err != nil {
+		t.Fatalf("Failed to delete quiz: %v", err)
+	}
+	if _, err := lib.Get(saved.ID, 0); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after delete, got %v", err)
+	}
+	if err := lib.Delete(saved.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound deleting twice, got %v", err)
+	}
+}
+
+func TestPersistence(t *testing.T) {
+	dir := t.TempDir()
+
+	lib, err := NewLibrary(dir)
+	if err != nil {
+		t.Fatalf("Failed to create library: %v", err)
+	}
+	saved, _ := lib.Create(testMarkdown)
+	lib.Update(saved.ID, testMarkdown, 0)
+
+	// A new library on the same directory sees the stored quiz and its history
+	reloaded, err := NewLibrary(dir)
+	if err != nil {
+		t.Fatalf("Failed to reload library: %v", err)
+	}
+	got, err := reloaded.Get(saved.ID, 0)
+	if err != nil {
+		t.Fatalf("Failed to get reloaded quiz: %v", err)
+	}
+	if got.Version != 2 {
+		t.Errorf("Expected version 2 after reload, got %d", got.Version)
+	}
+	if list := reloaded.List(); len(list) != 1 || list[0].QuestionCount != 1 {
+		t.Errorf("Expected the reloaded quiz to be listed with 1 question, got %+v", list)
+	}
+
+	reloaded.Delete(saved.ID)
+	again, _ := NewLibrary(dir)
+	if len(again.List()) != 0 {
+		t.Error("Expected deleted quiz to be removed from disk")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	lib, err := NewLibrary(dir)
+	if err != nil {
+		t.Fatalf("Failed to create library: %v", err)
+	}
+	if err := lib.Check(); err != nil {
+		t.Errorf("Expected healthy library, got %v", err)
+	}
+
+	// A library whose directory disappeared can no longer persist quizzes
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatalf("Failed to remove directory: %v", err)
+	}
+	if err := lib.Check(); err == nil {
+		t.Error("Expected error for missing directory, got nil")
+	}
+}
@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"bytes"
+	"flag"
+	"math"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+func TestRegistryOutput(t *testing.T) {
+	r := NewRegistry()
+
+	answers := r.NewCounter("test_answers_total", "Answers submitted")
+	failures := r.NewCounterVec("test_failures_total", "Failures by type", "type")
+	latency := r.NewHistogram("test_latency_seconds", "Latency", []float64{1, 0.5, 5})
+	r.NewGaugeFunc("test_connections", "Open connections", func() float64 { return 3 })
+	r.NewGaugeVecFunc("test_sessions", "Sessions by state", "state", func() map[string]float64 {
+		return map[string]float64{"waiting": 2, "question": 1}
+	})
+
+	answers.Inc()
+	answers.Add(2)
+	failures.WithLabelValues("question").Inc()
+	failures.WithLabelValues(`say "hi"`).Inc()
+	for _, v := range []float64{0.2, 0.5, 0.7, 10} {
+		latency.Observe(v)
+	}
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	out := rec.Body.String()
+
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf("Unexpected content type %s", rec.Header().Get("Content-Type"))
+	}
+
+	want := []string{
+		"# HELP test_answers_total Answers submitted\n# TYPE test_answers_total counter\ntest_answers_total 3\n",
+		"# TYPE test_failures_total counter\n",
+		`test_failures_total{type="question"} 1`,
+		`test_failures_total{type="say \"hi\""} 1`,
+		"# TYPE test_latency_seconds histogram\n",
+		`test_latency_seconds_bucket{le="0.5"} 2`,
+		`test_latency_seconds_bucket{le="1"} 3`,
+		`test_latency_seconds_bucket{le="5"} 3`,
+		`test_latency_seconds_bucket{le="+Inf"} 4`,
+		"test_latency_seconds_sum 11.4\n",
+		"test_latency_seconds_count 4\n",
+		"# TYPE test_connections gauge\ntest_connections 3\n",
+		"test_sessions{state=\"question\"} 1\ntest_sessions{state=\"waiting\"} 2\n",
+	}
+	for _, w := range want {
+		if !strings.Contains(out, w) {
+			t.Errorf("Expected output to contain %q, got:\n%s", w, out)
+		}
+	}
+
+	// Metrics are sorted by name
+	if strings.Index(out, "test_answers_total") > strings.Index(out, "test_sessions") {
+		t.Error("Expected metrics to be sorted by name")
+	}
+}
+
+// TestExpositionGolden compares the whole exposition with
+// testdata/exposition.golden; run go test ./internal/metrics -update after a
+// deliberate format change
+func TestExpositionGolden(t *testing.T) {
+	r := NewRegistry()
+
+	requests := r.NewCounterVec("test_requests_total", "Requests by route and status", "route", "status")
+	requests.WithLabelValues("/api/quiz", "200").Add(5)
+	requests.WithLabelValues(`C:\quizzes`, "404").Inc()
+	requests.WithLabelValues(`say "hi"`, "400").Inc()
+	requests.WithLabelValues("line\nbreak", "500").Inc()
+	requests.WithLabelValues("", "200").Inc()
+
+	r.NewCounter("test_empty_total", "Help with a \\ backslash\nand a second line")
+	r.NewGaugeFunc("test_special", "Special values", func() float64 { return math.Inf(1) })
+	r.NewGaugeFunc("test_small", "Small values", func() float64 { return 0.000001 })
+	r.NewGaugeVecFunc("test_sessions", "Sessions by state", "state", func() map[string]float64 {
+		return map[string]float64{"waiting": 2, `odd "state"`: 1e21}
+	})
+	latency := r.NewHistogram("test_latency_seconds", "Latency", []float64{0.1, 1, 2.5})
+	for _, v := range []float64{0.05, 0.1, 2, 30} {
+		latency.Observe(v)
+	}
+
+	var out bytes.Buffer
+	r.Write(&out)
+
+	golden := "testdata/exposition.golden"
+	if *update {
+		if err := os.WriteFile(golden, out.Bytes(), 0o644); err != nil {
+			t.Fatalf("Failed to update %s: %v", golden, err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", golden, err)
+	}
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Errorf("Exposition differs from %s:\n--- got\n%s--- want\n%s", golden, out.Bytes(), want)
+	}
+}
+
+func TestDuplicateRegistrationPanics(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("test_total", "help")
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected duplicate registration to panic")
+		}
+	}()
+	r.NewCounter("test_total", "help")
+}
+
+func TestCounterConcurrent(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("test_total", "help", "kind")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c.WithLabelValues("a").Inc()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if v := c.WithLabelValues("a").Value(); v != 5000 {
+		t.Errorf("Expected 5000, got %v", v)
+	}
+}
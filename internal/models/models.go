@@ -1,24 +1,123 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
 // Quiz represents a parsed quiz from markdown
 type Quiz struct {
-	Title                string     `json:"title"`
-	TimePerQuestion      int        `json:"time_per_question"`      // in seconds
-	TimeBetweenQuestions int        `json:"time_between_questions"` // in seconds
-	StreakBonus          bool       `json:"streak_bonus"`           // Enable streak bonus points
-	QuickestAnswerBonus  bool       `json:"quickest_answer_bonus"`  // Give +1 point to first correct answer
-	Questions            []Question `json:"questions"`
+	Title                string       `json:"title"`
+	TimePerQuestion      int          `json:"time_per_question"`      // in seconds
+	TimeBetweenQuestions int          `json:"time_between_questions"` // in seconds
+	StreakBonus          bool         `json:"streak_bonus"`           // Enable streak bonus points
+	QuickestAnswerBonus  bool         `json:"quickest_answer_bonus"`  // Give +1 point to first correct answer
+	ShuffleOptions       bool         `json:"shuffle_options"`        // Show options in a different order to each participant
+	ShuffleQuestions     bool         `json:"shuffle_questions"`      // Ask questions in a random order
+	TimeBetweenRounds    int          `json:"time_between_rounds"`    // in seconds, round summary duration
+	RoundIntroTime       int          `json:"round_intro_time"`       // in seconds, round intro duration
+	Scoring              string       `json:"scoring,omitempty"`      // How correct answers score, classic when empty
+	SpeedDecay           string       `json:"speed_decay,omitempty"`  // How speed scoring falls with time, linear when empty
+	MaxPoints            int          `json:"max_points,omitempty"`   // Speed scoring points for an instant answer
+	MinPoints            int          `json:"min_points,omitempty"`   // Speed scoring points for an answer as time runs out
+	Penalty              int          `json:"penalty,omitempty"`      // Negative scoring points lost for a wrong answer
+	StreakTiers          []StreakTier `json:"streak_tiers,omitempty"` // Streak bonus tiers, DefaultStreakTiers when empty
+	StreakBreak          string       `json:"streak_break,omitempty"` // What breaks a streak, StreakBreakMissed when empty
+	Wagers               bool         `json:"wagers,omitempty"`       // Players stake points on each question before its options appear
+	WagerTime            int          `json:"wager_time,omitempty"`   // in seconds, wager phase duration
+	TieBreakers          []string     `json:"tie_breakers,omitempty"` // What ranks players tied on score, in order; ties stand when empty
+	Questions            []Question   `json:"questions"`
+	SuddenDeath          []Question   `json:"sudden_death,omitempty"` // Asked one at a time after the last question while first place is tied
+	Rounds               []Round      `json:"rounds,omitempty"`       // Rounds defined by "##" headings
+
+	// Question sampling
+	Sample int    `json:"sample,omitempty"` // Number of questions to draw per session (0 = all)
+	Seed   int64  `json:"seed,omitempty"`   // Fixed sampling seed (0 = random per session)
+	Pools  []Pool `json:"pools,omitempty"`  // Named question pools
+
+	// Metadata from YAML front matter
+	Author      string   `json:"author,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Category    string   `json:"category,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Language    string   `json:"language,omitempty"`
+	Difficulty  string   `json:"difficulty,omitempty"`
 }
 
+// Scoring modes
+const (
+	ScoringClassic    = "classic"    // One point per correct answer
+	ScoringSpeed      = "speed"      // Points fall from MaxPoints to MinPoints over the time limit
+	ScoringNegative   = "negative"   // One point per correct answer, Penalty lost per wrong one
+	ScoringConfidence = "confidence" // Players stake a confidence level on each answer, won or lost
+)
+
+// Speed scoring decays
+const (
+	DecayLinear      = "linear"      // Points fall by the same amount every second
+	DecayExponential = "exponential" // Points fall by the same fraction every second
+)
+
+// Default scoring points
+const (
+	DefaultMaxPoints = 1000
+	DefaultMinPoints = 500
+	DefaultPenalty   = 1
+)
+
+// DefaultWagerTime is how long players have to wager, in seconds, in quizzes
+// with wagers that do not set their own time
+const DefaultWagerTime = 10
+
+// StreakTier is the bonus for a streak of at least Streak correct answers:
+// either Bonus extra points or the answer's points times Multiplier
+type StreakTier struct {
+	Streak     int     `json:"streak"`
+	Bonus      int     `json:"bonus,omitempty"`
+	Multiplier float64 `json:"multiplier,omitempty"`
+}
+
+// DefaultStreakTiers are the streak bonus tiers of quizzes that do not set their own
+var DefaultStreakTiers = []StreakTier{
+	{Streak: 3, Bonus: 1},
+	{Streak: 5, Bonus: 2},
+	{Streak: 10, Bonus: 5},
+}
+
+// What breaks a streak
+const (
+	StreakBreakMissed = "missed" // A wrong answer or no answer
+	StreakBreakWrong  = "wrong"  // Only a wrong answer; not answering keeps the streak
+)
+
+// Tie-breakers ranking players with the same score
+const (
+	TieBreakCorrect     = "correct"      // More correct answers
+	TieBreakTime        = "time"         // Less time taken over all correct answers
+	TieBreakLastCorrect = "last_correct" // Last correct answer given earlier, so the score was reached first
+)
+
 // Question represents a single quiz question
 type Question struct {
 	Text    string   `json:"text"`
 	Options []string `json:"options"`
 	Answer  string   `json:"answer"`
+	Pool    string   `json:"pool,omitempty"` // Name of the pool the question belongs to
+	Round   int      `json:"round"`          // Index of the round the question belongs to
+}
+
+// Round is a titled group of consecutive questions with optional setting overrides
+type Round struct {
+	Title                string `json:"title"`
+	Description          string `json:"description,omitempty"`
+	TimePerQuestion      int    `json:"time_per_question,omitempty"`      // in seconds, 0 = quiz default
+	TimeBetweenQuestions int    `json:"time_between_questions,omitempty"` // in seconds, 0 = quiz default
+}
+
+// Pool is a named group of questions from which a random subset is drawn
+type Pool struct {
+	Name   string `json:"name"`
+	Sample int    `json:"sample,omitempty"` // Number of questions to draw (0 = all)
 }
 
 // QuizSession represents an active quiz session
@@ -28,9 +127,14 @@ type QuizSession struct {
 	Participants    map[string]*Participant `json:"participants"`
 	CreatorID       string                  `json:"creator_id"` // ID of the participant who created the quiz (spectator)
 	CurrentQuestion int                     `json:"current_question"`
+	CurrentRound    int                     `json:"current_round"` // Index into Quiz.Rounds
 	State           SessionState            `json:"state"`
 	CreatedAt       time.Time               `json:"created_at"`
 	QuestionStarted time.Time               `json:"question_started"`
+	Seed            int64                   `json:"seed"` // Seed used to sample questions, recorded for auditing
+	Paused          bool                    `json:"paused"`
+	PausedAt        time.Time               `json:"paused_at,omitempty"`
+	SuddenDeath     bool                    `json:"sudden_death,omitempty"` // Asking a sudden death question to the players tied for first
 }
 
 // Participant represents a user in a quiz session
@@ -39,11 +143,22 @@ type Participant struct {
 	Name          string    `json:"name"`
 	Score         int       `json:"score"`
 	CurrentAnswer string    `json:"current_answer"`
+	Confidence    int       `json:"confidence,omitempty"` // Confidence level put on the current answer
+	Wager         int       `json:"wager,omitempty"`      // Points staked on the current question
+	HasWagered    bool      `json:"has_wagered,omitempty"`
 	AnsweredAt    time.Time `json:"answered_at"`
 	HasAnswered   bool      `json:"has_answered"`
 	IsSpectator   bool      `json:"is_spectator"`   // True for the quiz creator
 	CurrentStreak int       `json:"current_streak"` // Consecutive correct answers
+	RoundScore    int       `json:"round_score"`    // Points earned in the current round
 	JoinedAt      time.Time `json:"joined_at"`      // When the participant joined
+
+	// Tie-breaking statistics
+	CorrectCount  int           `json:"correct_count"`
+	CorrectTime   time.Duration `json:"correct_time"`              // Time taken over all correct answers
+	LastCorrectAt time.Time     `json:"last_correct_at,omitempty"` // When the last correct answer was given
+	InSuddenDeath bool          `json:"in_sudden_death,omitempty"` // Tied for first, so answering sudden death questions
+	PreviousRank  int           `json:"previous_rank,omitempty"`   // Rank before the last answer reveal, 0 before the first
 }
 
 // SessionState represents the state of a quiz session
@@ -52,24 +167,56 @@ type SessionState string
 const (
 	StateWaiting    SessionState = "waiting"     // Waiting for participants
 	StateInProgress SessionState = "in_progress" // Quiz in progress
+	StateWager      SessionState = "wager"       // Showing question without options, taking wagers
 	StateQuestion   SessionState = "question"    // Showing question
 	StateAnswer     SessionState = "answer"      // Showing answer
+	StateRoundIntro SessionState = "round_intro" // Showing round summary and intro between rounds
 	StateFinished   SessionState = "finished"    // Quiz finished
 )
 
 // WebSocketMessage represents messages sent via WebSocket
 type WebSocketMessage struct {
-	Type    string      `json:"type"`
-	Payload interface{} `json:"payload"`
+	Seq     uint64  `json:"seq,omitempty"` // Position of the message on its connection, from protocol version 2
+	Type    string  `json:"type"`
+	Payload Payload `json:"payload"`
+}
+
+// Payload is implemented by the payload of every message the server sends
+type Payload interface {
+	MessageType() string
+}
+
+// NewMessage wraps a payload in a message of its type
+func NewMessage(p Payload) WebSocketMessage {
+	return WebSocketMessage{Type: p.MessageType(), Payload: p}
+}
+
+// Welcome sent first on every connection using protocol version 2 or later
+type Welcome struct {
+	ProtocolVersion int    `json:"protocol_version"` // Version negotiated for the connection
+	ParticipantID   string `json:"participant_id"`   // Participant the connection acts as
+}
+
+// Countdown sent every second before the first question
+type Countdown struct {
+	Count int `json:"count"` // Seconds left, 0 means go
+}
+
+// TimeUpdate sent every second with the time left in the current phase
+type TimeUpdate struct {
+	TimeRemaining int `json:"time_remaining"` // Seconds
 }
 
 // QuestionUpdate sent to participants when a new question starts
 type QuestionUpdate struct {
-	QuestionNumber int      `json:"question_number"`
-	TotalQuestions int      `json:"total_questions"`
-	Text           string   `json:"text"`
-	Options        []string `json:"options"`
-	TimeRemaining  int      `json:"time_remaining"`
+	QuestionNumber int         `json:"question_number"`
+	TotalQuestions int         `json:"total_questions"`
+	Text           string      `json:"text"`
+	Options        []string    `json:"options"`
+	TimeRemaining  int         `json:"time_remaining"`
+	MaxConfidence  int         `json:"max_confidence,omitempty"` // Highest confidence level an answer may carry, 0 when the quiz does not score confidence
+	StreakTier     *StreakTier `json:"streak_tier,omitempty"`    // Streak bonus a correct answer earns this participant, nil when none
+	SuddenDeath    bool        `json:"sudden_death,omitempty"`   // Only the players tied for first answer this question
 }
 
 // AnswerReveal sent when answer is revealed
@@ -81,13 +228,21 @@ type AnswerReveal struct {
 // ParticipantInfo for displaying participant status
 type ParticipantInfo struct {
 	Name                 string  `json:"name"`
+	Rank                 int     `json:"rank,omitempty"`          // Place in the standings, shared by tied players; set on leaderboards
+	Tied                 bool    `json:"tied,omitempty"`          // Shares its rank with another player
+	RankChange           int     `json:"rank_change,omitempty"`   // Places gained since the previous question, negative when lost
+	CorrectCount         int     `json:"correct_count,omitempty"` // Correct answers so far
 	Answer               string  `json:"answer"`
 	IsCorrect            bool    `json:"is_correct"`
 	Score                int     `json:"score"`
-	Streak               int     `json:"streak"`                 // Current streak count
-	StreakBonus          int     `json:"streak_bonus"`           // Bonus points earned from streak
-	QuickestAnswerFlag   bool    `json:"quickest_answer_flag"`   // True if this participant answered correctly first
-	AnswerSubmissionTime float64 `json:"answer_submission_time"` // Time in seconds to submit answer (0 if not answered)
+	RoundScore           int     `json:"round_score,omitempty"`      // Points earned in the current round
+	Streak               int     `json:"streak"`                     // Current streak count
+	StreakBonus          int     `json:"streak_bonus"`               // Bonus points earned from streak
+	StreakMilestone      int     `json:"streak_milestone,omitempty"` // Streak tier reached with this answer, 0 when none
+	Wager                int     `json:"wager,omitempty"`            // Points won (positive) or lost (negative) on the stake
+	Points               int     `json:"points"`                     // Points the answer earned, before bonuses
+	QuickestAnswerFlag   bool    `json:"quickest_answer_flag"`       // True if this participant answered correctly first
+	AnswerSubmissionTime float64 `json:"answer_submission_time"`     // Time in seconds to submit answer (0 if not answered)
 }
 
 // ParticipantJoined sent when a new participant joins
@@ -98,6 +253,14 @@ type ParticipantJoined struct {
 	ParticipantCount int    `json:"participant_count"`
 }
 
+// PresenceChanged sent when a participant connects or loses their last connection
+type PresenceChanged struct {
+	ParticipantID string `json:"participant_id"`
+	Name          string `json:"name"`
+	Online        bool   `json:"online"`
+	OnlineCount   int    `json:"online_count"` // Participants online on the server instance that sent it
+}
+
 // QuizFinished sent when quiz is complete
 type QuizFinished struct {
 	Leaderboard []ParticipantInfo `json:"leaderboard"`
@@ -109,3 +272,195 @@ type AnswerCountUpdate struct {
 	AnsweredCount     int    `json:"answered_count"`
 	TotalParticipants int    `json:"total_participants"`
 }
+
+// RoundIntro sent before the first question of a round
+type RoundIntro struct {
+	RoundNumber   int    `json:"round_number"`
+	TotalRounds   int    `json:"total_rounds"`
+	Title         string `json:"title"`
+	Description   string `json:"description,omitempty"`
+	QuestionCount int    `json:"question_count"`
+	TimeRemaining int    `json:"time_remaining"`
+}
+
+// RoundSummary sent between rounds with the round's standings
+type RoundSummary struct {
+	RoundNumber   int               `json:"round_number"`
+	TotalRounds   int               `json:"total_rounds"`
+	Title         string            `json:"title"`
+	Leaderboard   []ParticipantInfo `json:"leaderboard"` // Sorted by round score
+	TimeRemaining int               `json:"time_remaining"`
+}
+
+// Leaderboard sent to each connection after an answer is revealed, with the
+// leading players and where its own participant stands
+type Leaderboard struct {
+	QuestionNumber int               `json:"question_number"` // Question just revealed
+	TotalQuestions int               `json:"total_questions"`
+	Top            []ParticipantInfo `json:"top"`           // Leading players in rank order
+	You            *ParticipantInfo  `json:"you,omitempty"` // The participant's own standing, nil for spectators
+	TotalPlayers   int               `json:"total_players"`
+}
+
+// WagerPhase sent when a question with wagers starts, before its options
+// are shown
+type WagerPhase struct {
+	QuestionNumber int    `json:"question_number"`
+	TotalQuestions int    `json:"total_questions"`
+	Text           string `json:"text"`
+	MaxStake       int    `json:"max_stake"` // The participant's score; nothing can be staked without points
+	Stake          int    `json:"stake"`     // Points the participant has staked so far
+	TimeRemaining  int    `json:"time_remaining"`
+}
+
+// ServerShutdown sent when the server is shutting down
+type ServerShutdown struct {
+	Message  string `json:"message"`
+	Draining bool   `json:"draining"` // Games in progress may finish first, until the shutdown timeout
+}
+
+// PauseChanged sent when the host pauses or resumes the quiz
+type PauseChanged struct {
+	Paused bool `json:"paused"`
+}
+
+// Reaction sent when a participant reacts with an emoji
+type Reaction struct {
+	ParticipantID string `json:"participant_id"`
+	Name          string `json:"name"`
+	Emoji         string `json:"emoji"`
+}
+
+// ClientMessage is a command sent by a client over the WebSocket. The server
+// answers every command carrying an ID with an ack or an error.
+type ClientMessage struct {
+	Type    string          `json:"type"` // answer, wager, start, pause, react or ping
+	ID      string          `json:"id,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// AnswerCommand is the payload of an answer command
+type AnswerCommand struct {
+	Answer      string `json:"answer"`
+	OptionIndex *int   `json:"option_index"`         // Index of the option as displayed to the participant
+	Confidence  int    `json:"confidence,omitempty"` // Confidence level in quizzes scored by confidence, the lowest when omitted
+}
+
+// WagerCommand is the payload of a wager command
+type WagerCommand struct {
+	Stake int `json:"stake"` // Points to win or lose, from 0 to the participant's score
+}
+
+// PauseCommand is the payload of a pause command
+type PauseCommand struct {
+	Paused bool `json:"paused"` // False resumes the quiz
+}
+
+// ReactCommand is the payload of a react command
+type ReactCommand struct {
+	Emoji string `json:"emoji"`
+}
+
+// CommandAck acknowledges a client command
+type CommandAck struct {
+	ID string `json:"id"`
+}
+
+// Pong answers a ping command
+type Pong struct {
+	ID string `json:"id"`
+}
+
+// CommandError reports a client command that failed. Status is the HTTP
+// status the equivalent API request would have returned.
+type CommandError struct {
+	ID     string `json:"id,omitempty"`
+	Error  string `json:"error"`
+	Status int    `json:"status"`
+}
+
+func (Welcome) MessageType() string           { return "welcome" }
+func (Countdown) MessageType() string         { return "countdown" }
+func (TimeUpdate) MessageType() string        { return "time_update" }
+func (QuestionUpdate) MessageType() string    { return "question" }
+func (AnswerReveal) MessageType() string      { return "answer_reveal" }
+func (ParticipantJoined) MessageType() string { return "participant_joined" }
+func (PresenceChanged) MessageType() string   { return "presence_changed" }
+func (QuizFinished) MessageType() string      { return "quiz_finished" }
+func (AnswerCountUpdate) MessageType() string { return "answer_count_update" }
+func (RoundIntro) MessageType() string        { return "round_intro" }
+func (RoundSummary) MessageType() string      { return "round_summary" }
+func (Leaderboard) MessageType() string       { return "leaderboard" }
+func (WagerPhase) MessageType() string        { return "wager_phase" }
+func (ServerShutdown) MessageType() string    { return "server_shutdown" }
+func (PauseChanged) MessageType() string      { return "pause_changed" }
+func (Reaction) MessageType() string          { return "reaction" }
+func (CommandAck) MessageType() string        { return "ack" }
+func (Pong) MessageType() string              { return "pong" }
+func (CommandError) MessageType() string      { return "error" }
+
+// ServerStatus describes the running server for operators
+type ServerStatus struct {
+	Ready           bool                 `json:"ready"`
+	Draining        bool                 `json:"draining"`
+	StartedAt       time.Time            `json:"started_at"`
+	UptimeSeconds   int64                `json:"uptime_seconds"`
+	Sessions        int                  `json:"sessions"`
+	SessionsByState map[SessionState]int `json:"sessions_by_state"`
+	ActiveGames     int                  `json:"active_games"`
+	Players         int                  `json:"players"`
+	Spectators      int                  `json:"spectators"`
+	Connections     int                  `json:"connections"`
+	Goroutines      int                  `json:"goroutines"`
+	Memory          MemoryStatus         `json:"memory"`
+	Library         StoreStatus          `json:"library"`
+	CatalogQuizzes  int                  `json:"catalog_quizzes"`
+}
+
+// MemoryStatus summarizes Go runtime memory usage in bytes
+type MemoryStatus struct {
+	HeapAlloc uint64 `json:"heap_alloc"`
+	HeapInuse uint64 `json:"heap_inuse"`
+	Sys       uint64 `json:"sys"`
+	NumGC     uint32 `json:"num_gc"`
+}
+
+// StoreStatus reports the health of a persistent store
+type StoreStatus struct {
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
+	Quizzes int    `json:"quizzes"`
+}
+
+// SavedQuiz is a quiz stored in the library
+type SavedQuiz struct {
+	ID            string    `json:"id"`
+	Title         string    `json:"title"`
+	Version       int       `json:"version"`
+	Markdown      string    `json:"markdown,omitempty"`
+	QuestionCount int       `json:"question_count"`
+	Tags          []string  `json:"tags,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// QuizVersion is a single revision of a saved quiz
+type QuizVersion struct {
+	Version   int       `json:"version"`
+	Title     string    `json:"title"`
+	Markdown  string    `json:"markdown,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CatalogEntry describes a built-in quiz served from the catalog
+type CatalogEntry struct {
+	ID            string   `json:"id"`
+	Title         string   `json:"title"`
+	Description   string   `json:"description,omitempty"`
+	Author        string   `json:"author,omitempty"`
+	Category      string   `json:"category"`
+	Difficulty    string   `json:"difficulty,omitempty"`
+	Language      string   `json:"language,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
+	QuestionCount int      `json:"question_count"`
+}
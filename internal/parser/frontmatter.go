@@ -0,0 +1,215 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rkrmr33/quickwiz/internal/models"
+)
+
+const frontMatterDelimiter = "---"
+
+// splitFrontMatter separates a leading "---" delimited front matter block from
+// the rest of the markdown. If the markdown has no front matter, lines is nil
+// and rest is the markdown unchanged.
+func splitFrontMatter(markdown string) (lines []string, rest string, err error) {
+	all := strings.Split(markdown, "\n")
+
+	// Front matter must be the first non-empty line
+	start := 0
+	for start < len(all) && strings.TrimSpace(all[start]) == "" {
+		start++
+	}
+	if start == len(all) || strings.TrimSpace(all[start]) != frontMatterDelimiter {
+		return nil, markdown, nil
+	}
+
+	for i := start + 1; i < len(all); i++ {
+		if strings.TrimSpace(all[i]) == frontMatterDelimiter {
+			return all[start+1 : i], strings.Join(all[i+1:], "\n"), nil
+		}
+	}
+
+	return nil, "", fmt.Errorf("front matter starting on line %d is not closed with '---'", start+1)
+}
+
+// parseFrontMatter parses a minimal YAML subset into the quiz:
+//
+//	key: value
+//	key: "quoted value"
+//	key: [a, b, c]
+//	key:
+//	  - a
+//	  - b
+//
+// Settings keys are shared with the "# Settings" section; metadata keys
+// (title, author, description, category, tags, language, difficulty) are
+// stored on the quiz. A list replaces whatever the key held before, as the
+// same key does in the "# Settings" section.
+func parseFrontMatter(quiz *models.Quiz, lines []string) error {
+	var listKey string
+	var listItems []string
+	listLine := 0
+
+	// A block list is applied whole once its last item has been read
+	endList := func() error {
+		if listKey == "" {
+			return nil
+		}
+		key, items := listKey, listItems
+		listKey, listItems = "", nil
+		if err := applyFrontMatterList(quiz, key, items); err != nil {
+			return fmt.Errorf("front matter line %d: %w", listLine, err)
+		}
+		return nil
+	}
+
+	for i, line := range lines {
+		lineNum := i + 2 // account for the opening delimiter
+		trimmed := strings.TrimSpace(stripComment(line))
+
+		if trimmed == "" {
+			continue
+		}
+
+		// Block list item belonging to the previous key
+		if strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+			if listKey == "" {
+				return fmt.Errorf("front matter line %d: list item without a key", lineNum)
+			}
+			listItems = append(listItems, unquote(strings.TrimSpace(strings.TrimPrefix(trimmed, "-"))))
+			continue
+		}
+		if err := endList(); err != nil {
+			return err
+		}
+
+		parts := strings.SplitN(trimmed, ":", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("front matter line %d: expected 'key: value'", lineNum)
+		}
+		key := settingKey(parts[0])
+		value := strings.TrimSpace(parts[1])
+
+		// "key:" with no value starts a block list
+		if value == "" {
+			listKey, listLine = key, lineNum
+			continue
+		}
+
+		// Inline list
+		if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+			if err := applyFrontMatterList(quiz, key, splitInlineList(value)); err != nil {
+				return fmt.Errorf("front matter line %d: %w", lineNum, err)
+			}
+			continue
+		}
+
+		if err := applyFrontMatterValue(quiz, key, unquote(value)); err != nil {
+			return fmt.Errorf("front matter line %d: %w", lineNum, err)
+		}
+	}
+
+	return endList()
+}
+
+// applyFrontMatterValue applies a scalar front matter value to the quiz
+func applyFrontMatterValue(quiz *models.Quiz, key, value string) error {
+	switch key {
+	case "title":
+		quiz.Title = value
+	case "author":
+		quiz.Author = value
+	case "description":
+		quiz.Description = value
+	case "category":
+		quiz.Category = strings.ToLower(value)
+	case "language", "lang":
+		quiz.Language = value
+	case "difficulty":
+		quiz.Difficulty = strings.ToLower(value)
+	case "tags":
+		// Allow a comma-separated scalar as a shorthand
+		return applyFrontMatterList(quiz, key, strings.Split(value, ","))
+	default:
+		return applySetting(quiz, key, value)
+	}
+	return nil
+}
+
+// applyFrontMatterList sets a list value on the quiz, replacing any earlier one
+func applyFrontMatterList(quiz *models.Quiz, key string, items []string) error {
+	if key == "streak_tiers" {
+		tiers, err := parseStreakTiers(items)
+		if err != nil {
+			return err
+		}
+		quiz.StreakTiers = tiers
+		quiz.StreakBonus = true
+		return nil
+	}
+	if key == "tie_breakers" {
+		tieBreakers, err := parseTieBreakers(items)
+		if err != nil {
+			return err
+		}
+		quiz.TieBreakers = tieBreakers
+		return nil
+	}
+	if key != "tags" {
+		return fmt.Errorf("key '%s' does not accept a list", key)
+	}
+	quiz.Tags = nil
+	for _, item := range items {
+		tag := strings.ToLower(strings.TrimSpace(unquote(item)))
+		if tag != "" {
+			quiz.Tags = append(quiz.Tags, tag)
+		}
+	}
+	return nil
+}
+
+// splitInlineList splits "[a, b, c]" into its items
+func splitInlineList(value string) []string {
+	inner := strings.TrimSpace(value[1 : len(value)-1])
+	if inner == "" {
+		return nil
+	}
+	items := strings.Split(inner, ",")
+	for i := range items {
+		items[i] = strings.TrimSpace(items[i])
+	}
+	return items
+}
+
+// unquote strips matching single or double quotes around a value
+func unquote(s string) string {
+	if len(s) >= 2 {
+		if (s[0] == '"' && s[len(s)-1] == '"') || (s[0] == '\'' && s[len(s)-1] == '\'') {
+			return s[1 : len(s)-1]
+		}
+	}
+	return s
+}
+
+// stripComment removes a trailing "# comment" that is not inside quotes
+func stripComment(line string) string {
+	inSingle, inDouble := false, false
+	for i, r := range line {
+		switch r {
+		case '\'':
+			if !inDouble {
+				inSingle = !inSingle
+			}
+		case '"':
+			if !inSingle {
+				inDouble = !inDouble
+			}
+		case '#':
+			if !inSingle && !inDouble && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t') {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
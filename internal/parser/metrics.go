@@ -0,0 +1,19 @@
+package parser
+
+import "github.com/rkrmr33/quickwiz/internal/metrics"
+
+// Sources of quiz markdown, used to label parse failures
+const (
+	SourceCreate  = "create"  // Pasted or picked when creating a session
+	SourceLibrary = "library" // Saved to the quiz library
+	SourceCatalog = "catalog" // A built-in quiz file
+)
+
+var parseFailures = metrics.NewCounterVec("quickwiz_parse_failures_total",
+	"Quizzes rejected because their markdown failed to parse, by where it came from.", "source")
+
+// CountFailure records a quiz rejected by the parser. source is where its
+// markdown came from, one of the Source constants.
+func CountFailure(source string) {
+	parseFailures.WithLabelValues(source).Inc()
+}
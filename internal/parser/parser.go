@@ -4,22 +4,68 @@ import (
 	"bufio"
 	"fmt"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 
 	"github.com/rkrmr33/quickwiz/internal/models"
 )
 
+// Timings are the default timings, in seconds, of quizzes that do not set their own
+type Timings struct {
+	TimePerQuestion      int
+	TimeBetweenQuestions int
+	TimeBetweenRounds    int
+	RoundIntroTime       int
+}
+
+// DefaultTimings returns the built-in default timings
+func DefaultTimings() Timings {
+	return Timings{
+		TimePerQuestion:      30, // default 30 seconds
+		TimeBetweenQuestions: 5,  // default 5 seconds
+		TimeBetweenRounds:    10, // default 10 seconds
+		RoundIntroTime:       5,  // default 5 seconds
+	}
+}
+
 // ParseQuizMarkdown parses a markdown string into a Quiz struct
 func ParseQuizMarkdown(markdown string) (*models.Quiz, error) {
+	return ParseQuizMarkdownWithDefaults(markdown, DefaultTimings())
+}
+
+// ParseQuizMarkdownWithDefaults parses a markdown string into a Quiz struct,
+// using the given timings for settings the quiz does not specify
+func ParseQuizMarkdownWithDefaults(markdown string, defaults Timings) (*models.Quiz, error) {
 	quiz := &models.Quiz{
-		TimePerQuestion:      30, // default 30 seconds
-		TimeBetweenQuestions: 5,  // default 5 seconds
+		TimePerQuestion:      defaults.TimePerQuestion,
+		TimeBetweenQuestions: defaults.TimeBetweenQuestions,
+		TimeBetweenRounds:    defaults.TimeBetweenRounds,
+		RoundIntroTime:       defaults.RoundIntroTime,
+		MaxPoints:            models.DefaultMaxPoints,
+		MinPoints:            unsetMinPoints,
+		Penalty:              models.DefaultPenalty,
 		Questions:            []models.Question{},
 	}
 
-	scanner := bufio.NewScanner(strings.NewReader(markdown))
+	// Optional YAML front matter for settings and metadata
+	frontMatter, body, err := splitFrontMatter(markdown)
+	if err != nil {
+		return nil, err
+	}
+	if frontMatter != nil {
+		if err := parseFrontMatter(quiz, frontMatter); err != nil {
+			return nil, err
+		}
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(body))
 	var currentQuestion *models.Question
+	var currentPool *models.Pool
+	currentRound := -1
+	inRoundHeader := false
+	inSuddenDeath := false
+	questionsBeforeRounds := 0
 	inSettings := false
 	lineNum := 0
 
@@ -51,42 +97,100 @@ func ParseQuizMarkdown(markdown string) (*models.Quiz, error) {
 				inSettings = false
 			} else if strings.Contains(trimmed, ":") {
 				parts := strings.SplitN(trimmed, ":", 2)
-				key := strings.TrimSpace(parts[0])
+				key := settingKey(parts[0])
 				value := strings.TrimSpace(parts[1])
+				if err := applySetting(quiz, key, value); err != nil {
+					return nil, fmt.Errorf("line %d: %w", lineNum, err)
+				}
+				continue
+			}
+		}
+
+		// Parse section headings (## prefix); "## Pool: Name" starts a question
+		// pool, "## Sudden Death" holds the tiebreak questions and any other
+		// heading starts a new round
+		if strings.HasPrefix(trimmed, "## ") {
+			addQuestion(quiz, currentQuestion, inSuddenDeath)
+			currentQuestion = nil
+			currentPool = nil
+			inRoundHeader = false
+			inSuddenDeath = false
 
-				if key == "time_per_question" {
-					// Parse duration (e.g., "10 seconds", "1 minute")
-					timeVal, err := parseDuration(value)
-					if err == nil {
-						quiz.TimePerQuestion = timeVal
-					}
-				} else if key == "time_between_questions" {
-					// Parse duration (e.g., "10 seconds", "1 minute")
-					timeVal, err := parseDuration(value)
-					if err == nil {
-						quiz.TimeBetweenQuestions = timeVal
-					}
-				} else if key == "streak_bonus" {
-					// Parse boolean (e.g., "true", "false", "yes", "no")
-					quiz.StreakBonus = parseBool(value)
-				} else if key == "quickest_answer_bonus" {
-					// Parse boolean (e.g., "true", "false", "yes", "no")
-					quiz.QuickestAnswerBonus = parseBool(value)
+			heading := strings.TrimSpace(strings.TrimPrefix(trimmed, "## "))
+			if strings.EqualFold(heading, "sudden death") {
+				inSuddenDeath = true
+				continue
+			}
+			name, isPool := cutPrefixFold(heading, "pool:")
+			if !isPool {
+				if title, ok := cutPrefixFold(heading, "round:"); ok {
+					heading = title
+				}
+				if heading == "" {
+					return nil, fmt.Errorf("line %d: round must have a title", lineNum)
 				}
+				quiz.Rounds = append(quiz.Rounds, models.Round{Title: heading})
+				currentRound = len(quiz.Rounds) - 1
+				inRoundHeader = true
 				continue
 			}
+			if name == "" {
+				return nil, fmt.Errorf("line %d: pool must have a name", lineNum)
+			}
+			for _, pool := range quiz.Pools {
+				if pool.Name == name {
+					return nil, fmt.Errorf("line %d: duplicate pool '%s'", lineNum, name)
+				}
+			}
+			quiz.Pools = append(quiz.Pools, models.Pool{Name: name})
+			currentPool = &quiz.Pools[len(quiz.Pools)-1]
+			continue
+		}
+
+		// Parse round settings (between the round heading and its first question)
+		if inRoundHeader && !strings.HasPrefix(trimmed, "#") && strings.Contains(trimmed, ":") {
+			parts := strings.SplitN(trimmed, ":", 2)
+			key := settingKey(parts[0])
+			value := strings.TrimSpace(parts[1])
+			if err := applyRoundSetting(&quiz.Rounds[currentRound], key, value); err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNum, err)
+			}
+			continue
+		}
+
+		// Parse pool settings (between the pool heading and its first question)
+		if currentPool != nil && currentQuestion == nil && !strings.HasPrefix(trimmed, "#") && strings.Contains(trimmed, ":") {
+			parts := strings.SplitN(trimmed, ":", 2)
+			key := settingKey(parts[0])
+			value := strings.TrimSpace(parts[1])
+			if key != "sample" {
+				return nil, fmt.Errorf("line %d: unknown pool setting '%s'", lineNum, key)
+			}
+			sample, err := parsePositiveInt(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid pool sample: %w", lineNum, err)
+			}
+			currentPool.Sample = sample
+			continue
 		}
 
 		// Parse question (### prefix)
 		if strings.HasPrefix(trimmed, "###") {
 			// Save previous question if exists
-			if currentQuestion != nil && currentQuestion.Text != "" {
-				quiz.Questions = append(quiz.Questions, *currentQuestion)
-			}
+			addQuestion(quiz, currentQuestion, inSuddenDeath)
 			currentQuestion = &models.Question{
 				Text:    strings.TrimSpace(strings.TrimPrefix(trimmed, "###")),
 				Options: []string{},
 			}
+			if currentPool != nil {
+				currentQuestion.Pool = currentPool.Name
+			}
+			if currentRound >= 0 {
+				currentQuestion.Round = currentRound
+			} else if !inSuddenDeath {
+				questionsBeforeRounds++
+			}
+			inRoundHeader = false
 			continue
 		}
 
@@ -110,9 +214,7 @@ func ParseQuizMarkdown(markdown string) (*models.Quiz, error) {
 	}
 
 	// Add last question
-	if currentQuestion != nil && currentQuestion.Text != "" {
-		quiz.Questions = append(quiz.Questions, *currentQuestion)
-	}
+	addQuestion(quiz, currentQuestion, inSuddenDeath)
 
 	if err := scanner.Err(); err != nil {
 		return nil, fmt.Errorf("error reading markdown: %w", err)
@@ -127,31 +229,354 @@ func ParseQuizMarkdown(markdown string) (*models.Quiz, error) {
 	}
 
 	for i, q := range quiz.Questions {
-		if q.Text == "" {
-			return nil, fmt.Errorf("question %d has no text", i+1)
+		if err := validateQuestion(fmt.Sprintf("question %d", i+1), q); err != nil {
+			return nil, err
 		}
-		if len(q.Options) == 0 {
-			return nil, fmt.Errorf("question %d has no options", i+1)
+	}
+	for i, q := range quiz.SuddenDeath {
+		if err := validateQuestion(fmt.Sprintf("sudden death question %d", i+1), q); err != nil {
+			return nil, err
 		}
-		if q.Answer == "" {
-			return nil, fmt.Errorf("question %d has no answer", i+1)
+	}
+
+	if len(quiz.Rounds) > 0 {
+		if questionsBeforeRounds > 0 {
+			return nil, fmt.Errorf("%d question(s) appear before the first round", questionsBeforeRounds)
 		}
-		// Validate answer is in options
-		found := false
-		for _, opt := range q.Options {
-			if opt == q.Answer {
-				found = true
-				break
+		for i, round := range quiz.Rounds {
+			empty := true
+			for _, q := range quiz.Questions {
+				if q.Round == i {
+					empty = false
+					break
+				}
+			}
+			if empty {
+				return nil, fmt.Errorf("round '%s' has no questions", round.Title)
 			}
 		}
-		if !found {
-			return nil, fmt.Errorf("question %d: answer '%s' not found in options", i+1, q.Answer)
-		}
+	}
+
+	if err := validateSampling(quiz); err != nil {
+		return nil, err
+	}
+	if err := validateScoring(quiz); err != nil {
+		return nil, err
+	}
+	if err := validateStreakTiers(quiz.StreakTiers); err != nil {
+		return nil, err
 	}
 
 	return quiz, nil
 }
 
+// addQuestion adds a parsed question to the quiz's questions, or to its sudden
+// death questions
+func addQuestion(quiz *models.Quiz, question *models.Question, suddenDeath bool) {
+	switch {
+	case question == nil || question.Text == "":
+	case suddenDeath:
+		quiz.SuddenDeath = append(quiz.SuddenDeath, *question)
+	default:
+		quiz.Questions = append(quiz.Questions, *question)
+	}
+}
+
+// validateQuestion checks that a question has text, options and an answer
+// among its options
+func validateQuestion(label string, q models.Question) error {
+	if q.Text == "" {
+		return fmt.Errorf("%s has no text", label)
+	}
+	if len(q.Options) == 0 {
+		return fmt.Errorf("%s has no options", label)
+	}
+	if q.Answer == "" {
+		return fmt.Errorf("%s has no answer", label)
+	}
+	// Validate answer is in options
+	found := false
+	for _, opt := range q.Options {
+		if opt == q.Answer {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("%s: answer '%s' not found in options", label, q.Answer)
+	}
+	return nil
+}
+
+// unsetMinPoints marks min_points as not given, so its default can follow
+// max_points once all settings are read
+const unsetMinPoints = -1
+
+// validateScoring checks the scoring points. Quizzes only keep the points of
+// the scoring they use.
+func validateScoring(quiz *models.Quiz) error {
+	if quiz.Scoring != models.ScoringNegative {
+		quiz.Penalty = 0
+	}
+	if !quiz.Wagers {
+		quiz.WagerTime = 0
+	}
+	if quiz.Scoring != models.ScoringSpeed {
+		quiz.SpeedDecay, quiz.MaxPoints, quiz.MinPoints = "", 0, 0
+		return nil
+	}
+	if quiz.MinPoints == unsetMinPoints {
+		// Keep the default's share of max_points when max_points is below it
+		quiz.MinPoints = models.DefaultMinPoints
+		if quiz.MinPoints > quiz.MaxPoints {
+			quiz.MinPoints = quiz.MaxPoints * models.DefaultMinPoints / models.DefaultMaxPoints
+		}
+	}
+	if quiz.MinPoints > quiz.MaxPoints {
+		return fmt.Errorf("min_points %d is more than max_points %d", quiz.MinPoints, quiz.MaxPoints)
+	}
+	if quiz.SpeedDecay == models.DecayExponential && quiz.MinPoints == 0 {
+		return fmt.Errorf("exponential speed_decay needs min_points above 0")
+	}
+	return nil
+}
+
+// validateSampling checks that pool and quiz sample counts can be satisfied
+func validateSampling(quiz *models.Quiz) error {
+	available := 0
+	for _, q := range quiz.Questions {
+		if q.Pool == "" {
+			available++
+		}
+	}
+
+	for _, pool := range quiz.Pools {
+		size := 0
+		for _, q := range quiz.Questions {
+			if q.Pool == pool.Name {
+				size++
+			}
+		}
+		if size == 0 {
+			return fmt.Errorf("pool '%s' has no questions", pool.Name)
+		}
+		if pool.Sample > size {
+			return fmt.Errorf("pool '%s' samples %d questions but only has %d", pool.Name, pool.Sample, size)
+		}
+		if pool.Sample > 0 {
+			available += pool.Sample
+		} else {
+			available += size
+		}
+	}
+
+	if quiz.Sample > available {
+		return fmt.Errorf("quiz samples %d questions but only %d are available", quiz.Sample, available)
+	}
+
+	return nil
+}
+
+// validateStreakTiers checks that streak tiers are given from the shortest
+// streak to the longest, each streak once
+func validateStreakTiers(tiers []models.StreakTier) error {
+	for i := 1; i < len(tiers); i++ {
+		if tiers[i].Streak <= tiers[i-1].Streak {
+			return fmt.Errorf("streak_tiers must go from the shortest streak to the longest, %d comes after %d",
+				tiers[i].Streak, tiers[i-1].Streak)
+		}
+	}
+	return nil
+}
+
+// applySetting applies a single settings key to the quiz. Unparseable values
+// of the original settings are ignored so older quizzes keep working; newer
+// settings are validated. Unknown keys are rejected so a misspelled setting
+// does not silently do nothing.
+func applySetting(quiz *models.Quiz, key, value string) error {
+	switch key {
+	case "time_per_question":
+		// Parse duration (e.g., "10 seconds", "1 minute")
+		if timeVal, err := parseDuration(value); err == nil {
+			quiz.TimePerQuestion = timeVal
+		}
+	case "time_between_questions":
+		// Parse duration (e.g., "10 seconds", "1 minute")
+		if timeVal, err := parseDuration(value); err == nil {
+			quiz.TimeBetweenQuestions = timeVal
+		}
+	case "streak_bonus":
+		// Parse boolean (e.g., "true", "false", "yes", "no")
+		quiz.StreakBonus = parseBool(value)
+	case "quickest_answer_bonus":
+		// Parse boolean (e.g., "true", "false", "yes", "no")
+		quiz.QuickestAnswerBonus = parseBool(value)
+	case "time_between_rounds":
+		if timeVal, err := parseDuration(value); err == nil {
+			quiz.TimeBetweenRounds = timeVal
+		}
+	case "round_intro_time":
+		if timeVal, err := parseDuration(value); err == nil {
+			quiz.RoundIntroTime = timeVal
+		}
+	case "shuffle_options":
+		quiz.ShuffleOptions = parseBool(value)
+	case "shuffle_questions":
+		quiz.ShuffleQuestions = parseBool(value)
+	case "sample":
+		sample, err := parsePositiveInt(value)
+		if err != nil {
+			return fmt.Errorf("invalid sample: %w", err)
+		}
+		quiz.Sample = sample
+	case "seed":
+		seed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || seed == 0 {
+			return fmt.Errorf("invalid seed '%s': must be a non-zero integer", value)
+		}
+		quiz.Seed = seed
+	case "scoring":
+		scoring := strings.ToLower(value)
+		switch scoring {
+		case models.ScoringClassic, models.ScoringSpeed, models.ScoringNegative, models.ScoringConfidence:
+			quiz.Scoring = scoring
+		default:
+			return fmt.Errorf("invalid scoring '%s': must be classic, speed, negative or confidence", value)
+		}
+	case "speed_decay":
+		decay := strings.ToLower(value)
+		if decay != models.DecayLinear && decay != models.DecayExponential {
+			return fmt.Errorf("invalid speed_decay '%s': must be linear or exponential", value)
+		}
+		quiz.SpeedDecay = decay
+	case "max_points":
+		points, err := parsePositiveInt(value)
+		if err != nil {
+			return fmt.Errorf("invalid max_points: %w", err)
+		}
+		quiz.MaxPoints = points
+	case "min_points":
+		points, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || points < 0 {
+			return fmt.Errorf("invalid min_points '%s': must be a whole number", value)
+		}
+		quiz.MinPoints = points
+	case "streak_tiers":
+		tiers, err := parseStreakTiers(strings.Split(value, ","))
+		if err != nil {
+			return err
+		}
+		quiz.StreakTiers = tiers
+		quiz.StreakBonus = true
+	case "streak_break":
+		streakBreak := strings.ToLower(value)
+		if streakBreak != models.StreakBreakMissed && streakBreak != models.StreakBreakWrong {
+			return fmt.Errorf("invalid streak_break '%s': must be missed or wrong", value)
+		}
+		quiz.StreakBreak = streakBreak
+	case "tie_breakers":
+		tieBreakers, err := parseTieBreakers(strings.Split(value, ","))
+		if err != nil {
+			return err
+		}
+		quiz.TieBreakers = tieBreakers
+	case "wagers":
+		quiz.Wagers = parseBool(value)
+	case "wager_time":
+		timeVal, err := parseDuration(value)
+		if err != nil || timeVal <= 0 {
+			return fmt.Errorf("invalid wager_time '%s': must be a positive duration", value)
+		}
+		quiz.WagerTime = timeVal
+	case "penalty":
+		penalty, err := parsePositiveInt(value)
+		if err != nil {
+			return fmt.Errorf("invalid penalty: %w", err)
+		}
+		quiz.Penalty = penalty
+	default:
+		return fmt.Errorf("unknown setting '%s'", key)
+	}
+	return nil
+}
+
+// applyRoundSetting applies a settings key given under a round heading
+func applyRoundSetting(round *models.Round, key, value string) error {
+	switch key {
+	case "description":
+		round.Description = value
+	case "time_per_question":
+		timeVal, err := parseDuration(value)
+		if err != nil {
+			return err
+		}
+		round.TimePerQuestion = timeVal
+	case "time_between_questions":
+		timeVal, err := parseDuration(value)
+		if err != nil {
+			return err
+		}
+		round.TimeBetweenQuestions = timeVal
+	default:
+		return fmt.Errorf("unknown round setting '%s'", key)
+	}
+	return nil
+}
+
+// settingKey normalizes a settings key, which is matched case-insensitively
+func settingKey(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+// parseStreakTiers parses streak tiers like "3=+1" for one bonus point from a
+// streak of three, or "5=x2" for double points from a streak of five
+func parseStreakTiers(items []string) ([]models.StreakTier, error) {
+	var tiers []models.StreakTier
+	for _, item := range items {
+		streak, reward, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid streak tier '%s': expected streak=+bonus or streak=xmultiplier", strings.TrimSpace(item))
+		}
+
+		var tier models.StreakTier
+		var err error
+		if tier.Streak, err = parsePositiveInt(streak); err != nil {
+			return nil, fmt.Errorf("invalid streak tier '%s': streak %w", strings.TrimSpace(item), err)
+		}
+
+		reward = strings.TrimSpace(reward)
+		if multiplier, ok := cutPrefixFold(reward, "x"); ok {
+			tier.Multiplier, err = strconv.ParseFloat(multiplier, 64)
+			if err != nil || tier.Multiplier <= 1 {
+				return nil, fmt.Errorf("invalid streak tier '%s': multiplier must be a number above 1", strings.TrimSpace(item))
+			}
+		} else if tier.Bonus, err = parsePositiveInt(strings.TrimPrefix(reward, "+")); err != nil {
+			return nil, fmt.Errorf("invalid streak tier '%s': bonus %w", strings.TrimSpace(item), err)
+		}
+
+		tiers = append(tiers, tier)
+	}
+	return tiers, nil
+}
+
+// parseTieBreakers parses a list of tie-breakers, applied in the given order
+func parseTieBreakers(items []string) ([]string, error) {
+	var tieBreakers []string
+	for _, item := range items {
+		tieBreaker := strings.ToLower(strings.TrimSpace(item))
+		switch tieBreaker {
+		case models.TieBreakCorrect, models.TieBreakTime, models.TieBreakLastCorrect:
+		default:
+			return nil, fmt.Errorf("invalid tie-breaker '%s': must be correct, time or last_correct", strings.TrimSpace(item))
+		}
+		if slices.Contains(tieBreakers, tieBreaker) {
+			return nil, fmt.Errorf("duplicate tie-breaker '%s'", tieBreaker)
+		}
+		tieBreakers = append(tieBreakers, tieBreaker)
+	}
+	return tieBreakers, nil
+}
+
 // parseDuration parses time strings like "10 seconds", "1 minute", "30s", etc.
 func parseDuration(s string) (int, error) {
 	s = strings.ToLower(strings.TrimSpace(s))
@@ -188,3 +613,20 @@ func parseBool(s string) bool {
 	s = strings.ToLower(strings.TrimSpace(s))
 	return s == "true" || s == "yes" || s == "1" || s == "on"
 }
+
+// parsePositiveInt parses a strictly positive integer
+func parsePositiveInt(s string) (int, error) {
+	value, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("'%s' is not a positive integer", s)
+	}
+	return value, nil
+}
+
+// cutPrefixFold is like strings.CutPrefix but case-insensitive, and trims the remainder
+func cutPrefixFold(s, prefix string) (string, bool) {
+	if len(s) < len(prefix) || !strings.EqualFold(s[:len(prefix)], prefix) {
+		return s, false
+	}
+	return strings.TrimSpace(s[len(prefix):]), true
+}
@@ -1,7 +1,11 @@
 package parser
 
 import (
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/rkrmr33/quickwiz/internal/models"
 )
 
 func TestParseQuizMarkdown(t *testing.T) {
@@ -149,3 +153,636 @@ func TestParseDuration(t *testing.T) {
 		}
 	}
 }
+
+func TestParseQuizMarkdown_FrontMatter(t *testing.T) {
+	markdown := `---
+title: Front Matter Quiz
+author: "Jane Doe"
+description: A quiz with metadata # trailing comment
+category: Geography
+tags: [Geography, capitals]
+language: en
+difficulty: Hard
+time_per_question: 20 seconds
+streak_bonus: yes
+shuffle_options: true
+---
+
+### What is the capital of France?
+- Berlin
+- Paris
+* Answer: Paris`
+
+	quiz, err := ParseQuizMarkdown(markdown)
+	if err != nil {
+		t.Fatalf("Failed to parse quiz: %v", err)
+	}
+
+	if quiz.Title != "Front Matter Quiz" {
+		t.Errorf("Expected title 'Front Matter Quiz', got '%s'", quiz.Title)
+	}
+	if quiz.Author != "Jane Doe" {
+		t.Errorf("Expected author 'Jane Doe', got '%s'", quiz.Author)
+	}
+	if quiz.Description != "A quiz with metadata" {
+		t.Errorf("Expected description 'A quiz with metadata', got '%s'", quiz.Description)
+	}
+	if quiz.Category != "geography" {
+		t.Errorf("Expected category 'geography', got '%s'", quiz.Category)
+	}
+	if len(quiz.Tags) != 2 || quiz.Tags[0] != "geography" || quiz.Tags[1] != "capitals" {
+		t.Errorf("Expected tags [geography capitals], got %v", quiz.Tags)
+	}
+	if quiz.Language != "en" {
+		t.Errorf("Expected language 'en', got '%s'", quiz.Language)
+	}
+	if quiz.Difficulty != "hard" {
+		t.Errorf("Expected difficulty 'hard', got '%s'", quiz.Difficulty)
+	}
+	if quiz.TimePerQuestion != 20 {
+		t.Errorf("Expected time_per_question 20, got %d", quiz.TimePerQuestion)
+	}
+	if !quiz.StreakBonus {
+		t.Error("Expected streak_bonus to be true")
+	}
+	if !quiz.ShuffleOptions {
+		t.Error("Expected shuffle_options to be true")
+	}
+	if len(quiz.Questions) != 1 {
+		t.Errorf("Expected 1 question, got %d", len(quiz.Questions))
+	}
+}
+
+func TestParseQuizMarkdown_FrontMatterWithHeadingTitle(t *testing.T) {
+	markdown := `---
+author: Jane
+tags:
+  - science
+  - "space"
+---
+# Heading Title
+
+# Settings
+time_between_questions: 8 seconds
+
+### Which planet is known as the Red Planet?
+- Venus
+- Mars
+* Answer: Mars`
+
+	quiz, err := ParseQuizMarkdown(markdown)
+	if err != nil {
+		t.Fatalf("Failed to parse quiz: %v", err)
+	}
+
+	if quiz.Title != "Heading Title" {
+		t.Errorf("Expected title 'Heading Title', got '%s'", quiz.Title)
+	}
+	if len(quiz.Tags) != 2 || quiz.Tags[0] != "science" || quiz.Tags[1] != "space" {
+		t.Errorf("Expected tags [science space], got %v", quiz.Tags)
+	}
+	if quiz.TimeBetweenQuestions != 8 {
+		t.Errorf("Expected time_between_questions 8, got %d", quiz.TimeBetweenQuestions)
+	}
+}
+
+func TestParseQuizMarkdown_UnclosedFrontMatter(t *testing.T) {
+	markdown := `---
+title: Broken
+
+### Question 1?
+- Option A
+* Answer: Option A`
+
+	_, err := ParseQuizMarkdown(markdown)
+	if err == nil {
+		t.Error("Expected error for unclosed front matter, got nil")
+	}
+}
+
+func TestParseQuizMarkdown_SettingKeys(t *testing.T) {
+	// Keys are matched case-insensitively in both the settings section and
+	// front matter
+	settings := "# Quiz\n\n# Settings\nTime_Per_Question: 12 seconds\n Shuffle_Options : true\n\n### Question 1?\n- A\n* Answer: A"
+	frontMatter := "---\ntitle: Quiz\nTime_Per_Question: 12 seconds\nShuffle_Options: true\n---\n\n### Question 1?\n- A\n* Answer: A"
+	for name, markdown := range map[string]string{"settings": settings, "front matter": frontMatter} {
+		quiz, err := ParseQuizMarkdown(markdown)
+		if err != nil {
+			t.Fatalf("%s: failed to parse quiz: %v", name, err)
+		}
+		if quiz.TimePerQuestion != 12 || !quiz.ShuffleOptions {
+			t.Errorf("%s: expected mixed-case keys to apply, got %ds and shuffle %v", name, quiz.TimePerQuestion, quiz.ShuffleOptions)
+		}
+	}
+
+	// A misspelled key is reported instead of doing nothing
+	tests := map[string]string{
+		"settings":     "# Quiz\n\n# Settings\ntime_per_qestion: 12 seconds\n\n### Question 1?\n- A\n* Answer: A",
+		"front matter": "---\ntitle: Quiz\ntime_per_qestion: 12 seconds\n---\n\n### Question 1?\n- A\n* Answer: A",
+	}
+	for name, markdown := range tests {
+		_, err := ParseQuizMarkdown(markdown)
+		if err == nil || !strings.Contains(err.Error(), "time_per_qestion") {
+			t.Errorf("%s: expected an unknown setting error naming the key, got %v", name, err)
+		}
+	}
+}
+
+func TestParseQuizMarkdown_Pools(t *testing.T) {
+	markdown := `# Pool Quiz
+
+# Settings
+sample: 3
+seed: 42
+
+### Always asked?
+- Yes
+- No
+* Answer: Yes
+
+## Pool: Geography
+sample: 1
+
+### Capital of France?
+- Paris
+- Rome
+* Answer: Paris
+
+### Capital of Italy?
+- Paris
+- Rome
+* Answer: Rome
+
+## Pool: Science
+
+### What is H2O?
+- Water
+- Salt
+* Answer: Water`
+
+	quiz, err := ParseQuizMarkdown(markdown)
+	if err != nil {
+		t.Fatalf("Failed to parse quiz: %v", err)
+	}
+
+	if quiz.Sample != 3 {
+		t.Errorf("Expected sample 3, got %d", quiz.Sample)
+	}
+	if quiz.Seed != 42 {
+		t.Errorf("Expected seed 42, got %d", quiz.Seed)
+	}
+	if len(quiz.Pools) != 2 {
+		t.Fatalf("Expected 2 pools, got %d", len(quiz.Pools))
+	}
+	if quiz.Pools[0].Name != "Geography" || quiz.Pools[0].Sample != 1 {
+		t.Errorf("Expected pool Geography with sample 1, got %+v", quiz.Pools[0])
+	}
+	if quiz.Pools[1].Name != "Science" || quiz.Pools[1].Sample != 0 {
+		t.Errorf("Expected pool Science with no sample, got %+v", quiz.Pools[1])
+	}
+	if len(quiz.Questions) != 4 {
+		t.Fatalf("Expected 4 questions, got %d", len(quiz.Questions))
+	}
+	if quiz.Questions[0].Pool != "" || quiz.Questions[1].Pool != "Geography" || quiz.Questions[3].Pool != "Science" {
+		t.Errorf("Unexpected question pools: %q, %q, %q",
+			quiz.Questions[0].Pool, quiz.Questions[1].Pool, quiz.Questions[3].Pool)
+	}
+}
+
+func TestParseQuizMarkdown_InvalidSampling(t *testing.T) {
+	tests := map[string]string{
+		"sample too large": `# Quiz
+
+# Settings
+sample: 5
+
+### Question 1?
+- A
+* Answer: A`,
+		"pool sample too large": `# Quiz
+
+## Pool: Small
+sample: 2
+
+### Question 1?
+- A
+* Answer: A`,
+		"invalid sample": `# Quiz
+
+# Settings
+sample: lots
+
+### Question 1?
+- A
+* Answer: A`,
+		"empty pool": `# Quiz
+
+### Question 1?
+- A
+* Answer: A
+
+## Pool: Empty
+sample: 1`,
+	}
+
+	for name, markdown := range tests {
+		if _, err := ParseQuizMarkdown(markdown); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
+
+func TestParseQuizMarkdown_Rounds(t *testing.T) {
+	markdown := `# Round Quiz
+
+# Settings
+time_per_question: 20 seconds
+time_between_rounds: 15 seconds
+
+## Round: Warm Up
+description: Easy ones first
+time_per_question: 10 seconds
+
+### What is 1 + 1?
+- 1
+- 2
+* Answer: 2
+
+## Geography
+
+### Capital of France?
+- Paris
+- Rome
+* Answer: Paris
+
+## Pool: Capitals
+sample: 1
+
+### Capital of Italy?
+- Paris
+- Rome
+* Answer: Rome
+
+### Capital of Spain?
+- Madrid
+- Rome
+* Answer: Madrid`
+
+	quiz, err := ParseQuizMarkdown(markdown)
+	if err != nil {
+		t.Fatalf("Failed to parse quiz: %v", err)
+	}
+
+	if len(quiz.Rounds) != 2 {
+		t.Fatalf("Expected 2 rounds, got %d", len(quiz.Rounds))
+	}
+	if quiz.Rounds[0].Title != "Warm Up" || quiz.Rounds[0].Description != "Easy ones first" || quiz.Rounds[0].TimePerQuestion != 10 {
+		t.Errorf("Unexpected first round: %+v", quiz.Rounds[0])
+	}
+	if quiz.Rounds[1].Title != "Geography" || quiz.Rounds[1].TimePerQuestion != 0 {
+		t.Errorf("Unexpected second round: %+v", quiz.Rounds[1])
+	}
+	if quiz.TimeBetweenRounds != 15 {
+		t.Errorf("Expected time_between_rounds 15, got %d", quiz.TimeBetweenRounds)
+	}
+
+	expectedRounds := []int{0, 1, 1, 1}
+	for i, q := range quiz.Questions {
+		if q.Round != expectedRounds[i] {
+			t.Errorf("Question %d: expected round %d, got %d", i+1, expectedRounds[i], q.Round)
+		}
+	}
+	if quiz.Questions[2].Pool != "Capitals" {
+		t.Errorf("Expected pool inside round to be kept, got '%s'", quiz.Questions[2].Pool)
+	}
+}
+
+func TestParseQuizMarkdown_QuestionBeforeFirstRound(t *testing.T) {
+	markdown := `# Quiz
+
+### Orphan question?
+- A
+* Answer: A
+
+## Round One
+
+### Question 1?
+- A
+* Answer: A`
+
+	_, err := ParseQuizMarkdown(markdown)
+	if err == nil {
+		t.Error("Expected error for question before the first round, got nil")
+	}
+}
+
+func TestParseQuizMarkdownWithDefaults(t *testing.T) {
+	markdown := `# Defaults Quiz
+
+# Settings
+time_between_questions: 8 seconds
+
+### What is 2 + 2?
+- 3
+- 4
+* Answer: 4`
+
+	defaults := Timings{TimePerQuestion: 45, TimeBetweenQuestions: 3, TimeBetweenRounds: 20, RoundIntroTime: 7}
+	quiz, err := ParseQuizMarkdownWithDefaults(markdown, defaults)
+	if err != nil {
+		t.Fatalf("Failed to parse quiz: %v", err)
+	}
+
+	if quiz.TimePerQuestion != 45 {
+		t.Errorf("Expected default time_per_question 45, got %d", quiz.TimePerQuestion)
+	}
+	if quiz.TimeBetweenQuestions != 8 {
+		t.Errorf("Expected quiz time_between_questions 8 to override default, got %d", quiz.TimeBetweenQuestions)
+	}
+	if quiz.TimeBetweenRounds != 20 || quiz.RoundIntroTime != 7 {
+		t.Errorf("Expected round timings 20/7, got %d/%d", quiz.TimeBetweenRounds, quiz.RoundIntroTime)
+	}
+}
+
+func TestParseQuizMarkdown_Scoring(t *testing.T) {
+	markdown := `# Speed Quiz
+
+# Settings
+scoring: speed
+speed_decay: exponential
+max_points: 2000
+min_points: 100
+
+### Question 1?
+- A
+* Answer: A`
+
+	quiz, err := ParseQuizMarkdown(markdown)
+	if err != nil {
+		t.Fatalf("Failed to parse quiz: %v", err)
+	}
+	if quiz.Scoring != "speed" || quiz.SpeedDecay != "exponential" {
+		t.Errorf("Expected exponential speed scoring, got %q/%q", quiz.Scoring, quiz.SpeedDecay)
+	}
+	if quiz.MaxPoints != 2000 || quiz.MinPoints != 100 {
+		t.Errorf("Expected points 2000/100, got %d/%d", quiz.MaxPoints, quiz.MinPoints)
+	}
+
+	// Speed scoring defaults to 1000 down to 500; other quizzes carry no points
+	quiz, _ = ParseQuizMarkdown("# Quiz\n\n# Settings\nscoring: speed\n\n### Question 1?\n- A\n* Answer: A")
+	if quiz.MaxPoints != 1000 || quiz.MinPoints != 500 {
+		t.Errorf("Expected default points 1000/500, got %d/%d", quiz.MaxPoints, quiz.MinPoints)
+	}
+
+	// Without min_points, the default follows a max_points below it
+	quiz, err = ParseQuizMarkdown("# Quiz\n\n# Settings\nscoring: speed\nmax_points: 400\n\n### Question 1?\n- A\n* Answer: A")
+	if err != nil {
+		t.Fatalf("Failed to parse quiz with only max_points: %v", err)
+	}
+	if quiz.MaxPoints != 400 || quiz.MinPoints != 200 {
+		t.Errorf("Expected points 400/200, got %d/%d", quiz.MaxPoints, quiz.MinPoints)
+	}
+	quiz, _ = ParseQuizMarkdown("# Quiz\n\n# Settings\nscoring: speed\nmax_points: 800\n\n### Question 1?\n- A\n* Answer: A")
+	if quiz.MaxPoints != 800 || quiz.MinPoints != 500 {
+		t.Errorf("Expected points 800/500, got %d/%d", quiz.MaxPoints, quiz.MinPoints)
+	}
+	if _, err := ParseQuizMarkdown("# Quiz\n\n# Settings\nscoring: speed\nmax_points: 400\nmin_points: 600\n\n### Question 1?\n- A\n* Answer: A"); err == nil {
+		t.Error("Expected error for a min_points set above max_points")
+	}
+
+	quiz, _ = ParseQuizMarkdown("# Quiz\n\n### Question 1?\n- A\n* Answer: A")
+	if quiz.Scoring != "" || quiz.MaxPoints != 0 || quiz.MinPoints != 0 || quiz.Penalty != 0 {
+		t.Errorf("Expected classic scoring without points, got %q %d/%d/%d", quiz.Scoring, quiz.MaxPoints, quiz.MinPoints, quiz.Penalty)
+	}
+
+	// Negative marking takes one point off by default
+	quiz, _ = ParseQuizMarkdown("# Quiz\n\n# Settings\nscoring: negative\n\n### Question 1?\n- A\n* Answer: A")
+	if quiz.Scoring != "negative" || quiz.Penalty != 1 {
+		t.Errorf("Expected negative scoring with a penalty of 1, got %q %d", quiz.Scoring, quiz.Penalty)
+	}
+	quiz, _ = ParseQuizMarkdown("# Quiz\n\n# Settings\nscoring: negative\npenalty: 3\n\n### Question 1?\n- A\n* Answer: A")
+	if quiz.Penalty != 3 {
+		t.Errorf("Expected a penalty of 3, got %d", quiz.Penalty)
+	}
+}
+
+func TestParseQuizMarkdown_Wagers(t *testing.T) {
+	markdown := `# Exam
+
+# Settings
+scoring: negative
+wagers: true
+wager_time: 15 seconds
+
+### Question 1?
+- A
+* Answer: A`
+
+	quiz, err := ParseQuizMarkdown(markdown)
+	if err != nil {
+		t.Fatalf("Failed to parse quiz: %v", err)
+	}
+	if !quiz.Wagers || quiz.WagerTime != 15 || quiz.Scoring != "negative" {
+		t.Errorf("Expected negative scoring with 15s wagers, got %q %v %d", quiz.Scoring, quiz.Wagers, quiz.WagerTime)
+	}
+
+	if _, err := ParseQuizMarkdown("# Quiz\n\n# Settings\nwagers: true\nwager_time: soon\n\n### Question 1?\n- A\n* Answer: A"); err == nil {
+		t.Error("Expected error for an invalid wager_time")
+	}
+}
+
+func TestParseQuizMarkdown_InvalidScoring(t *testing.T) {
+	tests := map[string]string{
+		"unknown scoring":     "scoring: fastest",
+		"unknown decay":       "scoring: speed\nspeed_decay: cubic",
+		"zero max points":     "scoring: speed\nmax_points: 0",
+		"negative min points": "scoring: speed\nmin_points: -5",
+		"min above max":       "scoring: speed\nmax_points: 100\nmin_points: 200",
+		"exponential to zero": "scoring: speed\nspeed_decay: exponential\nmin_points: 0",
+		"zero penalty":        "scoring: negative\npenalty: 0",
+	}
+
+	for name, settings := range tests {
+		markdown := "# Quiz\n\n# Settings\n" + settings + "\n\n### Question 1?\n- A\n* Answer: A"
+		if _, err := ParseQuizMarkdown(markdown); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
+
+func TestParseQuizMarkdown_StreakTiers(t *testing.T) {
+	markdown := `# Streaks
+
+# Settings
+streak_tiers: 2=+1, 4=+3, 6=x1.5
+streak_break: wrong
+
+### Question 1?
+- A
+* Answer: A`
+
+	quiz, err := ParseQuizMarkdown(markdown)
+	if err != nil {
+		t.Fatalf("Failed to parse quiz: %v", err)
+	}
+	want := []models.StreakTier{{Streak: 2, Bonus: 1}, {Streak: 4, Bonus: 3}, {Streak: 6, Multiplier: 1.5}}
+	if !reflect.DeepEqual(quiz.StreakTiers, want) {
+		t.Errorf("Expected tiers %+v, got %+v", want, quiz.StreakTiers)
+	}
+	if !quiz.StreakBonus {
+		t.Error("Expected streak tiers to turn the streak bonus on")
+	}
+	if quiz.StreakBreak != "wrong" {
+		t.Errorf("Expected streak_break wrong, got %q", quiz.StreakBreak)
+	}
+
+	// Front matter takes the tiers as a list
+	frontMatter := `---
+title: Streaks
+streak_tiers:
+  - 3=x2
+  - 5=x3
+---
+
+### Question 1?
+- A
+* Answer: A`
+	quiz, err = ParseQuizMarkdown(frontMatter)
+	if err != nil {
+		t.Fatalf("Failed to parse front matter quiz: %v", err)
+	}
+	want = []models.StreakTier{{Streak: 3, Multiplier: 2}, {Streak: 5, Multiplier: 3}}
+	if !reflect.DeepEqual(quiz.StreakTiers, want) {
+		t.Errorf("Expected tiers %+v, got %+v", want, quiz.StreakTiers)
+	}
+}
+
+func TestParseQuizMarkdown_StreakTiersReplace(t *testing.T) {
+	want := []models.StreakTier{{Streak: 2, Bonus: 1}, {Streak: 4, Bonus: 3}}
+	question := "\n\n### Question 1?\n- A\n* Answer: A"
+
+	// Every form of the key sets the same tiers, and a later one replaces an
+	// earlier one instead of adding to it
+	tests := map[string]string{
+		"settings":                    "# Quiz\n\n# Settings\nstreak_tiers: 2=+1, 4=+3",
+		"front matter scalar":         "---\ntitle: Quiz\nstreak_tiers: 2=+1, 4=+3\n---",
+		"front matter inline":         "---\ntitle: Quiz\nstreak_tiers: [2=+1, 4=+3]\n---",
+		"front matter block":          "---\ntitle: Quiz\nstreak_tiers:\n  - 2=+1\n  - 4=+3\n---",
+		"front matter twice":          "---\ntitle: Quiz\nstreak_tiers:\n  - 3=x2\nstreak_tiers:\n  - 2=+1\n  - 4=+3\n---",
+		"settings after front matter": "---\ntitle: Quiz\nstreak_tiers:\n  - 3=x2\n  - 5=x3\n---\n\n# Settings\nstreak_tiers: 2=+1, 4=+3",
+	}
+
+	for name, markdown := range tests {
+		quiz, err := ParseQuizMarkdown(markdown + question)
+		if err != nil {
+			t.Errorf("%s: failed to parse quiz: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(quiz.StreakTiers, want) {
+			t.Errorf("%s: expected tiers %+v, got %+v", name, want, quiz.StreakTiers)
+		}
+	}
+}
+
+func TestParseQuizMarkdown_InvalidStreakTiers(t *testing.T) {
+	tests := map[string]string{
+		"missing reward":     "streak_tiers: 3",
+		"zero streak":        "streak_tiers: 0=+1",
+		"zero bonus":         "streak_tiers: 3=+0",
+		"multiplier of one":  "streak_tiers: 3=x1",
+		"bad multiplier":     "streak_tiers: 3=xdouble",
+		"out of order":       "streak_tiers: 5=+2, 3=+1",
+		"repeated streak":    "streak_tiers: 3=+1, 3=+2",
+		"unknown break rule": "streak_break: never",
+	}
+
+	for name, settings := range tests {
+		markdown := "# Quiz\n\n# Settings\n" + settings + "\n\n### Question 1?\n- A\n* Answer: A"
+		if _, err := ParseQuizMarkdown(markdown); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
+
+func TestParseQuizMarkdown_TieBreakers(t *testing.T) {
+	markdown := `# Ties
+
+# Settings
+tie_breakers: correct, time
+
+### Question 1?
+- A
+* Answer: A`
+
+	quiz, err := ParseQuizMarkdown(markdown)
+	if err != nil {
+		t.Fatalf("Failed to parse quiz: %v", err)
+	}
+	if want := []string{"correct", "time"}; !reflect.DeepEqual(quiz.TieBreakers, want) {
+		t.Errorf("Expected tie-breakers %v, got %v", want, quiz.TieBreakers)
+	}
+
+	// Front matter takes them as a list
+	frontMatter := `---
+title: Ties
+tie_breakers:
+  - last_correct
+  - correct
+---
+
+### Question 1?
+- A
+* Answer: A`
+	quiz, err = ParseQuizMarkdown(frontMatter)
+	if err != nil {
+		t.Fatalf("Failed to parse front matter quiz: %v", err)
+	}
+	if want := []string{"last_correct", "correct"}; !reflect.DeepEqual(quiz.TieBreakers, want) {
+		t.Errorf("Expected tie-breakers %v, got %v", want, quiz.TieBreakers)
+	}
+
+	for _, value := range []string{"correct, fastest", "time, time"} {
+		if _, err := ParseQuizMarkdown("# Quiz\n\n# Settings\ntie_breakers: " + value + "\n\n### Question 1?\n- A\n* Answer: A"); err == nil {
+			t.Errorf("Expected error for tie_breakers %q", value)
+		}
+	}
+}
+
+func TestParseQuizMarkdown_SuddenDeath(t *testing.T) {
+	markdown := `# Final
+
+## Round: Only Round
+
+### Question 1?
+- A
+- B
+* Answer: A
+
+## Sudden Death
+
+### Tiebreak 1?
+- A
+- B
+* Answer: B
+
+### Tiebreak 2?
+- A
+- B
+* Answer: A`
+
+	quiz, err := ParseQuizMarkdown(markdown)
+	if err != nil {
+		t.Fatalf("Failed to parse quiz: %v", err)
+	}
+	if len(quiz.Questions) != 1 || len(quiz.Rounds) != 1 {
+		t.Errorf("Expected 1 question in 1 round, got %d in %d", len(quiz.Questions), len(quiz.Rounds))
+	}
+	if len(quiz.SuddenDeath) != 2 || quiz.SuddenDeath[0].Text != "Tiebreak 1?" || quiz.SuddenDeath[1].Answer != "A" {
+		t.Errorf("Expected 2 sudden death questions, got %+v", quiz.SuddenDeath)
+	}
+
+	invalid := "# Quiz\n\n### Question 1?\n- A\n* Answer: A\n\n## Sudden Death\n\n### Tiebreak?\n- A\n* Answer: B"
+	if _, err := ParseQuizMarkdown(invalid); err == nil || !strings.Contains(err.Error(), "sudden death question 1") {
+		t.Errorf("Expected error for an invalid sudden death question, got %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+// Package protocol describes the real-time protocol spoken over /ws/{code}
+// and its Server-Sent Events fallback /sse/{code}:
+// its versions, how a version is negotiated and every message type with its
+// payload. The schema and document in docs/ are generated from it.
+package protocol
+
+//go:generate go run ../../cmd/protocoldoc -models ../models -schema schema.json -doc ../../docs/protocol.md
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rkrmr33/quickwiz/internal/models"
+)
+
+const (
+	// Version1 is the original protocol, spoken by clients that do not ask for a version
+	Version1 = 1
+	// Version2 adds a welcome message and a sequence number on every message
+	Version2 = 2
+
+	// Current is the newest version the server speaks
+	Current = Version2
+	// Min is the oldest version the server still speaks
+	Min = Version1
+
+	subprotocolPrefix = "quickwiz.v"
+)
+
+// Subprotocol returns the WebSocket subprotocol name of a version
+func Subprotocol(version int) string {
+	return subprotocolPrefix + strconv.Itoa(version)
+}
+
+// Subprotocols returns the WebSocket subprotocols the server accepts, newest
+// first so the newest version both sides speak is chosen
+func Subprotocols() []string {
+	names := make([]string, 0, Current-Min+1)
+	for v := Current; v >= Min; v-- {
+		names = append(names, Subprotocol(v))
+	}
+	return names
+}
+
+// Negotiate picks the version of a connection from the subprotocol agreed in
+// the WebSocket handshake or, for clients that cannot set one, the version
+// requested in the query string. Requests for a newer version than the server
+// speaks get the current version; clients asking for nothing get Version1.
+func Negotiate(subprotocol, requested string) (int, error) {
+	if subprotocol != "" {
+		name, ok := strings.CutPrefix(subprotocol, subprotocolPrefix)
+		v, err := strconv.Atoi(name)
+		if !ok || err != nil {
+			return 0, fmt.Errorf("unknown subprotocol %q", subprotocol)
+		}
+		return min(v, Current), nil
+	}
+
+	if requested == "" {
+		return Version1, nil
+	}
+	v, err := strconv.Atoi(requested)
+	if err != nil {
+		return 0, fmt.Errorf("invalid protocol version %q", requested)
+	}
+	if v < Min {
+		return 0, fmt.Errorf("protocol version %d is no longer supported, the oldest is %d", v, Min)
+	}
+	return min(v, Current), nil
+}
+
+// Sequenced reports whether messages carry sequence numbers on a version
+func Sequenced(version int) bool {
+	return version >= Version2
+}
+
+// Direction is who sends a message
+type Direction string
+
+const (
+	ServerToClient Direction = "server"
+	ClientToServer Direction = "client"
+)
+
+// Message describes one message type
+type Message struct {
+	Type        string
+	Direction   Direction
+	Since       int         // First protocol version with the message
+	Description string      // One or two sentences for the protocol document
+	Payload     interface{} // Zero value of the payload, nil when there is none
+}
+
+// Messages lists every message of the protocol
+var Messages = []Message{
+	// Sent by the server
+	{Type: "welcome", Direction: ServerToClient, Since: Version2, Payload: models.Welcome{},
+		Description: "First message on every connection. Confirms the negotiated protocol version."},
+	{Type: "participant_joined", Direction: ServerToClient, Since: Version1, Payload: models.ParticipantJoined{},
+		Description: "A new participant joined the quiz."},
+	{Type: "presence_changed", Direction: ServerToClient, Since: Version1, Payload: models.PresenceChanged{},
+		Description: "A participant came online or lost their last connection. Presence is tracked by each server instance for its own connections."},
+	{Type: "countdown", Direction: ServerToClient, Since: Version1, Payload: models.Countdown{},
+		Description: "Sent every second after the host starts the quiz, before the first question."},
+	{Type: "round_intro", Direction: ServerToClient, Since: Version1, Payload: models.RoundIntro{},
+		Description: "Introduces the next round before its first question."},
+	{Type: "wager_phase", Direction: ServerToClient, Since: Version2, Payload: models.WagerPhase{},
+		Description: "A question in a quiz with wagers started. Carries its text but not its options, which follow in a question message once wagers close."},
+	{Type: "question", Direction: ServerToClient, Since: Version1, Payload: models.QuestionUpdate{},
+		Description: "A question started. Options are in the order shown to this participant; answer with their index."},
+	{Type: "time_update", Direction: ServerToClient, Since: Version1, Payload: models.TimeUpdate{},
+		Description: "Time left in the current question, answer reveal or round screen. Not sent while paused."},
+	{Type: "answer_count_update", Direction: ServerToClient, Since: Version1, Payload: models.AnswerCountUpdate{},
+		Description: "A participant answered the current question."},
+	{Type: "answer_reveal", Direction: ServerToClient, Since: Version1, Payload: models.AnswerReveal{},
+		Description: "The question ended. Carries the correct answer and everyone's scores."},
+	{Type: "leaderboard", Direction: ServerToClient, Since: Version2, Payload: models.Leaderboard{},
+		Description: "Follows every answer reveal with the leading players, their rank changes and where this participant stands."},
+	{Type: "round_summary", Direction: ServerToClient, Since: Version1, Payload: models.RoundSummary{},
+		Description: "A round ended. Carries the standings of the round."},
+	{Type: "quiz_finished", Direction: ServerToClient, Since: Version1, Payload: models.QuizFinished{},
+		Description: "The quiz ended. Carries the final leaderboard."},
+	{Type: "pause_changed", Direction: ServerToClient, Since: Version1, Payload: models.PauseChanged{},
+		Description: "The host paused or resumed the quiz. Also sent on connect while paused."},
+	{Type: "reaction", Direction: ServerToClient, Since: Version1, Payload: models.Reaction{},
+		Description: "A participant reacted with an emoji."},
+	{Type: "server_shutdown", Direction: ServerToClient, Since: Version1, Payload: models.ServerShutdown{},
+		Description: "The server is shutting down. The connection closes once running games have finished or the shutdown timeout expires; games still running then are handed to another server instance to resume, or end if there is none."},
+	{Type: "ack", Direction: ServerToClient, Since: Version1, Payload: models.CommandAck{},
+		Description: "A command with an ID succeeded."},
+	{Type: "error", Direction: ServerToClient, Since: Version1, Payload: models.CommandError{},
+		Description: "A command failed. The ID is empty when the message could not be decoded."},
+	{Type: "pong", Direction: ServerToClient, Since: Version1, Payload: models.Pong{},
+		Description: "Reply to a ping."},
+
+	// Sent by clients
+	{Type: "answer", Direction: ClientToServer, Since: Version1, Payload: models.AnswerCommand{},
+		Description: "Answer the current question, preferably by option index. Rejected while paused."},
+	{Type: "wager", Direction: ClientToServer, Since: Version2, Payload: models.WagerCommand{},
+		Description: "Stake points on the current question during its wager phase. Can be changed until the options are shown. Rejected while paused."},
+	{Type: "start", Direction: ClientToServer, Since: Version1,
+		Description: "Start the quiz. Only the host may start it."},
+	{Type: "pause", Direction: ClientToServer, Since: Version1, Payload: models.PauseCommand{},
+		Description: "Pause or resume the game clock. Only the host may pause. Without a payload the quiz is paused."},
+	{Type: "react", Direction: ClientToServer, Since: Version1, Payload: models.ReactCommand{},
+		Description: "Send an emoji reaction to everyone: 👍 👏 😂 😮 🔥 ❤️ 🎉 🤔. At most two per second."},
+	{Type: "ping", Direction: ClientToServer, Since: Version1,
+		Description: "Check the connection; the server replies with pong."},
+}
+
+// Supports reports whether clients speaking a version understand a message
+// the server sends. Types missing from Messages are always supported.
+func Supports(version int, msgType string) bool {
+	for _, m := range Messages {
+		if m.Direction == ServerToClient && m.Type == msgType {
+			return version >= m.Since
+		}
+	}
+	return true
+}
@@ -0,0 +1,118 @@
+package protocol
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/rkrmr33/quickwiz/internal/models"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		subprotocol, requested string
+		want                   int
+		wantErr                bool
+	}{
+		{"", "", Version1, false},
+		{"quickwiz.v2", "", Version2, false},
+		{"quickwiz.v1", "2", Version1, false}, // The handshake wins over the query string
+		{"", "2", Version2, false},
+		{"", "99", Current, false},
+		{"", "0", 0, true},
+		{"", "two", 0, true},
+		{"chat", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := Negotiate(tt.subprotocol, tt.requested)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Negotiate(%q, %q) error = %v, want error %v", tt.subprotocol, tt.requested, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Negotiate(%q, %q) = %d, want %d", tt.subprotocol, tt.requested, got, tt.want)
+		}
+	}
+}
+
+func TestSubprotocols(t *testing.T) {
+	names := Subprotocols()
+	if len(names) != Current-Min+1 || names[0] != Subprotocol(Current) {
+		t.Errorf("Expected subprotocols newest first, got %v", names)
+	}
+}
+
+func TestMessages(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, m := range Messages {
+		key := string(m.Direction) + ":" + m.Type
+		if seen[key] {
+			t.Errorf("Message %s listed twice", key)
+		}
+		seen[key] = true
+
+		if m.Description == "" {
+			t.Errorf("Message %s has no description", m.Type)
+		}
+		if m.Since < Min || m.Since > Current {
+			t.Errorf("Message %s has unknown version %d", m.Type, m.Since)
+		}
+		if m.Direction != ServerToClient {
+			continue
+		}
+		payload, ok := m.Payload.(models.Payload)
+		if !ok {
+			t.Errorf("Server message %s has no payload", m.Type)
+			continue
+		}
+		if payload.MessageType() != m.Type {
+			t.Errorf("Message %s has a payload of type %s", m.Type, payload.MessageType())
+		}
+	}
+}
+
+func TestSupports(t *testing.T) {
+	tests := []struct {
+		version int
+		msgType string
+		want    bool
+	}{
+		{Version1, "question", true},
+		{Version1, "leaderboard", false},
+		{Version2, "leaderboard", true},
+		{Version1, "wager", true}, // Only server messages are checked
+		{Version1, "unknown", true},
+	}
+
+	for _, tt := range tests {
+		if got := Supports(tt.version, tt.msgType); got != tt.want {
+			t.Errorf("Supports(%d, %q) = %v, want %v", tt.version, tt.msgType, got, tt.want)
+		}
+	}
+}
+
+// TestGeneratedFiles fails when the schema or document are stale; run
+// go generate ./internal/protocol to update them
+func TestGeneratedFiles(t *testing.T) {
+	comments, err := LoadComments("../models")
+	if err != nil {
+		t.Fatalf("Failed to load comments: %v", err)
+	}
+
+	schema, err := Schema(comments)
+	if err != nil {
+		t.Fatalf("Failed to build schema: %v", err)
+	}
+	if !bytes.Equal(schema, schemaJSON) {
+		t.Error("schema.json is out of date, run go generate ./internal/protocol")
+	}
+
+	doc, err := os.ReadFile("../../docs/protocol.md")
+	if err != nil {
+		t.Fatalf("Failed to read document: %v", err)
+	}
+	if !bytes.Equal(Document(comments), doc) {
+		t.Error("docs/protocol.md is out of date, run go generate ./internal/protocol")
+	}
+}
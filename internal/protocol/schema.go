@@ -0,0 +1,446 @@
+package protocol
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// schemaJSON is the generated JSON Schema, see go generate
+//
+//go:embed schema.json
+var schemaJSON []byte
+
+// SchemaHandler serves the JSON Schema of the protocol
+func SchemaHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/schema+json")
+		w.Write(schemaJSON)
+	})
+}
+
+// Comments holds the doc comments of the payload types, keyed by type name
+// and by "Type.Field"
+type Comments map[string]string
+
+// LoadComments reads the doc comments of the types declared in a package directory
+func LoadComments(dir string) (Comments, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	comments := make(Comments)
+	fset := token.NewFileSet()
+	for _, path := range files {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		file, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				doc := ts.Doc
+				if doc == nil && len(gen.Specs) == 1 {
+					doc = gen.Doc
+				}
+				comments[ts.Name.Name] = commentText(doc)
+
+				st, ok := ts.Type.(*ast.StructType)
+				if !ok {
+					continue
+				}
+				for _, field := range st.Fields.List {
+					text := commentText(field.Doc)
+					if text == "" {
+						text = commentText(field.Comment)
+					}
+					for _, name := range field.Names {
+						comments[ts.Name.Name+"."+name.Name] = text
+					}
+				}
+			}
+		}
+	}
+	return comments, nil
+}
+
+func commentText(group *ast.CommentGroup) string {
+	if group == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(group.Text()), " ")
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// field is a JSON property of a payload struct
+type field struct {
+	name     string
+	goName   string
+	typ      reflect.Type
+	optional bool // Omitted when empty
+}
+
+// fields lists the JSON properties of a struct in declaration order
+func fields(t reflect.Type) []field {
+	var out []field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		out = append(out, field{
+			name:     name,
+			goName:   f.Name,
+			typ:      f.Type,
+			optional: strings.Contains(opts, "omitempty") || f.Type.Kind() == reflect.Pointer,
+		})
+	}
+	return out
+}
+
+// schemaBuilder turns payload types into JSON Schema definitions
+type schemaBuilder struct {
+	comments Comments
+	defs     map[string]interface{}
+	server   bool // Building a server message, whose non-optional fields are always present
+}
+
+func (b *schemaBuilder) schema(t reflect.Type) map[string]interface{} {
+	switch t {
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case rawMessageType:
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return b.schema(t.Elem())
+	case reflect.Struct:
+		if _, exists := b.defs[t.Name()]; !exists {
+			b.defs[t.Name()] = nil // Reserve the name while building recursive types
+			b.defs[t.Name()] = b.object(t)
+		}
+		return map[string]interface{}{"$ref": "#/$defs/" + t.Name()}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	}
+	return map[string]interface{}{}
+}
+
+func (b *schemaBuilder) object(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := []string{}
+	for _, f := range fields(t) {
+		prop := b.schema(f.typ)
+		if doc := b.comments[t.Name()+"."+f.goName]; doc != "" {
+			if _, isRef := prop["$ref"]; isRef {
+				prop = map[string]interface{}{"allOf": []interface{}{prop}, "description": doc}
+			} else {
+				prop["description"] = doc
+			}
+		}
+		properties[f.name] = prop
+		if b.server && !f.optional {
+			required = append(required, f.name)
+		}
+	}
+
+	obj := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if doc := b.comments[t.Name()]; doc != "" {
+		obj["description"] = doc
+	}
+	if len(required) > 0 {
+		obj["required"] = required
+	}
+	return obj
+}
+
+// message builds the schema of a message envelope with its payload
+func (b *schemaBuilder) message(m Message) map[string]interface{} {
+	b.server = m.Direction == ServerToClient
+
+	properties := map[string]interface{}{
+		"type": map[string]interface{}{"const": m.Type},
+	}
+	required := []string{"type"}
+	if m.Payload != nil {
+		properties["payload"] = b.schema(reflect.TypeOf(m.Payload))
+		if b.server {
+			required = append(required, "payload")
+		}
+	}
+	if b.server {
+		properties["seq"] = map[string]interface{}{
+			"type":        "integer",
+			"minimum":     1,
+			"description": "Position of the message on its connection, counting from 1. Present from protocol version 2.",
+		}
+	} else {
+		properties["id"] = map[string]interface{}{
+			"type":        "string",
+			"description": "Echoed in the ack or error reply. Commands without an ID are only answered when they fail.",
+		}
+	}
+
+	return map[string]interface{}{
+		"title":       m.Type,
+		"description": m.Description,
+		"type":        "object",
+		"properties":  properties,
+		"required":    required,
+	}
+}
+
+// Schema builds the JSON Schema of every message. A message is either a
+// ServerMessage or a ClientMessage.
+func Schema(comments Comments) ([]byte, error) {
+	b := &schemaBuilder{comments: comments, defs: make(map[string]interface{})}
+
+	var server, client []interface{}
+	for _, m := range Messages {
+		b.defs[messageDef(m)] = b.message(m)
+		ref := map[string]interface{}{"$ref": "#/$defs/" + messageDef(m)}
+		if m.Direction == ServerToClient {
+			server = append(server, ref)
+		} else {
+			client = append(client, ref)
+		}
+	}
+	b.defs["ServerMessage"] = map[string]interface{}{"oneOf": server}
+	b.defs["ClientMessage"] = map[string]interface{}{"oneOf": client}
+
+	schema := map[string]interface{}{
+		"$schema":        "https://json-schema.org/draft/2020-12/schema",
+		"$id":            "/api/protocol",
+		"title":          "QuicKwiz real-time protocol",
+		"description":    fmt.Sprintf("Messages exchanged over /ws/{code}. Protocol versions %d to %d.", Min, Current),
+		"x-version":      Current,
+		"x-subprotocols": Subprotocols(),
+		"oneOf":          []interface{}{map[string]interface{}{"$ref": "#/$defs/ServerMessage"}, map[string]interface{}{"$ref": "#/$defs/ClientMessage"}},
+		"$defs":          b.defs,
+	}
+
+	out, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// messageDef names the schema definition of a message. Payload definitions
+// use Go type names, so snake case keeps the two apart.
+func messageDef(m Message) string {
+	if m.Direction == ServerToClient {
+		return m.Type + "_message"
+	}
+	return m.Type + "_command"
+}
+
+// Document renders the protocol as Markdown for client authors
+func Document(comments Comments) []byte {
+	var buf bytes.Buffer
+	w := &buf
+
+	fmt.Fprintf(w, "<!-- Code generated by go generate ./internal/protocol; DO NOT EDIT. -->\n\n")
+	fmt.Fprintf(w, "# QuicKwiz Real-time Protocol\n\n")
+	fmt.Fprintf(w, "Current version: **%d** (oldest supported: %d). A JSON Schema of every message is served at `GET /api/protocol`.\n\n", Current, Min)
+	io.WriteString(w, overview)
+
+	var nested []reflect.Type
+	seen := make(map[reflect.Type]bool)
+	for _, dir := range []Direction{ServerToClient, ClientToServer} {
+		if dir == ServerToClient {
+			fmt.Fprintf(w, "## Server messages\n\n")
+		} else {
+			fmt.Fprintf(w, "## Client commands\n\n")
+		}
+
+		fmt.Fprintf(w, "| Type | Since | Description |\n|------|-------|-------------|\n")
+		for _, m := range Messages {
+			if m.Direction == dir {
+				fmt.Fprintf(w, "| [`%s`](#%s) | v%d | %s |\n", m.Type, m.Type, m.Since, m.Description)
+			}
+		}
+		fmt.Fprintln(w)
+
+		for _, m := range Messages {
+			if m.Direction != dir {
+				continue
+			}
+			fmt.Fprintf(w, "### %s\n\n%s\n\n", m.Type, m.Description)
+			if m.Payload == nil {
+				fmt.Fprintf(w, "No payload.\n\n")
+				continue
+			}
+			t := reflect.TypeOf(m.Payload)
+			writeFields(w, t, comments, dir == ServerToClient)
+			for _, f := range fields(t) {
+				nested = collectStructs(f.typ, seen, nested)
+			}
+		}
+	}
+
+	if len(nested) > 0 {
+		sort.Slice(nested, func(i, j int) bool { return nested[i].Name() < nested[j].Name() })
+		fmt.Fprintf(w, "## Types\n\n")
+		for _, t := range nested {
+			fmt.Fprintf(w, "### %s\n\n", t.Name())
+			if doc := comments[t.Name()]; doc != "" {
+				fmt.Fprintf(w, "%s\n\n", doc)
+			}
+			writeFields(w, t, comments, true)
+		}
+	}
+
+	return buf.Bytes()
+}
+
+const overview = `## Connecting
+
+Open a WebSocket to ` + "`/ws/{code}?participant_id={id}`" + `, where the participant ID is the one
+returned when joining the quiz. Connections without a participant ID receive updates but cannot
+send commands that act on the quiz.
+
+Where WebSockets are blocked, open ` + "`/sse/{code}?participant_id={id}&protocol={N}`" + ` as an
+EventSource instead. Every server message arrives as one event whose data is the message, and
+commands are sent with ` + "`POST /api/quiz/{code}/command`" + `: the command message plus
+` + "`participant_id`" + `. The response body is the ` + "`ack`" + `, ` + "`pong`" + ` or ` + "`error`" + ` message the
+WebSocket would have sent, with the error's status as the HTTP status.
+
+## Versions
+
+Ask for a version with the WebSocket subprotocol ` + "`quickwiz.v{N}`" + `, offering every version the
+client speaks; the server picks the newest one it also speaks. Clients that cannot set a
+subprotocol pass ` + "`?protocol={N}`" + ` instead. Clients that ask for nothing get version 1.
+
+| Version | Changes |
+|---------|---------|
+| 1 | Original protocol |
+| 2 | ` + "`welcome`" + ` message on connect; ` + "`seq`" + ` on every server message |
+
+## Envelope
+
+Every message is a JSON object:
+
+| Field | Type | Description |
+|-------|------|-------------|
+| ` + "`type`" + ` | string | Message type, see below |
+| ` + "`payload`" + ` | object | Depends on the type |
+| ` + "`seq`" + ` | integer | Server messages from version 2: position of the message on the connection, counting from 1. A gap means messages were lost; refetch ` + "`GET /api/quiz/{code}`" + ` |
+| ` + "`id`" + ` | string | Client commands: echoed in the ` + "`ack`" + ` or ` + "`error`" + ` reply |
+
+Clients must ignore message types and fields they do not know.
+
+`
+
+// writeFields writes the field table of a struct
+func writeFields(w io.Writer, t reflect.Type, comments Comments, server bool) {
+	fmt.Fprintf(w, "| Field | Type | Description |\n|-------|------|-------------|\n")
+	for _, f := range fields(t) {
+		doc := comments[t.Name()+"."+f.goName]
+		if f.optional && server {
+			doc = strings.TrimSpace("Optional. " + doc)
+		}
+		fmt.Fprintf(w, "| `%s` | %s | %s |\n", f.name, docType(f.typ), doc)
+	}
+	fmt.Fprintln(w)
+}
+
+// docType describes a type for the document
+func docType(t reflect.Type) string {
+	switch t {
+	case timeType:
+		return "string (date-time)"
+	case rawMessageType:
+		return "any"
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return docType(t.Elem())
+	case reflect.Struct:
+		return fmt.Sprintf("[%s](#%s)", t.Name(), strings.ToLower(t.Name()))
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array of " + docType(t.Elem())
+	case reflect.Map:
+		return "object of " + docType(t.Elem())
+	case reflect.Interface:
+		return "any"
+	}
+	return "integer"
+}
+
+// collectStructs appends the struct types used by a field that are not yet seen
+func collectStructs(t reflect.Type, seen map[reflect.Type]bool, out []reflect.Type) []reflect.Type {
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return collectStructs(t.Elem(), seen, out)
+	case reflect.Struct:
+		if t == timeType || seen[t] {
+			return out
+		}
+		seen[t] = true
+		out = append(out, t)
+		for _, f := range fields(t) {
+			out = collectStructs(f.typ, seen, out)
+		}
+	}
+	return out
+}
@@ -3,196 +3,650 @@ package quiz
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/rkrmr33/quickwiz/internal/clock"
 	"github.com/rkrmr33/quickwiz/internal/models"
 )
 
+var (
+	// ErrTooManySessions is returned when the maximum number of sessions is reached
+	ErrTooManySessions = errors.New("too many active quiz sessions")
+	// ErrSessionFull is returned when a session has reached its participant limit
+	ErrSessionFull = errors.New("quiz is full")
+	// ErrShuttingDown is returned when new games are rejected during shutdown
+	ErrShuttingDown = errors.New("server is shutting down")
+	// ErrPaused is returned when answering or wagering while the host has
+	// paused the quiz
+	ErrPaused = errors.New("quiz is paused")
+	// ErrNotInSuddenDeath is returned when a player not tied for first place
+	// answers a sudden death question
+	ErrNotInSuddenDeath = errors.New("only players tied for first place play sudden death")
+)
+
+// maxCodeAttempts bounds the retries when a generated code is already taken
+const maxCodeAttempts = 5
+
+// Options configures the limits of a Manager
+type Options struct {
+	SessionTTL      time.Duration // Sessions older than this are removed by CleanupOldSessions
+	MaxSessions     int           // Maximum concurrent sessions, 0 for no limit
+	MaxParticipants int           // Maximum participants per session, 0 for no limit
+	Store           Store         // Where sessions are kept, in memory when nil
+	Clock           clock.Clock   // Timestamps answers and sessions, the system clock when nil
+}
+
+// DefaultOptions returns the default manager options
+func DefaultOptions() Options {
+	return Options{
+		SessionTTL: 24 * time.Hour,
+	}
+}
+
 // Manager handles quiz sessions
 type Manager struct {
-	sessions map[string]*models.QuizSession
-	mu       sync.RWMutex
+	store    Store
+	opts     Options
+	draining bool
+	mu       sync.RWMutex // Guards draining and serialises session creation
 }
 
-// NewManager creates a new quiz manager
+// NewManager creates a new quiz manager with the default options
 func NewManager() *Manager {
+	return NewManagerWithOptions(DefaultOptions())
+}
+
+// NewManagerWithOptions creates a new quiz manager with the given options
+func NewManagerWithOptions(opts Options) *Manager {
+	store := opts.Store
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	if opts.Clock == nil {
+		opts.Clock = clock.Real
+	}
 	return &Manager{
-		sessions: make(map[string]*models.QuizSession),
+		store: store,
+		opts:  opts,
 	}
 }
 
-// CreateSession creates a new quiz session from a quiz
+// Clock returns the clock the manager tells the time with. Game loops use it
+// too, so answer times and timers agree.
+func (m *Manager) Clock() clock.Clock {
+	return m.opts.Clock
+}
+
+// CreateSession creates a new quiz session from a quiz.
+// If the quiz defines pools or a sample size, a random subset of questions is
+// drawn (and optionally shuffled); the seed used is recorded on the session so
+// the draw can be reproduced.
 func (m *Manager) CreateSession(quiz models.Quiz) (string, error) {
-	code := generateCode()
+	seed := quiz.Seed
+	if seed == 0 {
+		seed = generateSeed()
+	}
+	quiz.Questions = sampleQuestions(quiz, seed)
+	if quiz.ShuffleQuestions {
+		quiz.Questions = shuffleQuestions(quiz.Questions, seed)
+	}
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	session := &models.QuizSession{
-		Code:            code,
-		Quiz:            quiz,
-		Participants:    make(map[string]*models.Participant),
-		CurrentQuestion: -1,
-		State:           models.StateWaiting,
-		CreatedAt:       time.Now(),
+	if m.draining {
+		return "", ErrShuttingDown
+	}
+	if m.opts.MaxSessions > 0 {
+		count, err := m.store.Len()
+		if err != nil {
+			return "", err
+		}
+		if count >= m.opts.MaxSessions {
+			return "", ErrTooManySessions
+		}
 	}
 
-	m.sessions[code] = session
-	return code, nil
-}
-
-// GetSession retrieves a quiz session by code
-func (m *Manager) GetSession(code string) (*models.QuizSession, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	for attempt := 0; ; attempt++ {
+		session := &models.QuizSession{
+			Code:            generateCode(),
+			Quiz:            quiz,
+			Participants:    make(map[string]*models.Participant),
+			CurrentQuestion: -1,
+			State:           models.StateWaiting,
+			CreatedAt:       m.opts.Clock.Now(),
+			Seed:            seed,
+		}
 
-	session, exists := m.sessions[code]
-	if !exists {
-		return nil, fmt.Errorf("quiz session not found")
+		err := m.store.Create(session)
+		if errors.Is(err, errSessionExists) && attempt < maxCodeAttempts {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		sessionsCreated.Inc()
+		return session.Code, nil
 	}
+}
 
-	return session, nil
+// GetSession retrieves a copy of a quiz session by code. The copy is safe to
+// read while the game goes on; changes to it are not saved.
+func (m *Manager) GetSession(code string) (*models.QuizSession, error) {
+	var data []byte
+	err := m.store.View(code, func(s *models.QuizSession) error {
+		var err error
+		data, err = json.Marshal(s)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Copy through the encoding the shared store uses so nothing is shared
+	var session models.QuizSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("failed to copy session %s: %w", code, err)
+	}
+	return &session, nil
 }
 
 // AddParticipant adds a participant to a session
 func (m *Manager) AddParticipant(code, participantID, name string, isSpectator bool) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	session, exists := m.sessions[code]
-	if !exists {
-		return fmt.Errorf("quiz session not found")
-	}
+	return m.store.Update(code, func(session *models.QuizSession) error {
+		if session.State != models.StateWaiting {
+			return fmt.Errorf("quiz has already started")
+		}
 
-	if session.State != models.StateWaiting {
-		return fmt.Errorf("quiz has already started")
-	}
+		if m.opts.MaxParticipants > 0 && len(session.Participants) >= m.opts.MaxParticipants {
+			return ErrSessionFull
+		}
 
-	// Check if this is the first participant (creator)
-	if len(session.Participants) == 0 {
-		session.CreatorID = participantID
-	}
+		// Check if this is the first participant (creator)
+		if len(session.Participants) == 0 {
+			session.CreatorID = participantID
+		}
 
-	participant := &models.Participant{
-		ID:          participantID,
-		Name:        name,
-		Score:       0,
-		IsSpectator: isSpectator,
-		JoinedAt:    time.Now(),
-	}
+		participant := &models.Participant{
+			ID:          participantID,
+			Name:        name,
+			Score:       0,
+			IsSpectator: isSpectator,
+			JoinedAt:    m.opts.Clock.Now(),
+		}
 
-	session.Participants[participantID] = participant
-	return nil
+		session.Participants[participantID] = participant
+		return nil
+	})
 }
 
 // StartQuiz starts the quiz and moves to the first question
 func (m *Manager) StartQuiz(code string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	// A game started now could not finish before the server stops
+	draining := m.Draining()
 
-	session, exists := m.sessions[code]
-	if !exists {
-		return fmt.Errorf("quiz session not found")
-	}
+	return m.store.Update(code, func(session *models.QuizSession) error {
+		if session.State != models.StateWaiting {
+			return fmt.Errorf("quiz already started")
+		}
 
-	if session.State != models.StateWaiting {
-		return fmt.Errorf("quiz already started")
-	}
+		if draining {
+			return ErrShuttingDown
+		}
 
-	if len(session.Participants) == 0 {
-		return fmt.Errorf("no participants in quiz")
-	}
+		if len(session.Participants) == 0 {
+			return fmt.Errorf("no participants in quiz")
+		}
 
-	session.State = models.StateInProgress
-	session.CurrentQuestion = 0
-	session.QuestionStarted = time.Now()
-	session.State = models.StateQuestion
+		session.State = models.StateInProgress
+		session.CurrentQuestion = 0
+		session.QuestionStarted = m.opts.Clock.Now()
+		session.State = questionState(session)
 
-	// Reset all participants' answers
-	for _, p := range session.Participants {
-		p.HasAnswered = false
-		p.CurrentAnswer = ""
-	}
+		// Quizzes with rounds open with the first round's intro
+		if len(session.Quiz.Rounds) > 0 {
+			session.CurrentRound = session.Quiz.Questions[0].Round
+			session.State = models.StateRoundIntro
+		}
 
-	return nil
+		// Reset all participants' answers
+		for _, p := range session.Participants {
+			p.HasAnswered = false
+			p.CurrentAnswer = ""
+			p.Confidence = 0
+			p.Wager = 0
+			p.HasWagered = false
+		}
+
+		return nil
+	})
+}
+
+// BeginQuestion starts the current question after a round intro
+func (m *Manager) BeginQuestion(code string) error {
+	return m.store.Update(code, func(session *models.QuizSession) error {
+		if session.State != models.StateRoundIntro {
+			return fmt.Errorf("not in round intro state")
+		}
+
+		session.State = questionState(session)
+		session.QuestionStarted = m.opts.Clock.Now()
+
+		return nil
+	})
+}
+
+// PlaceWager stakes points of a participant's score on the current question,
+// to be won if they answer it correctly and lost otherwise. Wagers are taken
+// before the options are shown and can be changed until then.
+func (m *Manager) PlaceWager(code, participantID string, stake int) error {
+	return m.store.Update(code, func(session *models.QuizSession) error {
+		if session.State != models.StateWager {
+			return fmt.Errorf("not taking wagers right now")
+		}
+		if session.Paused {
+			return ErrPaused
+		}
+
+		participant, exists := session.Participants[participantID]
+		if !exists || participant.IsSpectator {
+			return fmt.Errorf("participant not found")
+		}
+		if !playing(session, participant) {
+			return ErrNotInSuddenDeath
+		}
+
+		if stake < 0 || stake > max(participant.Score, 0) {
+			return fmt.Errorf("stake must be between 0 and your score of %d", max(participant.Score, 0))
+		}
+
+		participant.Wager = stake
+		participant.HasWagered = true
+		return nil
+	})
+}
+
+// CheckAllWagered checks if all participants playing the question have wagered
+func (m *Manager) CheckAllWagered(code string) bool {
+	allWagered := false
+	m.store.View(code, func(session *models.QuizSession) error {
+		for _, p := range session.Participants {
+			if playing(session, p) && !p.HasWagered {
+				return nil
+			}
+		}
+		allWagered = true
+		return nil
+	})
+	return allWagered
+}
+
+// GetWagerPhase returns the wager phase of the current question as a
+// participant sees it
+func (m *Manager) GetWagerPhase(code, participantID string) (*models.WagerPhase, error) {
+	var phase *models.WagerPhase
+	err := m.store.View(code, func(session *models.QuizSession) error {
+		if session.State != models.StateWager {
+			return fmt.Errorf("not in wager state")
+		}
+
+		phase = &models.WagerPhase{
+			QuestionNumber: session.CurrentQuestion + 1,
+			TotalQuestions: len(session.Quiz.Questions),
+			Text:           session.Quiz.Questions[session.CurrentQuestion].Text,
+			TimeRemaining:  wagerTime(session),
+		}
+		if p, ok := session.Participants[participantID]; ok {
+			phase.MaxStake = max(p.Score, 0)
+			phase.Stake = p.Wager
+		}
+		return nil
+	})
+	return phase, err
+}
+
+// OpenAnswers ends the wager phase and shows the options; the time to answer
+// starts now
+func (m *Manager) OpenAnswers(code string) error {
+	return m.store.Update(code, func(session *models.QuizSession) error {
+		if session.State != models.StateWager {
+			return fmt.Errorf("not in wager state")
+		}
+
+		session.State = models.StateQuestion
+		session.QuestionStarted = m.opts.Clock.Now()
+		return nil
+	})
+}
+
+// RestartQuestion starts the time of the current question over, for a game
+// taken over from another server instance that replays the question from the
+// start. A paused quiz stays paused, with no time spent paused yet.
+func (m *Manager) RestartQuestion(code string) error {
+	return m.store.Update(code, func(session *models.QuizSession) error {
+		if session.State != models.StateQuestion && session.State != models.StateWager {
+			return fmt.Errorf("not in question state")
+		}
+
+		now := m.opts.Clock.Now()
+		session.QuestionStarted = now
+		if session.Paused {
+			session.PausedAt = now
+		}
+		return nil
+	})
 }
 
 // SubmitAnswer submits an answer for a participant
 func (m *Manager) SubmitAnswer(code, participantID, answer string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	now := m.opts.Clock.Now()
+	var latency time.Duration
+	err := m.store.Update(code, func(session *models.QuizSession) error {
+		var err error
+		latency, err = submitAnswer(session, participantID, answer, now)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	answersSubmitted.Inc()
+	answerLatency.Observe(latency.Seconds())
+	return nil
+}
 
-	session, exists := m.sessions[code]
-	if !exists {
-		return fmt.Errorf("quiz session not found")
+// SubmitAnswerIndex submits an answer by the index of the option as displayed
+// to the participant, mapping it back to the canonical option for scoring
+func (m *Manager) SubmitAnswerIndex(code, participantID string, index int) error {
+	now := m.opts.Clock.Now()
+	var latency time.Duration
+	err := m.store.Update(code, func(session *models.QuizSession) error {
+		if session.State != models.StateQuestion {
+			return fmt.Errorf("not accepting answers right now")
+		}
+
+		options := participantOptions(session, participantID)
+		if index < 0 || index >= len(options) {
+			return fmt.Errorf("invalid option index %d", index)
+		}
+
+		var err error
+		latency, err = submitAnswer(session, participantID, options[index], now)
+		return err
+	})
+	if err != nil {
+		return err
 	}
 
+	answersSubmitted.Inc()
+	answerLatency.Observe(latency.Seconds())
+	return nil
+}
+
+// submitAnswer records an answer given at now and returns how long the
+// participant took
+func submitAnswer(session *models.QuizSession, participantID, answer string, now time.Time) (time.Duration, error) {
 	if session.State != models.StateQuestion {
-		return fmt.Errorf("not accepting answers right now")
+		return 0, fmt.Errorf("not accepting answers right now")
+	}
+
+	if session.Paused {
+		return 0, ErrPaused
 	}
 
 	participant, exists := session.Participants[participantID]
 	if !exists {
-		return fmt.Errorf("participant not found")
+		return 0, fmt.Errorf("participant not found")
+	}
+	if session.SuddenDeath && !participant.InSuddenDeath {
+		return 0, ErrNotInSuddenDeath
 	}
 
 	if participant.HasAnswered {
-		return fmt.Errorf("already answered this question")
+		return 0, fmt.Errorf("already answered this question")
 	}
 
 	participant.CurrentAnswer = answer
 	participant.HasAnswered = true
-	participant.AnsweredAt = time.Now()
+	participant.AnsweredAt = now
 
-	return nil
+	return participant.AnsweredAt.Sub(session.QuestionStarted), nil
 }
 
-// CheckAllAnswered checks if all participants have answered (excluding spectators)
-func (m *Manager) CheckAllAnswered(code string) bool {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+// SetConfidence sets the confidence level a participant puts on their answer
+// to the current question, in quizzes scored by confidence. It must be set
+// before answering.
+func (m *Manager) SetConfidence(code, participantID string, level int) error {
+	return m.store.Update(code, func(session *models.QuizSession) error {
+		if session.Quiz.Scoring != models.ScoringConfidence {
+			return fmt.Errorf("quiz is not scored by confidence")
+		}
+		if level < 1 || level > MaxConfidence {
+			return fmt.Errorf("confidence must be between 1 and %d", MaxConfidence)
+		}
+		if session.State != models.StateQuestion {
+			return fmt.Errorf("not accepting answers right now")
+		}
 
-	session, exists := m.sessions[code]
-	if !exists {
-		return false
-	}
+		participant, exists := session.Participants[participantID]
+		if !exists {
+			return fmt.Errorf("participant not found")
+		}
+		if session.SuddenDeath && !participant.InSuddenDeath {
+			return ErrNotInSuddenDeath
+		}
+		if participant.HasAnswered {
+			return fmt.Errorf("already answered this question")
+		}
 
-	for _, p := range session.Participants {
-		// Skip spectators
-		if p.IsSpectator {
-			continue
+		participant.Confidence = level
+		return nil
+	})
+}
+
+// Pause freezes the game clock of a running quiz until Resume is called
+func (m *Manager) Pause(code string) error {
+	return m.store.Update(code, func(session *models.QuizSession) error {
+		if session.State == models.StateWaiting || session.State == models.StateFinished {
+			return fmt.Errorf("quiz is not running")
 		}
-		if !p.HasAnswered {
-			return false
+
+		if session.Paused {
+			return fmt.Errorf("quiz already paused")
 		}
-	}
 
-	return true
+		session.Paused = true
+		session.PausedAt = m.opts.Clock.Now()
+
+		return nil
+	})
 }
 
-// GetAnswerCount returns the number of participants who have answered (excluding spectators)
-func (m *Manager) GetAnswerCount(code string) (int, int) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+// Resume continues a paused quiz. The time spent paused does not count
+// towards answer times.
+func (m *Manager) Resume(code string) error {
+	return m.store.Update(code, func(session *models.QuizSession) error {
+		if !session.Paused {
+			return fmt.Errorf("quiz is not paused")
+		}
 
-	session, exists := m.sessions[code]
-	if !exists {
-		return 0, 0
-	}
+		session.QuestionStarted = session.QuestionStarted.Add(m.opts.Clock.Now().Sub(session.PausedAt))
+		session.Paused = false
+		session.PausedAt = time.Time{}
+
+		return nil
+	})
+}
+
+// IsPaused reports whether the host has paused the quiz
+func (m *Manager) IsPaused(code string) bool {
+	paused := false
+	m.store.View(code, func(session *models.QuizSession) error {
+		paused = session.Paused
+		return nil
+	})
+	return paused
+}
+
+// GetQuestionUpdate builds the current question as seen by a participant,
+// with options in that participant's order
+func (m *Manager) GetQuestionUpdate(code, participantID string) (*models.QuestionUpdate, error) {
+	var update *models.QuestionUpdate
+	err := m.store.View(code, func(session *models.QuizSession) error {
+		if session.CurrentQuestion < 0 || session.CurrentQuestion >= len(session.Quiz.Questions) {
+			return fmt.Errorf("no current question")
+		}
+
+		q := session.Quiz.Questions[session.CurrentQuestion]
+		options := participantOptions(session, participantID)
+
+		update = &models.QuestionUpdate{
+			QuestionNumber: session.CurrentQuestion + 1,
+			TotalQuestions: len(session.Quiz.Questions),
+			Text:           q.Text,
+			Options:        options,
+			TimeRemaining:  timePerQuestion(session),
+			SuddenDeath:    session.SuddenDeath,
+		}
+		if session.Quiz.Scoring == models.ScoringConfidence {
+			update.MaxConfidence = MaxConfidence
+		}
+		if p, ok := session.Participants[participantID]; ok {
+			update.StreakTier = streakTier(&session.Quiz, p.CurrentStreak+1)
+		}
+		return nil
+	})
+	return update, err
+}
+
+// CurrentTimings returns the time per question and the time between questions
+// in seconds for the current question, taking round overrides into account
+func (m *Manager) CurrentTimings(code string) (int, int) {
+	perQuestion, between := 0, 0
+	m.store.View(code, func(session *models.QuizSession) error {
+		perQuestion, between = timePerQuestion(session), timeBetweenQuestions(session)
+		return nil
+	})
+	return perQuestion, between
+}
+
+// GetRoundIntro returns the intro of the current round
+func (m *Manager) GetRoundIntro(code string) (*models.RoundIntro, error) {
+	var intro *models.RoundIntro
+	err := m.store.View(code, func(session *models.QuizSession) error {
+		if len(session.Quiz.Rounds) == 0 {
+			return fmt.Errorf("quiz has no rounds")
+		}
 
+		round := session.Quiz.Rounds[session.CurrentRound]
+		rounds := sessionRounds(session)
+
+		questionCount := 0
+		for _, q := range session.Quiz.Questions {
+			if q.Round == session.CurrentRound {
+				questionCount++
+			}
+		}
+
+		intro = &models.RoundIntro{
+			RoundNumber:   roundNumber(rounds, session.CurrentRound),
+			TotalRounds:   len(rounds),
+			Title:         round.Title,
+			Description:   round.Description,
+			QuestionCount: questionCount,
+			TimeRemaining: session.Quiz.RoundIntroTime,
+		}
+		return nil
+	})
+	return intro, err
+}
+
+// GetRoundSummary returns the standings of the current round if the current
+// question is the last of its round and more rounds follow, or nil otherwise
+func (m *Manager) GetRoundSummary(code string) (*models.RoundSummary, error) {
+	var summary *models.RoundSummary
+	err := m.store.View(code, func(session *models.QuizSession) error {
+		questions := session.Quiz.Questions
+		next := session.CurrentQuestion + 1
+		if len(session.Quiz.Rounds) == 0 || next >= len(questions) || questions[next].Round == session.CurrentRound {
+			return nil
+		}
+
+		leaderboard := make([]models.ParticipantInfo, 0, len(session.Participants))
+		for _, p := range session.Participants {
+			if p.IsSpectator {
+				continue
+			}
+			leaderboard = append(leaderboard, models.ParticipantInfo{
+				Name:       p.Name,
+				Score:      p.Score,
+				RoundScore: p.RoundScore,
+			})
+		}
+		sort.SliceStable(leaderboard, func(i, j int) bool {
+			if leaderboard[i].RoundScore != leaderboard[j].RoundScore {
+				return leaderboard[i].RoundScore > leaderboard[j].RoundScore
+			}
+			return leaderboard[i].Name < leaderboard[j].Name
+		})
+
+		rounds := sessionRounds(session)
+		summary = &models.RoundSummary{
+			RoundNumber:   roundNumber(rounds, session.CurrentRound),
+			TotalRounds:   len(rounds),
+			Title:         session.Quiz.Rounds[session.CurrentRound].Title,
+			Leaderboard:   leaderboard,
+			TimeRemaining: session.Quiz.TimeBetweenRounds,
+		}
+		return nil
+	})
+	return summary, err
+}
+
+// CheckAllAnswered checks if all participants playing the question have answered
+func (m *Manager) CheckAllAnswered(code string) bool {
+	allAnswered := false
+	m.store.View(code, func(session *models.QuizSession) error {
+		for _, p := range session.Participants {
+			// Skip spectators and players out of sudden death
+			if !playing(session, p) {
+				continue
+			}
+			if !p.HasAnswered {
+				return nil
+			}
+		}
+		allAnswered = true
+		return nil
+	})
+	return allAnswered
+}
+
+// GetAnswerCount returns how many of the participants playing the question
+// have answered, and how many play it
+func (m *Manager) GetAnswerCount(code string) (int, int) {
 	answeredCount := 0
 	totalParticipants := 0
-	for _, p := range session.Participants {
-		// Skip spectators
-		if p.IsSpectator {
-			continue
-		}
-		totalParticipants++
-		if p.HasAnswered {
-			answeredCount++
+	err := m.store.View(code, func(session *models.QuizSession) error {
+		for _, p := range session.Participants {
+			// Skip spectators and players out of sudden death
+			if !playing(session, p) {
+				continue
+			}
+			totalParticipants++
+			if p.HasAnswered {
+				answeredCount++
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0
 	}
 
 	return answeredCount, totalParticipants
@@ -200,175 +654,305 @@ func (m *Manager) GetAnswerCount(code string) (int, int) {
 
 // RevealAnswer reveals the answer and updates scores
 func (m *Manager) RevealAnswer(code string) (*models.AnswerReveal, error) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	session, exists := m.sessions[code]
-	if !exists {
-		return nil, fmt.Errorf("quiz session not found")
-	}
+	var reveal *models.AnswerReveal
+	err := m.store.Update(code, func(session *models.QuizSession) error {
+		if session.State != models.StateQuestion {
+			return fmt.Errorf("not in question state")
+		}
 
-	if session.State != models.StateQuestion {
-		return nil, fmt.Errorf("not in question state")
-	}
+		currentQ := session.Quiz.Questions[session.CurrentQuestion]
+		session.State = models.StateAnswer
 
-	currentQ := session.Quiz.Questions[session.CurrentQuestion]
-	session.State = models.StateAnswer
+		// Remember the standings before this question to report rank changes
+		if session.CurrentQuestion > 0 {
+			for _, s := range rankParticipants(session) {
+				s.PreviousRank = s.Rank
+			}
+		}
 
-	// Find the quickest correct answer if bonus is enabled
-	var quickestParticipant *models.Participant
-	var earliestTime time.Time
-	if session.Quiz.QuickestAnswerBonus {
+		// Score every player's answer with the quiz's scorer
+		answers := make([]Answer, 0, len(session.Participants))
 		for _, p := range session.Participants {
-			if p.IsSpectator || !p.HasAnswered {
+			// Skip spectators and players out of sudden death in results
+			if !playing(session, p) {
 				continue
 			}
-			if p.CurrentAnswer == currentQ.Answer {
-				if quickestParticipant == nil || p.AnsweredAt.Before(earliestTime) {
-					quickestParticipant = p
-					earliestTime = p.AnsweredAt
-				}
+			answer := Answer{
+				ParticipantID: p.ID,
+				Answer:        p.CurrentAnswer,
+				Answered:      p.HasAnswered,
+				Confidence:    p.Confidence,
+				Streak:        p.CurrentStreak,
+			}
+			if p.HasAnswered {
+				answer.AnsweredAt = p.AnsweredAt
+				answer.Took = p.AnsweredAt.Sub(session.QuestionStarted)
 			}
+			answers = append(answers, answer)
 		}
-	}
-
-	participants := make([]models.ParticipantInfo, 0, len(session.Participants))
-	for _, p := range session.Participants {
-		// Skip spectators in results
-		if p.IsSpectator {
-			continue
+		question := ScoredQuestion{
+			Question:  currentQ,
+			TimeLimit: time.Duration(timePerQuestion(session)) * time.Second,
 		}
+		breakdowns := scorerFor(&session.Quiz).Score(question, answers, &session.Quiz)
+
+		participants := make([]models.ParticipantInfo, 0, len(answers))
+		for _, answer := range answers {
+			p := session.Participants[answer.ParticipantID]
+			b := breakdowns[p.ID]
+			b.Wager = wagerResult(b.Correct, p.Wager)
+
+			p.Score += b.Total()
+			p.RoundScore += b.Total()
+			p.CurrentStreak = b.Streak
+			if b.Correct {
+				p.CorrectCount++
+				p.CorrectTime += answer.Took
+				p.LastCorrectAt = p.AnsweredAt
+			}
 
-		isCorrect := p.CurrentAnswer == currentQ.Answer
-		streakBonus := 0
-		isQuickest := false
-		answerTime := 0.0
+			participants = append(participants, models.ParticipantInfo{
+				Name:                 p.Name,
+				Answer:               p.CurrentAnswer,
+				IsCorrect:            b.Correct,
+				Score:                p.Score,
+				RoundScore:           p.RoundScore,
+				Streak:               p.CurrentStreak,
+				Points:               b.Points,
+				StreakBonus:          b.StreakBonus,
+				StreakMilestone:      b.Milestone,
+				Wager:                b.Wager,
+				QuickestAnswerFlag:   b.QuickestBonus > 0,
+				AnswerSubmissionTime: answer.Took.Seconds(),
+			})
+		}
 
-		// Calculate answer submission time if participant answered
-		if p.HasAnswered {
-			answerTime = p.AnsweredAt.Sub(session.QuestionStarted).Seconds()
+		reveal = &models.AnswerReveal{
+			CorrectAnswer: currentQ.Answer,
+			Participants:  participants,
 		}
+		return nil
+	})
+	return reveal, err
+}
 
-		if isCorrect {
-			// Increment streak
-			p.CurrentStreak++
+// NextQuestion moves to the next question or finishes the quiz
+func (m *Manager) NextQuestion(code string) (bool, error) {
+	hasNext := false
+	err := m.store.Update(code, func(session *models.QuizSession) error {
+		if session.State != models.StateAnswer {
+			return fmt.Errorf("not in answer state")
+		}
 
-			// Base point for correct answer
-			p.Score++
+		// Check if there are more questions; sudden death questions follow
+		// the last one while first place is tied
+		if session.CurrentQuestion+1 >= len(session.Quiz.Questions) && !addSuddenDeathQuestion(session) {
+			session.State = models.StateFinished
+			hasNext = false
+			return nil
+		}
 
-			// Calculate streak bonus if enabled
-			if session.Quiz.StreakBonus {
-				streakBonus = calculateStreakBonus(p.CurrentStreak)
-				p.Score += streakBonus
+		// Move to next question
+		session.CurrentQuestion++
+		session.State = questionState(session)
+		session.QuestionStarted = m.opts.Clock.Now()
+
+		// Entering a new round shows its intro before the question starts
+		if next := session.Quiz.Questions[session.CurrentQuestion]; len(session.Quiz.Rounds) > 0 && next.Round != session.CurrentRound {
+			session.CurrentRound = next.Round
+			session.State = models.StateRoundIntro
+			for _, p := range session.Participants {
+				p.RoundScore = 0
 			}
+		}
 
-			// Award quickest answer bonus if applicable
-			if session.Quiz.QuickestAnswerBonus && quickestParticipant != nil && p.ID == quickestParticipant.ID {
-				p.Score++
-				isQuickest = true
+		// Reset all participants' answers
+		for _, p := range session.Participants {
+			p.HasAnswered = false
+			p.CurrentAnswer = ""
+			p.Confidence = 0
+			p.Wager = 0
+			p.HasWagered = false
+		}
+
+		hasNext = true
+		return nil
+	})
+	return hasNext, err
+}
+
+// GetLeaderboardUpdate returns the live leaderboard as a participant sees it:
+// the leading players and the participant's own standing
+func (m *Manager) GetLeaderboardUpdate(code, participantID string) (*models.Leaderboard, error) {
+	var update *models.Leaderboard
+	err := m.store.View(code, func(session *models.QuizSession) error {
+		standings := rankParticipants(session)
+		participants := leaderboard(standings)
+
+		update = &models.Leaderboard{
+			QuestionNumber: session.CurrentQuestion + 1,
+			TotalQuestions: len(session.Quiz.Questions),
+			Top:            participants[:min(LeaderboardSize, len(participants))],
+			TotalPlayers:   len(participants),
+		}
+		for i, s := range standings {
+			if s.ID == participantID {
+				update.You = &participants[i]
 			}
-		} else {
-			// Reset streak on wrong answer
-			p.CurrentStreak = 0
-		}
-
-		participants = append(participants, models.ParticipantInfo{
-			Name:                 p.Name,
-			Answer:               p.CurrentAnswer,
-			IsCorrect:            isCorrect,
-			Score:                p.Score,
-			Streak:               p.CurrentStreak,
-			StreakBonus:          streakBonus,
-			QuickestAnswerFlag:   isQuickest,
-			AnswerSubmissionTime: answerTime,
-		})
-	}
+		}
+		return nil
+	})
+	return update, err
+}
 
-	return &models.AnswerReveal{
-		CorrectAnswer: currentQ.Answer,
-		Participants:  participants,
-	}, nil
+// GetLeaderboard returns the standings of the players, ranked by score and
+// the quiz's tie-breakers (excluding spectators)
+func (m *Manager) GetLeaderboard(code string) ([]models.ParticipantInfo, error) {
+	var participants []models.ParticipantInfo
+	err := m.store.View(code, func(session *models.QuizSession) error {
+		participants = leaderboard(rankParticipants(session))
+		return nil
+	})
+	return participants, err
 }
 
-// NextQuestion moves to the next question or finishes the quiz
-func (m *Manager) NextQuestion(code string) (bool, error) {
+// Drain stops the manager from accepting new sessions and starting games.
+// Games already in progress keep running.
+func (m *Manager) Drain() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	session, exists := m.sessions[code]
-	if !exists {
-		return false, fmt.Errorf("quiz session not found")
-	}
+	m.draining = true
+}
 
-	if session.State != models.StateAnswer {
-		return false, fmt.Errorf("not in answer state")
-	}
+// Draining reports whether Drain has been called
+func (m *Manager) Draining() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 
-	// Check if there are more questions
-	if session.CurrentQuestion+1 >= len(session.Quiz.Questions) {
-		session.State = models.StateFinished
-		return false, nil
-	}
+	return m.draining
+}
 
-	// Move to next question
-	session.CurrentQuestion++
-	session.State = models.StateQuestion
-	session.QuestionStarted = time.Now()
+// ActiveGames returns the number of games that have started but not finished
+func (m *Manager) ActiveGames() int {
+	codes, _ := m.ActiveCodes()
+	return len(codes)
+}
 
-	// Reset all participants' answers
-	for _, p := range session.Participants {
-		p.HasAnswered = false
-		p.CurrentAnswer = ""
-	}
+// ActiveCodes returns the codes of the games that have started but not finished
+func (m *Manager) ActiveCodes() ([]string, error) {
+	var codes []string
+	err := m.store.Each(func(session *models.QuizSession) {
+		if session.State != models.StateWaiting && session.State != models.StateFinished {
+			codes = append(codes, session.Code)
+		}
+	})
+	return codes, err
+}
 
-	return true, nil
+// SessionCounts returns the number of sessions in each state
+func (m *Manager) SessionCounts() map[models.SessionState]int {
+	counts := make(map[models.SessionState]int)
+	m.store.Each(func(session *models.QuizSession) {
+		counts[session.State]++
+	})
+	return counts
 }
 
-// GetLeaderboard returns the final leaderboard (excluding spectators)
-func (m *Manager) GetLeaderboard(code string) ([]models.ParticipantInfo, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+// ParticipantCounts returns the number of players and spectators across all sessions
+func (m *Manager) ParticipantCounts() (int, int) {
+	players, spectators := 0, 0
+	m.store.Each(func(session *models.QuizSession) {
+		for _, p := range session.Participants {
+			if p.IsSpectator {
+				spectators++
+			} else {
+				players++
+			}
+		}
+	})
+	return players, spectators
+}
 
-	session, exists := m.sessions[code]
-	if !exists {
-		return nil, fmt.Errorf("quiz session not found")
+// CleanupOldSessions removes sessions older than the session TTL
+func (m *Manager) CleanupOldSessions() error {
+	cutoff := m.opts.Clock.Now().Add(-m.opts.SessionTTL)
+	var expired []string
+	err := m.store.Each(func(session *models.QuizSession) {
+		if session.CreatedAt.Before(cutoff) {
+			expired = append(expired, session.Code)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %w", err)
 	}
 
-	participants := make([]models.ParticipantInfo, 0, len(session.Participants))
-	for _, p := range session.Participants {
-		// Skip spectators in leaderboard
-		if p.IsSpectator {
-			continue
+	var errs []error
+	for _, code := range expired {
+		if err := m.store.Delete(code); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete session %s: %w", code, err))
 		}
-		participants = append(participants, models.ParticipantInfo{
-			Name:  p.Name,
-			Score: p.Score,
-		})
 	}
+	return errors.Join(errs...)
+}
 
-	// Sort by score (descending)
-	for i := 0; i < len(participants)-1; i++ {
-		for j := i + 1; j < len(participants); j++ {
-			if participants[j].Score > participants[i].Score {
-				participants[i], participants[j] = participants[j], participants[i]
-			}
+// questionState is the state a question starts in: taking wagers first in
+// quizzes with wagers
+func questionState(session *models.QuizSession) models.SessionState {
+	if session.Quiz.Wagers {
+		return models.StateWager
+	}
+	return models.StateQuestion
+}
+
+// wagerTime returns how long players have to wager in seconds
+func wagerTime(session *models.QuizSession) int {
+	if session.Quiz.WagerTime > 0 {
+		return session.Quiz.WagerTime
+	}
+	return models.DefaultWagerTime
+}
+
+// timePerQuestion returns the time limit of the current question in seconds
+func timePerQuestion(session *models.QuizSession) int {
+	if len(session.Quiz.Rounds) > 0 {
+		if t := session.Quiz.Rounds[session.CurrentRound].TimePerQuestion; t > 0 {
+			return t
 		}
 	}
+	return session.Quiz.TimePerQuestion
+}
 
-	return participants, nil
+// timeBetweenQuestions returns the pause after the current question in seconds
+func timeBetweenQuestions(session *models.QuizSession) int {
+	if len(session.Quiz.Rounds) > 0 {
+		if t := session.Quiz.Rounds[session.CurrentRound].TimeBetweenQuestions; t > 0 {
+			return t
+		}
+	}
+	return session.Quiz.TimeBetweenQuestions
 }
 
-// CleanupOldSessions removes sessions older than 24 hours
-func (m *Manager) CleanupOldSessions() {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+// sessionRounds returns the indices of the rounds that have questions in the
+// session, in play order (sampling may leave some rounds empty)
+func sessionRounds(session *models.QuizSession) []int {
+	var rounds []int
+	for i, q := range session.Quiz.Questions {
+		if i == 0 || q.Round != session.Quiz.Questions[i-1].Round {
+			rounds = append(rounds, q.Round)
+		}
+	}
+	return rounds
+}
 
-	cutoff := time.Now().Add(-24 * time.Hour)
-	for code, session := range m.sessions {
-		if session.CreatedAt.Before(cutoff) {
-			delete(m.sessions, code)
+// roundNumber returns the 1-based position of a round in play order
+func roundNumber(rounds []int, round int) int {
+	for i, r := range rounds {
+		if r == round {
+			return i + 1
 		}
 	}
+	return 0
 }
 
 // generateCode generates a random 6-character code (lowercase for URLs)
@@ -377,18 +961,3 @@ func generateCode() string {
 	rand.Read(bytes)
 	return hex.EncodeToString(bytes) // lowercase for URLs
 }
-
-// calculateStreakBonus calculates bonus points based on current streak
-// 3+ correct: +1 point per answer
-// 5+ correct: +2 points per answer
-// 10+ correct: +5 points per answer
-func calculateStreakBonus(streak int) int {
-	if streak >= 10 {
-		return 5
-	} else if streak >= 5 {
-		return 2
-	} else if streak >= 3 {
-		return 1
-	}
-	return 0
-}
@@ -1,8 +1,12 @@
 package quiz
 
 import (
+	"errors"
+	"fmt"
 	"testing"
+	"time"
 
+	"github.com/rkrmr33/quickwiz/internal/clock"
 	"github.com/rkrmr33/quickwiz/internal/models"
 )
 
@@ -273,6 +277,24 @@ func TestNextQuestion(t *testing.T) {
 	}
 }
 
+func TestGetSessionCopy(t *testing.T) {
+	manager := NewManager()
+	code, _ := manager.CreateSession(models.Quiz{
+		Title:     "Copy Quiz",
+		Questions: []models.Question{{Text: "Question 1?", Options: []string{"A", "B"}, Answer: "A"}},
+	})
+	manager.AddParticipant(code, "p1", "Alice", false)
+
+	session, _ := manager.GetSession(code)
+	session.Participants["p1"].Score = 10
+	session.Quiz.Questions[0].Options[0] = "Z"
+
+	session, _ = manager.GetSession(code)
+	if session.Participants["p1"].Score != 0 || session.Quiz.Questions[0].Options[0] != "A" {
+		t.Error("Expected changes to a copy of the session not to reach the manager")
+	}
+}
+
 func TestGetLeaderboard(t *testing.T) {
 	manager := NewManager()
 	quiz := models.Quiz{
@@ -294,10 +316,12 @@ func TestGetLeaderboard(t *testing.T) {
 	manager.StartQuiz(code)
 
 	// Set scores manually for testing
-	session, _ := manager.GetSession(code)
-	session.Participants["p1"].Score = 10
-	session.Participants["p2"].Score = 5
-	session.Participants["p3"].Score = 15
+	manager.store.Update(code, func(session *models.QuizSession) error {
+		session.Participants["p1"].Score = 10
+		session.Participants["p2"].Score = 5
+		session.Participants["p3"].Score = 15
+		return nil
+	})
 
 	// Get leaderboard
 	leaderboard, err := manager.GetLeaderboard(code)
@@ -323,3 +347,579 @@ func TestGetLeaderboard(t *testing.T) {
 			leaderboard[2].Name, leaderboard[2].Score)
 	}
 }
+
+func TestCreateSessionSampling(t *testing.T) {
+	quiz := models.Quiz{
+		Title:           "Test Quiz",
+		TimePerQuestion: 30,
+		Sample:          3,
+		Seed:            1234,
+		Pools:           []models.Pool{{Name: "Pool A", Sample: 2}},
+	}
+	for i := 0; i < 10; i++ {
+		q := models.Question{
+			Text:    fmt.Sprintf("Question %d?", i+1),
+			Options: []string{"A", "B"},
+			Answer:  "A",
+		}
+		if i >= 5 {
+			q.Pool = "Pool A"
+		}
+		quiz.Questions = append(quiz.Questions, q)
+	}
+
+	manager := NewManager()
+	code1, _ := manager.CreateSession(quiz)
+	code2, _ := manager.CreateSession(quiz)
+	session1, _ := manager.GetSession(code1)
+	session2, _ := manager.GetSession(code2)
+
+	if session1.Seed != 1234 {
+		t.Errorf("Expected seed 1234 to be recorded, got %d", session1.Seed)
+	}
+	if len(session1.Quiz.Questions) != 3 {
+		t.Fatalf("Expected 3 sampled questions, got %d", len(session1.Quiz.Questions))
+	}
+
+	// Same seed must draw the same questions
+	for i := range session1.Quiz.Questions {
+		if session1.Quiz.Questions[i].Text != session2.Quiz.Questions[i].Text {
+			t.Errorf("Expected identical draws for the same seed, got '%s' and '%s'",
+				session1.Quiz.Questions[i].Text, session2.Quiz.Questions[i].Text)
+		}
+	}
+
+	// The source quiz must not be modified
+	if len(quiz.Questions) != 10 {
+		t.Errorf("Expected source quiz to keep 10 questions, got %d", len(quiz.Questions))
+	}
+}
+
+func TestSampleQuestionsPools(t *testing.T) {
+	quiz := models.Quiz{
+		Pools: []models.Pool{{Name: "A", Sample: 1}, {Name: "B"}},
+		Questions: []models.Question{
+			{Text: "Fixed"},
+			{Text: "A1", Pool: "A"},
+			{Text: "A2", Pool: "A"},
+			{Text: "A3", Pool: "A"},
+			{Text: "B1", Pool: "B"},
+			{Text: "B2", Pool: "B"},
+		},
+	}
+
+	for seed := int64(1); seed <= 20; seed++ {
+		questions := sampleQuestions(quiz, seed)
+		if len(questions) != 4 {
+			t.Fatalf("Seed %d: expected 4 questions, got %d", seed, len(questions))
+		}
+		if questions[0].Text != "Fixed" {
+			t.Errorf("Seed %d: expected fixed question first, got '%s'", seed, questions[0].Text)
+		}
+		if questions[1].Pool != "A" {
+			t.Errorf("Seed %d: expected one question from pool A, got '%s'", seed, questions[1].Text)
+		}
+		if questions[2].Text != "B1" || questions[3].Text != "B2" {
+			t.Errorf("Seed %d: expected all of pool B in order, got '%s', '%s'",
+				seed, questions[2].Text, questions[3].Text)
+		}
+	}
+}
+
+func TestShuffleOptionsPerParticipant(t *testing.T) {
+	manager := NewManager()
+	quiz := models.Quiz{
+		Title:           "Test Quiz",
+		TimePerQuestion: 30,
+		ShuffleOptions:  true,
+		Seed:            99,
+		Questions: []models.Question{
+			{
+				Text:    "Question 1?",
+				Options: []string{"A", "B", "C", "D", "E", "F"},
+				Answer:  "C",
+			},
+		},
+	}
+
+	code, _ := manager.CreateSession(quiz)
+	manager.AddParticipant(code, "host", "Host", true)
+	for i := 0; i < 5; i++ {
+		manager.AddParticipant(code, fmt.Sprintf("p%d", i), fmt.Sprintf("Player %d", i), false)
+	}
+	manager.StartQuiz(code)
+
+	// Spectators see the canonical order
+	hostUpdate, err := manager.GetQuestionUpdate(code, "host")
+	if err != nil {
+		t.Fatalf("Failed to get question update: %v", err)
+	}
+	if fmt.Sprint(hostUpdate.Options) != fmt.Sprint(quiz.Questions[0].Options) {
+		t.Errorf("Expected spectator to see canonical order, got %v", hostUpdate.Options)
+	}
+
+	// Each participant gets a stable order, and not everyone gets the same one
+	layouts := make(map[string]bool)
+	for i := 0; i < 5; i++ {
+		id := fmt.Sprintf("p%d", i)
+		first, _ := manager.GetQuestionUpdate(code, id)
+		second, _ := manager.GetQuestionUpdate(code, id)
+		if fmt.Sprint(first.Options) != fmt.Sprint(second.Options) {
+			t.Errorf("Expected a deterministic order for %s, got %v and %v", id, first.Options, second.Options)
+		}
+		layouts[fmt.Sprint(first.Options)] = true
+	}
+	if len(layouts) < 2 {
+		t.Error("Expected participants to see different option orders")
+	}
+
+	// Answering by displayed index maps back to the canonical option
+	update, _ := manager.GetQuestionUpdate(code, "p0")
+	correctIndex := -1
+	for i, opt := range update.Options {
+		if opt == "C" {
+			correctIndex = i
+		}
+	}
+	if err := manager.SubmitAnswerIndex(code, "p0", correctIndex); err != nil {
+		t.Fatalf("Failed to submit answer by index: %v", err)
+	}
+	if err := manager.SubmitAnswerIndex(code, "p1", 42); err == nil {
+		t.Error("Expected error for out of range option index")
+	}
+
+	reveal, _ := manager.RevealAnswer(code)
+	for _, p := range reveal.Participants {
+		if p.Name == "Player 0" && !p.IsCorrect {
+			t.Errorf("Expected Player 0's answer '%s' to be correct", p.Answer)
+		}
+	}
+}
+
+func TestShuffleQuestions(t *testing.T) {
+	quiz := models.Quiz{
+		Title:            "Test Quiz",
+		TimePerQuestion:  30,
+		ShuffleQuestions: true,
+		Seed:             7,
+	}
+	for i := 0; i < 10; i++ {
+		quiz.Questions = append(quiz.Questions, models.Question{
+			Text:    fmt.Sprintf("Question %d?", i+1),
+			Options: []string{"A", "B"},
+			Answer:  "A",
+		})
+	}
+
+	manager := NewManager()
+	code1, _ := manager.CreateSession(quiz)
+	code2, _ := manager.CreateSession(quiz)
+	session1, _ := manager.GetSession(code1)
+	session2, _ := manager.GetSession(code2)
+
+	if len(session1.Quiz.Questions) != 10 {
+		t.Fatalf("Expected 10 questions, got %d", len(session1.Quiz.Questions))
+	}
+
+	inOrder := true
+	for i := range session1.Quiz.Questions {
+		if session1.Quiz.Questions[i].Text != session2.Quiz.Questions[i].Text {
+			t.Errorf("Expected the same order for the same seed at position %d", i)
+		}
+		if session1.Quiz.Questions[i].Text != quiz.Questions[i].Text {
+			inOrder = false
+		}
+	}
+	if inOrder {
+		t.Error("Expected questions to be shuffled")
+	}
+}
+
+func TestRounds(t *testing.T) {
+	manager := NewManager()
+	quiz := models.Quiz{
+		Title:                "Test Quiz",
+		TimePerQuestion:      30,
+		TimeBetweenQuestions: 5,
+		TimeBetweenRounds:    10,
+		RoundIntroTime:       5,
+		Rounds: []models.Round{
+			{Title: "One", TimePerQuestion: 10},
+			{Title: "Two"},
+		},
+		Questions: []models.Question{
+			{Text: "Q1?", Options: []string{"A", "B"}, Answer: "A", Round: 0},
+			{Text: "Q2?", Options: []string{"A", "B"}, Answer: "A", Round: 1},
+			{Text: "Q3?", Options: []string{"A", "B"}, Answer: "B", Round: 1},
+		},
+	}
+
+	code, _ := manager.CreateSession(quiz)
+	manager.AddParticipant(code, "p1", "Alice", false)
+	manager.AddParticipant(code, "p2", "Bob", false)
+	manager.StartQuiz(code)
+
+	// The quiz opens with the first round's intro and does not accept answers yet
+	session, _ := manager.GetSession(code)
+	if session.State != models.StateRoundIntro {
+		t.Fatalf("Expected state 'round_intro', got '%s'", session.State)
+	}
+	if err := manager.SubmitAnswer(code, "p1", "A"); err == nil {
+		t.Error("Expected answers to be rejected during the round intro")
+	}
+
+	intro, err := manager.GetRoundIntro(code)
+	if err != nil {
+		t.Fatalf("Failed to get round intro: %v", err)
+	}
+	if intro.Title != "One" || intro.RoundNumber != 1 || intro.TotalRounds != 2 || intro.QuestionCount != 1 {
+		t.Errorf("Unexpected round intro: %+v", intro)
+	}
+
+	if err := manager.BeginQuestion(code); err != nil {
+		t.Fatalf("Failed to begin question: %v", err)
+	}
+	if perQuestion, _ := manager.CurrentTimings(code); perQuestion != 10 {
+		t.Errorf("Expected round time override of 10, got %d", perQuestion)
+	}
+
+	manager.SubmitAnswer(code, "p1", "A")
+	manager.SubmitAnswer(code, "p2", "B")
+	manager.RevealAnswer(code)
+
+	// Last question of round one: summary is available
+	summary, err := manager.GetRoundSummary(code)
+	if err != nil || summary == nil {
+		t.Fatalf("Expected a round summary, got %v (err: %v)", summary, err)
+	}
+	if summary.Leaderboard[0].Name != "Alice" || summary.Leaderboard[0].RoundScore != 1 {
+		t.Errorf("Expected Alice to lead round one with 1 point, got %+v", summary.Leaderboard[0])
+	}
+
+	// Moving on enters round two's intro and resets round scores
+	hasNext, _ := manager.NextQuestion(code)
+	if !hasNext {
+		t.Fatal("Expected more questions")
+	}
+	session, _ = manager.GetSession(code)
+	if session.State != models.StateRoundIntro || session.CurrentRound != 1 {
+		t.Errorf("Expected round two intro, got state '%s' round %d", session.State, session.CurrentRound)
+	}
+	if session.Participants["p1"].RoundScore != 0 || session.Participants["p1"].Score != 1 {
+		t.Errorf("Expected round score reset and total kept, got round %d total %d",
+			session.Participants["p1"].RoundScore, session.Participants["p1"].Score)
+	}
+	if perQuestion, _ := manager.CurrentTimings(code); perQuestion != 30 {
+		t.Errorf("Expected quiz default time of 30, got %d", perQuestion)
+	}
+
+	manager.BeginQuestion(code)
+	manager.SubmitAnswer(code, "p1", "A")
+	manager.RevealAnswer(code)
+
+	// Not the last question of round two: no summary
+	if summary, _ := manager.GetRoundSummary(code); summary != nil {
+		t.Errorf("Expected no summary mid-round, got %+v", summary)
+	}
+
+	// Staying within a round goes straight to the next question
+	manager.NextQuestion(code)
+	session, _ = manager.GetSession(code)
+	if session.State != models.StateQuestion {
+		t.Errorf("Expected state 'question', got '%s'", session.State)
+	}
+}
+
+func TestManagerLimits(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	manager := NewManagerWithOptions(Options{
+		SessionTTL:      time.Hour,
+		MaxSessions:     1,
+		MaxParticipants: 2,
+		Clock:           c,
+	})
+	quiz := models.Quiz{
+		Title:           "Test Quiz",
+		TimePerQuestion: 30,
+		Questions: []models.Question{
+			{Text: "Question 1?", Options: []string{"A", "B"}, Answer: "A"},
+		},
+	}
+
+	code, err := manager.CreateSession(quiz)
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	if _, err := manager.CreateSession(quiz); !errors.Is(err, ErrTooManySessions) {
+		t.Errorf("Expected ErrTooManySessions, got %v", err)
+	}
+
+	manager.AddParticipant(code, "p1", "Alice", false)
+	manager.AddParticipant(code, "p2", "Bob", false)
+	if err := manager.AddParticipant(code, "p3", "Carol", true); !errors.Is(err, ErrSessionFull) {
+		t.Errorf("Expected ErrSessionFull, got %v", err)
+	}
+
+	// Sessions younger than the TTL survive cleanup, older ones are removed
+	c.Advance(time.Hour)
+	manager.CleanupOldSessions()
+	if _, err := manager.GetSession(code); err != nil {
+		t.Fatalf("Expected session to survive cleanup: %v", err)
+	}
+	c.Advance(time.Nanosecond)
+	manager.CleanupOldSessions()
+	if _, err := manager.GetSession(code); err == nil {
+		t.Error("Expected expired session to be removed")
+	}
+
+	// Removing the session frees a slot
+	if _, err := manager.CreateSession(quiz); err != nil {
+		t.Errorf("Expected session to be created after cleanup: %v", err)
+	}
+}
+
+func TestDrain(t *testing.T) {
+	manager := NewManager()
+	quiz := models.Quiz{
+		Title:           "Test Quiz",
+		TimePerQuestion: 30,
+		Questions: []models.Question{
+			{Text: "Question 1?", Options: []string{"A", "B"}, Answer: "A"},
+		},
+	}
+
+	running, _ := manager.CreateSession(quiz)
+	manager.AddParticipant(running, "p1", "Alice", false)
+	manager.StartQuiz(running)
+	waiting, _ := manager.CreateSession(quiz)
+	manager.AddParticipant(waiting, "p1", "Alice", false)
+
+	if active := manager.ActiveGames(); active != 1 {
+		t.Errorf("Expected 1 active game, got %d", active)
+	}
+	if codes, _ := manager.ActiveCodes(); len(codes) != 1 || codes[0] != running {
+		t.Errorf("Expected the running game to be active, got %v", codes)
+	}
+
+	manager.Drain()
+
+	if _, err := manager.CreateSession(quiz); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("Expected ErrShuttingDown creating a session, got %v", err)
+	}
+	if err := manager.StartQuiz(waiting); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("Expected ErrShuttingDown starting a quiz, got %v", err)
+	}
+
+	// The running game can still be played to the end
+	if err := manager.SubmitAnswer(running, "p1", "A"); err != nil {
+		t.Fatalf("Failed to submit answer while draining: %v", err)
+	}
+	manager.RevealAnswer(running)
+	if hasMore, _ := manager.NextQuestion(running); hasMore {
+		t.Fatal("Expected quiz to be finished")
+	}
+	if active := manager.ActiveGames(); active != 0 {
+		t.Errorf("Expected no active games, got %d", active)
+	}
+}
+
+func TestPauseResume(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	manager := NewManagerWithOptions(Options{Clock: c})
+	quiz := models.Quiz{
+		Title:           "Test Quiz",
+		TimePerQuestion: 30,
+		Questions: []models.Question{
+			{Text: "Question 1?", Options: []string{"A", "B"}, Answer: "A"},
+		},
+	}
+
+	code, _ := manager.CreateSession(quiz)
+	manager.AddParticipant(code, "p1", "Alice", false)
+
+	if err := manager.Pause(code); err == nil {
+		t.Error("Expected error pausing a quiz that has not started")
+	}
+
+	manager.StartQuiz(code)
+	if err := manager.Pause(code); err != nil {
+		t.Fatalf("Failed to pause quiz: %v", err)
+	}
+	if !manager.IsPaused(code) {
+		t.Error("Expected quiz to be paused")
+	}
+	if err := manager.Pause(code); err == nil {
+		t.Error("Expected error pausing a paused quiz")
+	}
+
+	if err := manager.SubmitAnswer(code, "p1", "A"); !errors.Is(err, ErrPaused) {
+		t.Errorf("Expected ErrPaused answering while paused, got %v", err)
+	}
+
+	session, _ := manager.GetSession(code)
+	started := session.QuestionStarted
+	c.Advance(20 * time.Second)
+
+	if err := manager.Resume(code); err != nil {
+		t.Fatalf("Failed to resume quiz: %v", err)
+	}
+	if manager.IsPaused(code) {
+		t.Error("Expected quiz to be running")
+	}
+	if err := manager.Resume(code); err == nil {
+		t.Error("Expected error resuming a running quiz")
+	}
+
+	// Time spent paused does not count towards the answer time
+	session, _ = manager.GetSession(code)
+	if shift := session.QuestionStarted.Sub(started); shift != 20*time.Second {
+		t.Errorf("Expected question start to move forward by the pause, moved %v", shift)
+	}
+	c.Advance(3 * time.Second)
+	if err := manager.SubmitAnswer(code, "p1", "A"); err != nil {
+		t.Errorf("Failed to submit answer after resuming: %v", err)
+	}
+	reveal, _ := manager.RevealAnswer(code)
+	if got := reveal.Participants[0].AnswerSubmissionTime; got != 3 {
+		t.Errorf("Expected an answer time of 3s, got %vs", got)
+	}
+}
+
+func TestAnswerTiming(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	manager := NewManagerWithOptions(Options{Clock: c})
+	quiz := models.Quiz{
+		Title:               "Test Quiz",
+		TimePerQuestion:     30,
+		QuickestAnswerBonus: true,
+		Questions: []models.Question{
+			{Text: "Question 1?", Options: []string{"A", "B"}, Answer: "A"},
+		},
+	}
+
+	code, _ := manager.CreateSession(quiz)
+	manager.AddParticipant(code, "p1", "Alice", false)
+	manager.AddParticipant(code, "p2", "Bob", false)
+	manager.AddParticipant(code, "p3", "Carol", false)
+	manager.StartQuiz(code)
+
+	// Bob is first but wrong, so Carol is the quickest correct answer
+	c.Advance(1500 * time.Millisecond)
+	manager.SubmitAnswer(code, "p2", "B")
+	c.Advance(time.Second)
+	manager.SubmitAnswer(code, "p3", "A")
+	c.Advance(time.Millisecond)
+	manager.SubmitAnswer(code, "p1", "A")
+
+	reveal, err := manager.RevealAnswer(code)
+	if err != nil {
+		t.Fatalf("Failed to reveal answer: %v", err)
+	}
+
+	want := map[string]struct {
+		score    int
+		quickest bool
+		time     float64
+	}{
+		"Alice": {1, false, 2.501},
+		"Bob":   {0, false, 1.5},
+		"Carol": {2, true, 2.5},
+	}
+	for _, p := range reveal.Participants {
+		w := want[p.Name]
+		if p.Score != w.score || p.QuickestAnswerFlag != w.quickest || p.AnswerSubmissionTime != w.time {
+			t.Errorf("Expected %s on %d points, quickest %v, answered in %vs; got %d, %v, %vs",
+				p.Name, w.score, w.quickest, w.time, p.Score, p.QuickestAnswerFlag, p.AnswerSubmissionTime)
+		}
+	}
+}
+
+func TestWagers(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	manager := NewManagerWithOptions(Options{Clock: c})
+	quiz := models.Quiz{
+		Title:           "Wager Quiz",
+		TimePerQuestion: 30,
+		Wagers:          true,
+		Questions: []models.Question{
+			{Text: "Question 1?", Options: []string{"A", "B"}, Answer: "A"},
+			{Text: "Question 2?", Options: []string{"A", "B"}, Answer: "B"},
+		},
+	}
+
+	code, _ := manager.CreateSession(quiz)
+	manager.AddParticipant(code, "host", "Host", true)
+	manager.AddParticipant(code, "p1", "Alice", false)
+	manager.AddParticipant(code, "p2", "Bob", false)
+	manager.StartQuiz(code)
+
+	// Questions open with a wager phase that takes no answers
+	session, _ := manager.GetSession(code)
+	if session.State != models.StateWager {
+		t.Fatalf("Expected the quiz to start by taking wagers, got %s", session.State)
+	}
+	if err := manager.SubmitAnswer(code, "p1", "A"); err == nil {
+		t.Error("Expected error answering before the options are shown")
+	}
+
+	// Nothing can be staked without points
+	if err := manager.PlaceWager(code, "p1", 1); err == nil {
+		t.Error("Expected error staking more than the score")
+	}
+	if err := manager.PlaceWager(code, "host", 0); err == nil {
+		t.Error("Expected error for a spectator wagering")
+	}
+	// Stakes are frozen while the host has paused the quiz
+	manager.Pause(code)
+	if err := manager.PlaceWager(code, "p1", 0); err != ErrPaused {
+		t.Errorf("Expected ErrPaused wagering while paused, got %v", err)
+	}
+	manager.Resume(code)
+
+	manager.PlaceWager(code, "p1", 0)
+	if manager.CheckAllWagered(code) {
+		t.Error("Expected Bob still to wager")
+	}
+	manager.PlaceWager(code, "p2", 0)
+	if !manager.CheckAllWagered(code) {
+		t.Error("Expected everyone to have wagered")
+	}
+
+	c.Advance(4 * time.Second)
+	if err := manager.OpenAnswers(code); err != nil {
+		t.Fatalf("Failed to open answers: %v", err)
+	}
+	if err := manager.PlaceWager(code, "p1", 0); err == nil {
+		t.Error("Expected error wagering once the options are shown")
+	}
+	c.Advance(time.Second)
+	manager.SubmitAnswer(code, "p1", "A")
+	manager.SubmitAnswer(code, "p2", "A")
+	reveal, _ := manager.RevealAnswer(code)
+	for _, p := range reveal.Participants {
+		if p.AnswerSubmissionTime != 1 {
+			t.Errorf("Expected answer times to start with the options, %s took %vs", p.Name, p.AnswerSubmissionTime)
+		}
+	}
+
+	// The stake is won with a correct answer and lost with a wrong one
+	manager.NextQuestion(code)
+	phase, err := manager.GetWagerPhase(code, "p1")
+	if err != nil {
+		t.Fatalf("Failed to get wager phase: %v", err)
+	}
+	if phase.QuestionNumber != 2 || phase.MaxStake != 1 || phase.TimeRemaining != models.DefaultWagerTime {
+		t.Errorf("Expected question 2 with a stake of up to 1 for %ds, got %+v", models.DefaultWagerTime, phase)
+	}
+	manager.PlaceWager(code, "p1", 1)
+	manager.PlaceWager(code, "p2", 1)
+	manager.OpenAnswers(code)
+	manager.SubmitAnswer(code, "p1", "B")
+	manager.SubmitAnswer(code, "p2", "A")
+	manager.RevealAnswer(code)
+
+	session, _ = manager.GetSession(code)
+	if session.Participants["p1"].Score != 3 || session.Participants["p2"].Score != 0 {
+		t.Errorf("Expected scores 3 and 0, got %d and %d", session.Participants["p1"].Score, session.Participants["p2"].Score)
+	}
+}
@@ -0,0 +1,29 @@
+package quiz
+
+import "github.com/rkrmr33/quickwiz/internal/metrics"
+
+var (
+	sessionsCreated = metrics.NewCounter("quickwiz_sessions_created_total",
+		"Quiz sessions created.")
+	answersSubmitted = metrics.NewCounter("quickwiz_answers_submitted_total",
+		"Answers accepted from participants.")
+	answerLatency = metrics.NewHistogram("quickwiz_answer_latency_seconds",
+		"Time from a question starting to a participant answering it.",
+		[]float64{1, 2, 3, 5, 8, 10, 15, 20, 30, 45, 60})
+)
+
+// RegisterMetrics registers gauges describing the manager's sessions. It must
+// be called at most once per registry.
+func (m *Manager) RegisterMetrics(reg *metrics.Registry) {
+	reg.NewGaugeVecFunc("quickwiz_sessions", "Quiz sessions by state.", "state", func() map[string]float64 {
+		counts := make(map[string]float64)
+		for state, count := range m.SessionCounts() {
+			counts[string(state)] = float64(count)
+		}
+		return counts
+	})
+	reg.NewGaugeVecFunc("quickwiz_participants", "Participants in quiz sessions by role.", "role", func() map[string]float64 {
+		players, spectators := m.ParticipantCounts()
+		return map[string]float64{"player": float64(players), "spectator": float64(spectators)}
+	})
+}
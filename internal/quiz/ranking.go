@@ -0,0 +1,140 @@
+package quiz
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/rkrmr33/quickwiz/internal/models"
+)
+
+// standing is a player's place in the standings of a session
+type standing struct {
+	*models.Participant
+	Rank int
+	Tied bool // Shares its rank with another player
+}
+
+// rankParticipants ranks the players of a session by score, then by the
+// quiz's tie-breakers, using competition ranking: players still tied share a
+// rank and the ranks after them skip as many places (1, 2, 2, 4). Tied players
+// are listed by name.
+func rankParticipants(session *models.QuizSession) []standing {
+	players := make([]*models.Participant, 0, len(session.Participants))
+	for _, p := range session.Participants {
+		if !p.IsSpectator {
+			players = append(players, p)
+		}
+	}
+
+	tieBreakers := session.Quiz.TieBreakers
+	slices.SortFunc(players, func(a, b *models.Participant) int {
+		if c := compareStanding(a, b, tieBreakers); c != 0 {
+			return c
+		}
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.ID, b.ID))
+	})
+
+	standings := make([]standing, len(players))
+	for i, p := range players {
+		standings[i] = standing{Participant: p, Rank: i + 1}
+		if i > 0 && compareStanding(players[i-1], p, tieBreakers) == 0 {
+			standings[i].Rank = standings[i-1].Rank
+			standings[i].Tied = true
+			standings[i-1].Tied = true
+		}
+	}
+	return standings
+}
+
+// compareStanding returns a negative number when a ranks above b, a positive
+// one when b ranks above a and 0 when they are tied
+func compareStanding(a, b *models.Participant, tieBreakers []string) int {
+	if c := cmp.Compare(b.Score, a.Score); c != 0 {
+		return c
+	}
+
+	for _, tieBreaker := range tieBreakers {
+		var c int
+		switch tieBreaker {
+		case models.TieBreakCorrect:
+			c = cmp.Compare(b.CorrectCount, a.CorrectCount)
+		case models.TieBreakTime:
+			c = cmp.Compare(a.CorrectTime, b.CorrectTime)
+		case models.TieBreakLastCorrect:
+			c = compareLastCorrect(a, b)
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compareLastCorrect ranks the player whose last correct answer came first
+// above the other. Players without a correct answer rank below those with one.
+func compareLastCorrect(a, b *models.Participant) int {
+	switch {
+	case a.LastCorrectAt.IsZero() && b.LastCorrectAt.IsZero():
+		return 0
+	case a.LastCorrectAt.IsZero():
+		return 1
+	case b.LastCorrectAt.IsZero():
+		return -1
+	}
+	return a.LastCorrectAt.Compare(b.LastCorrectAt)
+}
+
+// LeaderboardSize is how many leading players the live leaderboard lists
+const LeaderboardSize = 5
+
+// leaderboard returns the standings as shown to players, with the places
+// each gained or lost on the last question
+func leaderboard(standings []standing) []models.ParticipantInfo {
+	participants := make([]models.ParticipantInfo, 0, len(standings))
+	for _, s := range standings {
+		info := models.ParticipantInfo{
+			Name:         s.Name,
+			Rank:         s.Rank,
+			Tied:         s.Tied,
+			CorrectCount: s.CorrectCount,
+			Score:        s.Score,
+		}
+		if s.PreviousRank > 0 {
+			info.RankChange = s.PreviousRank - s.Rank
+		}
+		participants = append(participants, info)
+	}
+	return participants
+}
+
+// addSuddenDeathQuestion queues the quiz's next sudden death question when
+// players are tied for first place, and lets only them answer it. It reports
+// false when first place is not tied or no sudden death question is left.
+func addSuddenDeathQuestion(session *models.QuizSession) bool {
+	if len(session.Quiz.SuddenDeath) == 0 {
+		return false
+	}
+
+	standings := rankParticipants(session)
+	if len(standings) < 2 || standings[1].Rank != 1 {
+		return false
+	}
+	for _, s := range standings {
+		s.InSuddenDeath = s.Rank == 1
+	}
+
+	// The question stays in the current round so no round intro precedes it
+	question := session.Quiz.SuddenDeath[0]
+	question.Round = session.CurrentRound
+	session.Quiz.SuddenDeath = session.Quiz.SuddenDeath[1:]
+	session.Quiz.Questions = append(slices.Clip(session.Quiz.Questions), question)
+	session.SuddenDeath = true
+	return true
+}
+
+// playing reports whether a participant takes part in the current question:
+// every player does, except during sudden death where only those tied for
+// first place do
+func playing(session *models.QuizSession, p *models.Participant) bool {
+	return !p.IsSpectator && (!session.SuddenDeath || p.InSuddenDeath)
+}
@@ -0,0 +1,254 @@
+package quiz
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rkrmr33/quickwiz/internal/clock"
+	"github.com/rkrmr33/quickwiz/internal/models"
+)
+
+func TestRankParticipants(t *testing.T) {
+	start := time.Unix(0, 0)
+	players := []*models.Participant{
+		{ID: "p1", Name: "Dave", Score: 3, CorrectCount: 3, CorrectTime: 9 * time.Second, LastCorrectAt: start.Add(40 * time.Second)},
+		{ID: "p2", Name: "Carol", Score: 3, CorrectCount: 2, CorrectTime: 4 * time.Second, LastCorrectAt: start.Add(30 * time.Second)},
+		{ID: "p3", Name: "Bob", Score: 3, CorrectCount: 3, CorrectTime: 6 * time.Second, LastCorrectAt: start.Add(50 * time.Second)},
+		{ID: "p4", Name: "Alice", Score: 5, CorrectCount: 4, CorrectTime: 8 * time.Second, LastCorrectAt: start.Add(50 * time.Second)},
+		{ID: "p5", Name: "Eve", Score: 1, CorrectCount: 1, CorrectTime: 2 * time.Second, LastCorrectAt: start.Add(10 * time.Second)},
+		{ID: "host", Name: "Host", IsSpectator: true},
+	}
+
+	tests := []struct {
+		name        string
+		tieBreakers []string
+		want        string
+	}{
+		{
+			name: "ties stand",
+			want: "1 Alice, 2= Bob, 2= Carol, 2= Dave, 5 Eve",
+		},
+		{
+			name:        "most correct answers",
+			tieBreakers: []string{models.TieBreakCorrect},
+			want:        "1 Alice, 2= Bob, 2= Dave, 4 Carol, 5 Eve",
+		},
+		{
+			name:        "most correct answers, then quickest",
+			tieBreakers: []string{models.TieBreakCorrect, models.TieBreakTime},
+			want:        "1 Alice, 2 Bob, 3 Dave, 4 Carol, 5 Eve",
+		},
+		{
+			name:        "score reached first",
+			tieBreakers: []string{models.TieBreakLastCorrect},
+			want:        "1 Alice, 2 Carol, 3 Dave, 4 Bob, 5 Eve",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := &models.QuizSession{
+				Quiz:         models.Quiz{TieBreakers: tt.tieBreakers},
+				Participants: map[string]*models.Participant{},
+			}
+			for _, p := range players {
+				session.Participants[p.ID] = p
+			}
+
+			got := ""
+			for i, s := range rankParticipants(session) {
+				if i > 0 {
+					got += ", "
+				}
+				tied := ""
+				if s.Tied {
+					tied = "="
+				}
+				got += fmt.Sprintf("%d%s %s", s.Rank, tied, s.Name)
+			}
+			if got != tt.want {
+				t.Errorf("Expected standings %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLastCorrectWithoutCorrectAnswers(t *testing.T) {
+	answered := &models.Participant{Name: "Alice", LastCorrectAt: time.Unix(10, 0)}
+	never := &models.Participant{Name: "Bob"}
+
+	if compareLastCorrect(answered, never) >= 0 || compareLastCorrect(never, answered) <= 0 {
+		t.Error("Expected a player with a correct answer to rank above one without")
+	}
+	if compareLastCorrect(never, &models.Participant{Name: "Carol"}) != 0 {
+		t.Error("Expected players without correct answers to be tied")
+	}
+}
+
+func TestSuddenDeath(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	manager := NewManagerWithOptions(Options{Clock: c})
+	quiz := models.Quiz{
+		Title:           "Sudden Death Quiz",
+		TimePerQuestion: 30,
+		Questions: []models.Question{
+			{Text: "Question 1?", Options: []string{"A", "B"}, Answer: "A"},
+		},
+		SuddenDeath: []models.Question{
+			{Text: "Tiebreak 1?", Options: []string{"A", "B"}, Answer: "B"},
+			{Text: "Tiebreak 2?", Options: []string{"A", "B"}, Answer: "A"},
+		},
+	}
+
+	code, _ := manager.CreateSession(quiz)
+	manager.AddParticipant(code, "host", "Host", true)
+	manager.AddParticipant(code, "p1", "Alice", false)
+	manager.AddParticipant(code, "p2", "Bob", false)
+	manager.AddParticipant(code, "p3", "Carol", false)
+	manager.StartQuiz(code)
+
+	// Alice and Bob tie for first
+	manager.SubmitAnswer(code, "p1", "A")
+	manager.SubmitAnswer(code, "p2", "A")
+	manager.SubmitAnswer(code, "p3", "B")
+	manager.RevealAnswer(code)
+
+	hasNext, err := manager.NextQuestion(code)
+	if err != nil || !hasNext {
+		t.Fatalf("Expected a sudden death question, got %v, %v", hasNext, err)
+	}
+	update, _ := manager.GetQuestionUpdate(code, "p1")
+	if update.Text != "Tiebreak 1?" || !update.SuddenDeath || update.QuestionNumber != 2 {
+		t.Errorf("Expected the first sudden death question, got %+v", update)
+	}
+
+	// Only the players tied for first answer it
+	if err := manager.SubmitAnswer(code, "p3", "B"); err != ErrNotInSuddenDeath {
+		t.Errorf("Expected Carol to be left out of sudden death, got %v", err)
+	}
+	if _, total := manager.GetAnswerCount(code); total != 2 {
+		t.Errorf("Expected 2 players in sudden death, got %d", total)
+	}
+	manager.SubmitAnswer(code, "p1", "A")
+	manager.SubmitAnswer(code, "p2", "A")
+	if !manager.CheckAllAnswered(code) {
+		t.Error("Expected everyone in sudden death to have answered")
+	}
+	reveal, _ := manager.RevealAnswer(code)
+	if len(reveal.Participants) != 2 {
+		t.Errorf("Expected the reveal to show the 2 players in sudden death, got %d", len(reveal.Participants))
+	}
+
+	// Still tied, so the next one follows
+	if hasNext, _ := manager.NextQuestion(code); !hasNext {
+		t.Fatal("Expected the second sudden death question")
+	}
+	manager.SubmitAnswer(code, "p1", "B")
+	manager.SubmitAnswer(code, "p2", "A")
+	manager.RevealAnswer(code)
+
+	if hasNext, _ := manager.NextQuestion(code); hasNext {
+		t.Error("Expected the quiz to finish once first place is decided")
+	}
+	leaderboard, _ := manager.GetLeaderboard(code)
+	if leaderboard[0].Name != "Bob" || leaderboard[0].Rank != 1 || leaderboard[0].Tied {
+		t.Errorf("Expected Bob to win outright, got %+v", leaderboard[0])
+	}
+	if leaderboard[1].Name != "Alice" || leaderboard[1].Rank != 2 {
+		t.Errorf("Expected Alice second, got %+v", leaderboard[1])
+	}
+}
+
+func TestSuddenDeathRunsOut(t *testing.T) {
+	manager := NewManager()
+	quiz := models.Quiz{
+		Title:           "Sudden Death Quiz",
+		TimePerQuestion: 30,
+		Questions: []models.Question{
+			{Text: "Question 1?", Options: []string{"A", "B"}, Answer: "A"},
+		},
+		SuddenDeath: []models.Question{
+			{Text: "Tiebreak?", Options: []string{"A", "B"}, Answer: "B"},
+		},
+	}
+
+	code, _ := manager.CreateSession(quiz)
+	manager.AddParticipant(code, "p1", "Alice", false)
+	manager.AddParticipant(code, "p2", "Bob", false)
+	manager.StartQuiz(code)
+	manager.SubmitAnswer(code, "p1", "A")
+	manager.SubmitAnswer(code, "p2", "A")
+	manager.RevealAnswer(code)
+	manager.NextQuestion(code)
+	manager.RevealAnswer(code)
+
+	// Nobody answered the only sudden death question, so the tie stands
+	if hasNext, _ := manager.NextQuestion(code); hasNext {
+		t.Error("Expected the quiz to finish without sudden death questions left")
+	}
+	leaderboard, _ := manager.GetLeaderboard(code)
+	for _, p := range leaderboard {
+		if p.Rank != 1 || !p.Tied {
+			t.Errorf("Expected %s to share first place, got rank %d", p.Name, p.Rank)
+		}
+	}
+}
+
+func TestLeaderboardUpdate(t *testing.T) {
+	manager := NewManager()
+	quiz := models.Quiz{
+		Title:           "Leaderboard Quiz",
+		TimePerQuestion: 30,
+		Scoring:         models.ScoringNegative,
+		Questions: []models.Question{
+			{Text: "Question 1?", Options: []string{"A", "B"}, Answer: "A"},
+			{Text: "Question 2?", Options: []string{"A", "B"}, Answer: "B"},
+		},
+	}
+
+	code, _ := manager.CreateSession(quiz)
+	manager.AddParticipant(code, "host", "Host", true)
+	for i := 1; i <= LeaderboardSize+2; i++ {
+		manager.AddParticipant(code, fmt.Sprintf("p%d", i), fmt.Sprintf("Player %d", i), false)
+	}
+	manager.StartQuiz(code)
+
+	// Only the last player gets the first question right
+	manager.SubmitAnswer(code, "p7", "A")
+	manager.RevealAnswer(code)
+	update, err := manager.GetLeaderboardUpdate(code, "p7")
+	if err != nil {
+		t.Fatalf("Failed to get leaderboard: %v", err)
+	}
+	if update.QuestionNumber != 1 || update.TotalPlayers != 7 || len(update.Top) != LeaderboardSize {
+		t.Errorf("Expected the top %d of 7 players after question 1, got %+v", LeaderboardSize, update)
+	}
+	if update.Top[0].Name != "Player 7" || update.You == nil || update.You.Rank != 1 || update.You.RankChange != 0 {
+		t.Errorf("Expected Player 7 to lead without a rank change on the first question, got %+v and %+v", update.Top[0], update.You)
+	}
+
+	// Everyone else gets the second right and the leader loses their point,
+	// dropping out of the top but still seeing their own place
+	manager.NextQuestion(code)
+	for i := 1; i <= 6; i++ {
+		manager.SubmitAnswer(code, fmt.Sprintf("p%d", i), "B")
+	}
+	manager.SubmitAnswer(code, "p7", "A")
+	manager.RevealAnswer(code)
+
+	update, _ = manager.GetLeaderboardUpdate(code, "p7")
+	if update.You.Rank != 7 || update.You.RankChange != -6 {
+		t.Errorf("Expected Player 7 to drop to 7th, got rank %d moved %d", update.You.Rank, update.You.RankChange)
+	}
+	for _, p := range update.Top {
+		if p.Rank != 1 || p.RankChange != 1 || !p.Tied {
+			t.Errorf("Expected %s to move up to a shared first place, got rank %d moved %d", p.Name, p.Rank, p.RankChange)
+		}
+	}
+
+	update, _ = manager.GetLeaderboardUpdate(code, "host")
+	if update.You != nil {
+		t.Errorf("Expected no standing for the spectator, got %+v", update.You)
+	}
+}
@@ -0,0 +1,79 @@
+package quiz
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	mathrand "math/rand"
+	"sort"
+
+	"github.com/rkrmr33/quickwiz/internal/models"
+)
+
+// sampleQuestions draws the questions for a session. Each pool contributes
+// its sample count (or all of its questions), questions outside a pool are
+// always kept, and the quiz-level sample is then drawn from the result.
+// Selected questions keep their original markdown order, and the same seed
+// always yields the same selection.
+func sampleQuestions(quiz models.Quiz, seed int64) []models.Question {
+	if quiz.Sample == 0 && !hasPoolSampling(quiz.Pools) {
+		return quiz.Questions
+	}
+
+	rng := mathrand.New(mathrand.NewSource(seed))
+
+	// Group question indices by pool
+	poolIndices := make(map[string][]int)
+	for i, q := range quiz.Questions {
+		poolIndices[q.Pool] = append(poolIndices[q.Pool], i)
+	}
+
+	// Questions outside of any pool are always candidates
+	selected := append([]int(nil), poolIndices[""]...)
+	for _, pool := range quiz.Pools {
+		selected = append(selected, pickIndices(rng, poolIndices[pool.Name], pool.Sample)...)
+	}
+
+	selected = pickIndices(rng, selected, quiz.Sample)
+	sort.Ints(selected)
+
+	questions := make([]models.Question, 0, len(selected))
+	for _, i := range selected {
+		questions = append(questions, quiz.Questions[i])
+	}
+	return questions
+}
+
+// pickIndices returns n random indices from candidates, or all of them if n is
+// zero or not smaller than the number of candidates
+func pickIndices(rng *mathrand.Rand, candidates []int, n int) []int {
+	if n == 0 || n >= len(candidates) {
+		return candidates
+	}
+	shuffled := append([]int(nil), candidates...)
+	rng.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	return shuffled[:n]
+}
+
+// hasPoolSampling reports whether any pool draws a subset of its questions
+func hasPoolSampling(pools []models.Pool) bool {
+	for _, pool := range pools {
+		if pool.Sample > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// generateSeed generates a random non-zero sampling seed
+func generateSeed() int64 {
+	for {
+		var b [8]byte
+		rand.Read(b[:])
+		// Keep seeds positive so they are easy to copy into quiz settings
+		if seed := int64(binary.BigEndian.Uint64(b[:]) >> 1); seed != 0 {
+			return seed
+		}
+	}
+}
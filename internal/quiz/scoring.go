@@ -0,0 +1,221 @@
+package quiz
+
+import (
+	"math"
+	"time"
+
+	"github.com/rkrmr33/quickwiz/internal/models"
+)
+
+// MaxConfidence is the highest confidence level a player may put on an answer
+// in quizzes scored by confidence
+const MaxConfidence = 3
+
+// Scorer scores the answers to a question. Each quiz picks one by its scoring
+// setting; new rules are added by implementing Scorer and listing it in
+// scorers, without touching the game's state machine.
+type Scorer interface {
+	// Score returns the breakdown of every player's points by participant ID.
+	// Answers has an entry for every player, including those who did not answer.
+	Score(question ScoredQuestion, answers []Answer, settings *models.Quiz) map[string]Breakdown
+}
+
+// ScoredQuestion is the question being scored and how long players had to
+// answer it
+type ScoredQuestion struct {
+	models.Question
+	TimeLimit time.Duration
+}
+
+// Answer is a player's answer to a question as scorers see it
+type Answer struct {
+	ParticipantID string
+	Answer        string // Empty when the player did not answer
+	Answered      bool
+	AnsweredAt    time.Time
+	Took          time.Duration // From the question starting to the answer
+	Confidence    int           // Confidence level given with the answer, 0 when none was
+	Streak        int           // Correct answers in a row before this question
+}
+
+// Breakdown is what a player scored on a question
+type Breakdown struct {
+	Correct       bool
+	Points        int // For the answer itself, negative for a penalty
+	StreakBonus   int
+	QuickestBonus int
+	Streak        int // Correct answers in a row after this question
+	Milestone     int // Streak tier reached on this question, 0 when none
+	Wager         int // Points won or lost on the player's stake
+}
+
+// Total returns the points of the breakdown and its bonuses
+func (b Breakdown) Total() int {
+	return b.Points + b.StreakBonus + b.QuickestBonus + b.Wager
+}
+
+// scorers are the built-in scorers by scoring setting
+var scorers = map[string]Scorer{
+	models.ScoringClassic:    answerScorer(classicPoints),
+	models.ScoringSpeed:      answerScorer(speedPoints),
+	models.ScoringNegative:   answerScorer(negativePoints),
+	models.ScoringConfidence: answerScorer(confidencePoints),
+}
+
+// scorerFor returns the scorer of a quiz, classic when it does not choose one
+func scorerFor(quiz *models.Quiz) Scorer {
+	if scorer, ok := scorers[quiz.Scoring]; ok {
+		return scorer
+	}
+	return scorers[models.ScoringClassic]
+}
+
+// answerScorer is a Scorer that scores each answer on its own, then adds the
+// streak and quickest answer bonuses the quiz enables. Streaks follow the
+// quiz's streak tiers and break on a wrong answer and, unless the quiz says
+// otherwise, on no answer.
+type answerScorer func(question ScoredQuestion, answer Answer, correct bool, settings *models.Quiz) int
+
+func (points answerScorer) Score(question ScoredQuestion, answers []Answer, settings *models.Quiz) map[string]Breakdown {
+	// Find the quickest correct answer if bonus is enabled
+	quickest := ""
+	if settings.QuickestAnswerBonus {
+		var earliest time.Time
+		for _, a := range answers {
+			if a.Answered && a.Answer == question.Answer && (quickest == "" || a.AnsweredAt.Before(earliest)) {
+				quickest, earliest = a.ParticipantID, a.AnsweredAt
+			}
+		}
+	}
+
+	breakdowns := make(map[string]Breakdown, len(answers))
+	for _, a := range answers {
+		correct := a.Answered && a.Answer == question.Answer
+		b := Breakdown{
+			Correct: correct,
+			Points:  points(question, a, correct, settings),
+		}
+
+		switch {
+		case correct:
+			b.Streak = a.Streak + 1
+			if tier := streakTier(settings, b.Streak); tier != nil {
+				b.StreakBonus = streakBonus(*tier, b.Points)
+				if tier.Streak == b.Streak {
+					b.Milestone = b.Streak
+				}
+			}
+			if a.ParticipantID == quickest {
+				b.QuickestBonus = 1
+			}
+		case !a.Answered && settings.StreakBreak == models.StreakBreakWrong:
+			b.Streak = a.Streak
+		}
+
+		breakdowns[a.ParticipantID] = b
+	}
+	return breakdowns
+}
+
+// classicPoints gives one point per correct answer
+func classicPoints(_ ScoredQuestion, _ Answer, correct bool, _ *models.Quiz) int {
+	if !correct {
+		return 0
+	}
+	return 1
+}
+
+// speedPoints gives correct answers points falling from the quiz's maximum
+// for an instant answer to its minimum for one given as time runs out
+func speedPoints(question ScoredQuestion, answer Answer, correct bool, settings *models.Quiz) int {
+	if !correct {
+		return 0
+	}
+
+	maxPoints, minPoints := settings.MaxPoints, settings.MinPoints
+	if maxPoints == 0 {
+		maxPoints, minPoints = models.DefaultMaxPoints, models.DefaultMinPoints
+	}
+
+	// Fraction of the time limit used
+	used := 0.0
+	if question.TimeLimit > 0 {
+		used = min(max(answer.Took.Seconds()/question.TimeLimit.Seconds(), 0), 1)
+	}
+
+	if settings.SpeedDecay == models.DecayExponential && minPoints > 0 {
+		return int(math.Round(float64(maxPoints) * math.Pow(float64(minPoints)/float64(maxPoints), used)))
+	}
+	return int(math.Round(float64(maxPoints) - float64(maxPoints-minPoints)*used))
+}
+
+// negativePoints gives a point per correct answer and takes the quiz's
+// penalty off for a wrong one; not answering costs nothing
+func negativePoints(_ ScoredQuestion, answer Answer, correct bool, settings *models.Quiz) int {
+	switch {
+	case correct:
+		return 1
+	case !answer.Answered:
+		return 0
+	case settings.Penalty == 0:
+		return -models.DefaultPenalty
+	default:
+		return -settings.Penalty
+	}
+}
+
+// confidencePoints gives as many points as the confidence level of a correct
+// answer and takes as many off for a wrong one. Answers without a level count
+// as the lowest.
+func confidencePoints(_ ScoredQuestion, answer Answer, correct bool, _ *models.Quiz) int {
+	if !answer.Answered {
+		return 0
+	}
+	level := max(answer.Confidence, 1)
+	if !correct {
+		return -level
+	}
+	return level
+}
+
+// wagerResult returns the points a stake wins with a correct answer or loses
+// with a wrong or missing one
+func wagerResult(correct bool, stake int) int {
+	if correct {
+		return stake
+	}
+	return -stake
+}
+
+// streakTier returns the highest streak tier a streak has reached, nil when
+// it has reached none or the quiz has no streak bonus
+func streakTier(settings *models.Quiz, streak int) *models.StreakTier {
+	if !settings.StreakBonus {
+		return nil
+	}
+	tiers := settings.StreakTiers
+	if len(tiers) == 0 {
+		tiers = models.DefaultStreakTiers
+	}
+
+	var reached *models.StreakTier
+	for i := range tiers {
+		if tiers[i].Streak <= streak && (reached == nil || tiers[i].Streak > reached.Streak) {
+			tier := tiers[i]
+			reached = &tier
+		}
+	}
+	return reached
+}
+
+// streakBonus returns the bonus a streak tier adds to an answer's points.
+// Multipliers only apply to answers that earned points.
+func streakBonus(tier models.StreakTier, points int) int {
+	if tier.Multiplier == 0 {
+		return tier.Bonus
+	}
+	if points <= 0 {
+		return 0
+	}
+	return int(math.Round(float64(points) * (tier.Multiplier - 1)))
+}
@@ -0,0 +1,241 @@
+package quiz
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rkrmr33/quickwiz/internal/clock"
+	"github.com/rkrmr33/quickwiz/internal/models"
+)
+
+func TestSpeedPoints(t *testing.T) {
+	linear := models.Quiz{Scoring: models.ScoringSpeed, MaxPoints: 1000, MinPoints: 500}
+	exponential := models.Quiz{Scoring: models.ScoringSpeed, SpeedDecay: models.DecayExponential, MaxPoints: 1000, MinPoints: 250}
+	limit := 10 * time.Second
+
+	tests := []struct {
+		name string
+		quiz models.Quiz
+		took time.Duration
+		want int
+	}{
+		{"linear instant", linear, 0, 1000},
+		{"linear halfway", linear, 5 * time.Second, 750},
+		{"linear at the limit", linear, limit, 500},
+		{"linear after the limit", linear, 12 * time.Second, 500},
+		{"exponential instant", exponential, 0, 1000},
+		{"exponential halfway", exponential, 5 * time.Second, 500},
+		{"exponential at the limit", exponential, limit, 250},
+		{"speed defaults", models.Quiz{Scoring: models.ScoringSpeed}, 5 * time.Second, 750},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			question := ScoredQuestion{TimeLimit: limit}
+			answer := Answer{Answered: true, Took: tt.took}
+			if got := speedPoints(question, answer, true, &tt.quiz); got != tt.want {
+				t.Errorf("Expected %d points, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSpeedScoring(t *testing.T) {
+	c := clock.NewFake(time.Unix(0, 0))
+	manager := NewManagerWithOptions(Options{Clock: c})
+	quiz := models.Quiz{
+		Title:           "Speed Quiz",
+		TimePerQuestion: 20,
+		Scoring:         models.ScoringSpeed,
+		MaxPoints:       1000,
+		MinPoints:       500,
+		Questions: []models.Question{
+			{Text: "Question 1?", Options: []string{"A", "B"}, Answer: "A"},
+		},
+	}
+
+	code, _ := manager.CreateSession(quiz)
+	manager.AddParticipant(code, "p1", "Alice", false)
+	manager.AddParticipant(code, "p2", "Bob", false)
+	manager.AddParticipant(code, "p3", "Carol", false)
+	manager.StartQuiz(code)
+
+	c.Advance(2 * time.Second)
+	manager.SubmitAnswer(code, "p1", "A")
+	c.Advance(2 * time.Second)
+	manager.SubmitAnswer(code, "p2", "B")
+	c.Advance(6 * time.Second)
+	manager.SubmitAnswer(code, "p3", "A")
+
+	reveal, err := manager.RevealAnswer(code)
+	if err != nil {
+		t.Fatalf("Failed to reveal answer: %v", err)
+	}
+
+	want := map[string]int{"Alice": 950, "Bob": 0, "Carol": 750}
+	for _, p := range reveal.Participants {
+		if p.Points != want[p.Name] || p.Score != want[p.Name] {
+			t.Errorf("Expected %s to earn %d points, got %d for a score of %d", p.Name, want[p.Name], p.Points, p.Score)
+		}
+	}
+}
+
+func TestScorers(t *testing.T) {
+	question := ScoredQuestion{
+		Question:  models.Question{Text: "Question 1?", Options: []string{"A", "B"}, Answer: "A"},
+		TimeLimit: 10 * time.Second,
+	}
+	start := time.Unix(0, 0)
+	answers := []Answer{
+		{ParticipantID: "right", Answer: "A", Answered: true, AnsweredAt: start.Add(2 * time.Second), Took: 2 * time.Second, Confidence: 3, Streak: 2},
+		{ParticipantID: "quick", Answer: "A", Answered: true, AnsweredAt: start.Add(time.Second), Took: time.Second},
+		{ParticipantID: "wrong", Answer: "B", Answered: true, AnsweredAt: start, Confidence: 2, Streak: 4},
+		{ParticipantID: "silent"},
+	}
+
+	tests := []struct {
+		name     string
+		settings models.Quiz
+		points   map[string]int
+	}{
+		{"classic", models.Quiz{}, map[string]int{"right": 1, "quick": 1, "wrong": 0, "silent": 0}},
+		{"negative", models.Quiz{Scoring: models.ScoringNegative, Penalty: 2}, map[string]int{"right": 1, "quick": 1, "wrong": -2, "silent": 0}},
+		{"negative default penalty", models.Quiz{Scoring: models.ScoringNegative}, map[string]int{"right": 1, "quick": 1, "wrong": -1, "silent": 0}},
+		{"confidence", models.Quiz{Scoring: models.ScoringConfidence}, map[string]int{"right": 3, "quick": 1, "wrong": -2, "silent": 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.settings.StreakBonus = true
+			tt.settings.QuickestAnswerBonus = true
+			breakdowns := scorerFor(&tt.settings).Score(question, answers, &tt.settings)
+
+			for id, want := range tt.points {
+				if got := breakdowns[id].Points; got != want {
+					t.Errorf("Expected %s to earn %d points, got %d", id, want, got)
+				}
+			}
+
+			// Bonuses and streaks are the same whatever the scorer
+			right, quick, wrong := breakdowns["right"], breakdowns["quick"], breakdowns["wrong"]
+			if right.Streak != 3 || right.StreakBonus != 1 || right.QuickestBonus != 0 {
+				t.Errorf("Expected right on a streak of 3 with a bonus, got %+v", right)
+			}
+			if quick.QuickestBonus != 1 || quick.Total() != quick.Points+1 {
+				t.Errorf("Expected quick to get the quickest bonus, got %+v", quick)
+			}
+			if wrong.Correct || wrong.Streak != 0 || wrong.QuickestBonus != 0 {
+				t.Errorf("Expected wrong to lose its streak without bonuses, got %+v", wrong)
+			}
+		})
+	}
+}
+
+func TestConfidenceScoring(t *testing.T) {
+	manager := NewManager()
+	quiz := models.Quiz{
+		Title:           "Confidence Quiz",
+		TimePerQuestion: 20,
+		Scoring:         models.ScoringConfidence,
+		Questions: []models.Question{
+			{Text: "Question 1?", Options: []string{"A", "B"}, Answer: "A"},
+		},
+	}
+
+	code, _ := manager.CreateSession(quiz)
+	manager.AddParticipant(code, "p1", "Alice", false)
+	manager.AddParticipant(code, "p2", "Bob", false)
+
+	if err := manager.SetConfidence(code, "p1", 3); err == nil {
+		t.Error("Expected error setting confidence before the question")
+	}
+	manager.StartQuiz(code)
+
+	if update, _ := manager.GetQuestionUpdate(code, "p1"); update.MaxConfidence != MaxConfidence {
+		t.Errorf("Expected questions to offer confidence up to %d, got %d", MaxConfidence, update.MaxConfidence)
+	}
+	if err := manager.SetConfidence(code, "p1", MaxConfidence+1); err == nil {
+		t.Error("Expected error for a confidence level out of range")
+	}
+	if err := manager.SetConfidence(code, "p1", 3); err != nil {
+		t.Fatalf("Failed to set confidence: %v", err)
+	}
+	manager.SubmitAnswer(code, "p1", "A")
+	if err := manager.SetConfidence(code, "p1", 1); err == nil {
+		t.Error("Expected error changing confidence after answering")
+	}
+	manager.SetConfidence(code, "p2", 2)
+	manager.SubmitAnswer(code, "p2", "B")
+
+	manager.RevealAnswer(code)
+	session, _ := manager.GetSession(code)
+	if session.Participants["p1"].Score != 3 || session.Participants["p2"].Score != -2 {
+		t.Errorf("Expected scores 3 and -2, got %d and %d", session.Participants["p1"].Score, session.Participants["p2"].Score)
+	}
+
+	// Confidence only applies to quizzes scored by it
+	classic, _ := manager.CreateSession(models.Quiz{Title: "Classic", Questions: quiz.Questions})
+	manager.AddParticipant(classic, "p1", "Alice", false)
+	manager.StartQuiz(classic)
+	if err := manager.SetConfidence(classic, "p1", 2); err == nil {
+		t.Error("Expected error setting confidence in a classic quiz")
+	}
+}
+
+func TestStreakTiers(t *testing.T) {
+	question := ScoredQuestion{
+		Question:  models.Question{Text: "Question 1?", Options: []string{"A", "B"}, Answer: "A"},
+		TimeLimit: 10 * time.Second,
+	}
+	settings := models.Quiz{
+		Scoring:     models.ScoringSpeed,
+		StreakBonus: true,
+		StreakTiers: []models.StreakTier{{Streak: 2, Bonus: 50}, {Streak: 4, Multiplier: 2}},
+	}
+	answers := []Answer{
+		{ParticipantID: "first", Answer: "A", Answered: true},
+		{ParticipantID: "second", Answer: "A", Answered: true, Streak: 1},
+		{ParticipantID: "third", Answer: "A", Answered: true, Streak: 2},
+		{ParticipantID: "fourth", Answer: "A", Answered: true, Streak: 3},
+		{ParticipantID: "fifth", Answer: "A", Answered: true, Streak: 4},
+		{ParticipantID: "silent", Streak: 3},
+	}
+
+	want := map[string]Breakdown{
+		"first":  {Correct: true, Points: 1000, Streak: 1},
+		"second": {Correct: true, Points: 1000, StreakBonus: 50, Streak: 2, Milestone: 2},
+		"third":  {Correct: true, Points: 1000, StreakBonus: 50, Streak: 3},
+		"fourth": {Correct: true, Points: 1000, StreakBonus: 1000, Streak: 4, Milestone: 4},
+		"fifth":  {Correct: true, Points: 1000, StreakBonus: 1000, Streak: 5},
+		"silent": {},
+	}
+	breakdowns := scorerFor(&settings).Score(question, answers, &settings)
+	for id, w := range want {
+		if breakdowns[id] != w {
+			t.Errorf("Expected %s to score %+v, got %+v", id, w, breakdowns[id])
+		}
+	}
+
+	// Not answering keeps the streak when only wrong answers break it
+	settings.StreakBreak = models.StreakBreakWrong
+	breakdowns = scorerFor(&settings).Score(question, answers, &settings)
+	if got := breakdowns["silent"].Streak; got != 3 {
+		t.Errorf("Expected silent to keep a streak of 3, got %d", got)
+	}
+}
+
+func TestDefaultStreakTiers(t *testing.T) {
+	settings := models.Quiz{StreakBonus: true}
+	for streak, want := range map[int]int{1: 0, 2: 0, 3: 1, 4: 1, 5: 2, 9: 2, 10: 5, 20: 5} {
+		bonus := 0
+		if tier := streakTier(&settings, streak); tier != nil {
+			bonus = streakBonus(*tier, 1)
+		}
+		if bonus != want {
+			t.Errorf("Expected a bonus of %d for a streak of %d, got %d", want, streak, bonus)
+		}
+	}
+
+	settings.StreakBonus = false
+	if tier := streakTier(&settings, 10); tier != nil {
+		t.Errorf("Expected no streak tier without the streak bonus, got %+v", tier)
+	}
+}
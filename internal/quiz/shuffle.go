@@ -0,0 +1,69 @@
+package quiz
+
+import (
+	"hash/fnv"
+	mathrand "math/rand"
+	"strconv"
+
+	"github.com/rkrmr33/quickwiz/internal/models"
+)
+
+// shuffleQuestions shuffles the question order of a session. The order is
+// shared by all participants so everyone still answers the same question at
+// the same time, and is derived from the session seed so it can be reproduced.
+// Questions are only shuffled within their round, so rounds stay together.
+func shuffleQuestions(questions []models.Question, seed int64) []models.Question {
+	shuffled := append([]models.Question(nil), questions...)
+	rng := mathrand.New(mathrand.NewSource(seed))
+
+	for start := 0; start < len(shuffled); {
+		end := start + 1
+		for end < len(shuffled) && shuffled[end].Round == shuffled[start].Round {
+			end++
+		}
+		group := shuffled[start:end]
+		rng.Shuffle(len(group), func(i, j int) {
+			group[i], group[j] = group[j], group[i]
+		})
+		start = end
+	}
+
+	return shuffled
+}
+
+// optionPermutation returns the order in which a participant sees the options
+// of a question: element i is the canonical index of the i-th displayed option.
+// The permutation is deterministic for a given session seed, participant and
+// question, so reconnecting participants see the same layout.
+func optionPermutation(n int, seed int64, participantID string, question int) []int {
+	h := fnv.New64a()
+	h.Write([]byte(participantID))
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.Itoa(question)))
+
+	rng := mathrand.New(mathrand.NewSource(seed ^ int64(h.Sum64())))
+	return rng.Perm(n)
+}
+
+// participantOptions returns the options of the current question in the order
+// shown to the given participant. Spectators and unknown participants see the
+// canonical order.
+func participantOptions(session *models.QuizSession, participantID string) []string {
+	q := session.Quiz.Questions[session.CurrentQuestion]
+
+	order := make([]int, len(q.Options))
+	for i := range order {
+		order[i] = i
+	}
+
+	p, exists := session.Participants[participantID]
+	if session.Quiz.ShuffleOptions && exists && !p.IsSpectator {
+		order = optionPermutation(len(q.Options), session.Seed, participantID, session.CurrentQuestion)
+	}
+
+	options := make([]string, len(order))
+	for i, idx := range order {
+		options[i] = q.Options[idx]
+	}
+	return options
+}
@@ -0,0 +1,234 @@
+package quiz
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	mathrand "math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rkrmr33/quickwiz/internal/models"
+)
+
+var (
+	errSessionNotFound = errors.New("quiz session not found")
+	errSessionExists   = errors.New("quiz session already exists")
+)
+
+// Store holds the sessions of a Manager. Functions given to View, Update and
+// Each must not call back into the store. Update may call its function more
+// than once, so the function must do nothing but change the session.
+type Store interface {
+	// Create adds a new session
+	Create(session *models.QuizSession) error
+	// View calls fn with a session
+	View(code string, fn func(session *models.QuizSession) error) error
+	// Update calls fn with a session and saves its changes unless fn fails
+	Update(code string, fn func(session *models.QuizSession) error) error
+	// Delete removes a session
+	Delete(code string) error
+	// Each calls fn with every session
+	Each(fn func(session *models.QuizSession)) error
+	// Len returns the number of sessions
+	Len() (int, error)
+}
+
+// memoryStore keeps sessions in memory, for a server running on its own
+type memoryStore struct {
+	sessions map[string]*models.QuizSession
+	mu       sync.RWMutex
+}
+
+// NewMemoryStore creates a store that keeps sessions in memory
+func NewMemoryStore() Store {
+	return &memoryStore{sessions: make(map[string]*models.QuizSession)}
+}
+
+func (s *memoryStore) Create(session *models.QuizSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.sessions[session.Code]; exists {
+		return errSessionExists
+	}
+	s.sessions[session.Code] = session
+	return nil
+}
+
+func (s *memoryStore) View(code string, fn func(*models.QuizSession) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, exists := s.sessions[code]
+	if !exists {
+		return errSessionNotFound
+	}
+	return fn(session)
+}
+
+func (s *memoryStore) Update(code string, fn func(*models.QuizSession) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, exists := s.sessions[code]
+	if !exists {
+		return errSessionNotFound
+	}
+	return fn(session)
+}
+
+func (s *memoryStore) Delete(code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, code)
+	return nil
+}
+
+func (s *memoryStore) Each(fn func(*models.QuizSession)) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, session := range s.sessions {
+		fn(session)
+	}
+	return nil
+}
+
+func (s *memoryStore) Len() (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.sessions), nil
+}
+
+// KV is a key-value store shared between server instances, such as a
+// cluster.Client. Every value has a version that increases with each write.
+type KV interface {
+	// Get returns a value and its version, or a zero version if key is not set
+	Get(key string) ([]byte, uint64, error)
+	// Set stores a value if the stored version is still version (0 when the
+	// key must not exist yet), reporting false otherwise
+	Set(key string, value []byte, version uint64) (bool, error)
+	// Delete removes a value
+	Delete(key string) error
+	// Keys returns the keys starting with prefix
+	Keys(prefix string) ([]string, error)
+}
+
+const (
+	// sessionPrefix prefixes the keys of sessions in a KV
+	sessionPrefix = "session/"
+	// maxUpdateAttempts bounds retries when other instances keep changing a session
+	maxUpdateAttempts = 50
+	// updateBackoff is the longest wait before the first retry, doubling with
+	// each attempt, so writers racing on a busy session spread out
+	updateBackoff = time.Millisecond
+)
+
+// kvStore keeps sessions as JSON in a KV so every server instance sees them
+type kvStore struct {
+	kv KV
+}
+
+// NewKVStore creates a store keeping sessions in a KV shared by several server
+// instances. Sessions are updated with compare-and-set, retrying when another
+// instance changed the session in the meantime.
+func NewKVStore(kv KV) Store {
+	return &kvStore{kv: kv}
+}
+
+func (s *kvStore) Create(session *models.QuizSession) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+	created, err := s.kv.Set(sessionPrefix+session.Code, data, 0)
+	if err != nil {
+		return err
+	}
+	if !created {
+		return errSessionExists
+	}
+	return nil
+}
+
+func (s *kvStore) View(code string, fn func(*models.QuizSession) error) error {
+	session, _, err := s.get(code)
+	if err != nil {
+		return err
+	}
+	return fn(session)
+}
+
+func (s *kvStore) Update(code string, fn func(*models.QuizSession) error) error {
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		session, version, err := s.get(code)
+		if err != nil {
+			return err
+		}
+		if err := fn(session); err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(session)
+		if err != nil {
+			return fmt.Errorf("failed to encode session: %w", err)
+		}
+		saved, err := s.kv.Set(sessionPrefix+code, data, version)
+		if err != nil {
+			return err
+		}
+		if saved {
+			return nil
+		}
+		time.Sleep(time.Duration(mathrand.Int63n(int64(updateBackoff << min(attempt, 6)))))
+	}
+	return fmt.Errorf("quiz session %s changed too often to update", code)
+}
+
+func (s *kvStore) Delete(code string) error {
+	return s.kv.Delete(sessionPrefix + code)
+}
+
+func (s *kvStore) Each(fn func(*models.QuizSession)) error {
+	keys, err := s.kv.Keys(sessionPrefix)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		session, _, err := s.get(strings.TrimPrefix(key, sessionPrefix))
+		if errors.Is(err, errSessionNotFound) {
+			continue // Deleted since listing
+		}
+		if err != nil {
+			return err
+		}
+		fn(session)
+	}
+	return nil
+}
+
+func (s *kvStore) Len() (int, error) {
+	keys, err := s.kv.Keys(sessionPrefix)
+	return len(keys), err
+}
+
+// get loads and decodes a session
+func (s *kvStore) get(code string) (*models.QuizSession, uint64, error) {
+	data, version, err := s.kv.Get(sessionPrefix + code)
+	if err != nil {
+		return nil, 0, err
+	}
+	if version == 0 {
+		return nil, 0, errSessionNotFound
+	}
+
+	var session models.QuizSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode session %s: %w", code, err)
+	}
+	return &session, version, nil
+}
@@ -0,0 +1,131 @@
+package quiz
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/rkrmr33/quickwiz/internal/cluster"
+	"github.com/rkrmr33/quickwiz/internal/models"
+)
+
+// newKVStore returns a store backed by a broker running on a local port
+func newKVStore(t *testing.T) Store {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	broker := cluster.NewBroker()
+	go broker.Serve(l)
+	t.Cleanup(func() { broker.Close() })
+
+	client, err := cluster.Dial(l.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial broker: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return NewKVStore(client)
+}
+
+func TestKVStoreGame(t *testing.T) {
+	store := newKVStore(t)
+	manager := NewManagerWithOptions(Options{Store: store})
+	other := NewManagerWithOptions(Options{Store: store}) // Another instance
+
+	code, err := manager.CreateSession(models.Quiz{
+		Title:           "Shared",
+		TimePerQuestion: 30,
+		Questions: []models.Question{
+			{Text: "Q1?", Options: []string{"A", "B"}, Answer: "A"},
+			{Text: "Q2?", Options: []string{"A", "B"}, Answer: "B"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	other.AddParticipant(code, "host", "Host", true)
+	manager.AddParticipant(code, "p1", "Alice", false)
+	other.AddParticipant(code, "p2", "Bob", false)
+
+	if err := other.StartQuiz(code); err != nil {
+		t.Fatalf("Failed to start quiz: %v", err)
+	}
+	if err := manager.StartQuiz(code); err == nil {
+		t.Error("Expected the second start to fail")
+	}
+
+	manager.SubmitAnswer(code, "p1", "A")
+	other.SubmitAnswerIndex(code, "p2", 1)
+	if !manager.CheckAllAnswered(code) {
+		t.Error("Expected answers from both instances to be seen")
+	}
+
+	reveal, err := manager.RevealAnswer(code)
+	if err != nil {
+		t.Fatalf("Failed to reveal answer: %v", err)
+	}
+	if len(reveal.Participants) != 2 {
+		t.Errorf("Expected 2 participants in reveal, got %d", len(reveal.Participants))
+	}
+
+	hasNext, err := other.NextQuestion(code)
+	if err != nil || !hasNext {
+		t.Fatalf("Expected a next question, got %v and error %v", hasNext, err)
+	}
+
+	session, _ := manager.GetSession(code)
+	if session.CurrentQuestion != 1 || session.Participants["p1"].Score != 1 {
+		t.Errorf("Expected question 2 with Alice on 1 point, got question %d and %d points",
+			session.CurrentQuestion+1, session.Participants["p1"].Score)
+	}
+
+	if counts := other.SessionCounts(); counts[models.StateQuestion] != 1 {
+		t.Errorf("Expected 1 session in question state, got %v", counts)
+	}
+}
+
+func TestKVStoreConcurrentUpdates(t *testing.T) {
+	store := newKVStore(t)
+	managers := []*Manager{
+		NewManagerWithOptions(Options{Store: store}),
+		NewManagerWithOptions(Options{Store: store}),
+	}
+
+	code, _ := managers[0].CreateSession(models.Quiz{
+		Title:     "Crowded",
+		Questions: []models.Question{{Text: "Q?", Options: []string{"A"}, Answer: "A"}},
+	})
+
+	// Joins racing on the same session must all be kept
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprintf("p%d", i)
+			if err := managers[i%2].AddParticipant(code, id, id, false); err != nil {
+				t.Errorf("Failed to add %s: %v", id, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	session, _ := managers[1].GetSession(code)
+	if len(session.Participants) != 20 {
+		t.Errorf("Expected 20 participants, got %d", len(session.Participants))
+	}
+}
+
+func TestKVStoreNotFound(t *testing.T) {
+	manager := NewManagerWithOptions(Options{Store: newKVStore(t)})
+
+	if _, err := manager.GetSession("nope"); err == nil {
+		t.Error("Expected an error for a missing session")
+	}
+	if err := manager.AddParticipant("nope", "p1", "Alice", false); err == nil {
+		t.Error("Expected an error joining a missing session")
+	}
+}
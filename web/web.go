@@ -0,0 +1,10 @@
+// Package web embeds the HTML templates and static assets so the server
+// binary can run from any working directory.
+package web
+
+import "embed"
+
+// FS holds the templates/ and static/ directories
+//
+//go:embed templates/*.html static
+var FS embed.FS